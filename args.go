@@ -0,0 +1,263 @@
+/*
+Copyright 2016 Google Inc. All Rights Reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package subcommands
+
+import (
+	"encoding"
+	"errors"
+	"flag"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// An ArgsDeclarer is a Command declaring the positional arguments it
+// takes, after its flags.
+type ArgsDeclarer interface {
+	// Args returns the positional arguments of the command, separated by
+	// spaces, such as "<source> <dest> [extra...]". An argument in angle
+	// brackets is required and one in square brackets is optional; an
+	// argument ending in "..." may be repeated.
+	//
+	// The Commander checks the arguments given before Execute, and
+	// reports a missing required argument, or one too many, as a usage
+	// error. If the Usage of the command is empty, the arguments are
+	// shown in the usage line synthesized in its place.
+	Args() string
+}
+
+// An argSpec is a positional argument declared by an ArgsDeclarer.
+type argSpec struct {
+	text     string // text is the argument as declared, such as "<dest>".
+	required bool
+	variadic bool
+}
+
+// declaredArgs returns the positional arguments declared by cmd, if it is
+// an ArgsDeclarer.
+func declaredArgs(cmd Command) []argSpec {
+	d, ok := dealias(cmd).(ArgsDeclarer)
+	if !ok {
+		return nil
+	}
+	var specs []argSpec
+	for _, text := range strings.Fields(d.Args()) {
+		specs = append(specs, argSpec{
+			text:     text,
+			required: strings.HasPrefix(text, "<"),
+			variadic: strings.HasSuffix(strings.TrimRight(text, ">]"), "..."),
+		})
+	}
+	return specs
+}
+
+// checkArgs returns an error if args are not the positional arguments
+// declared by cmd, naming the first required argument missing or the
+// first argument not expected.
+func checkArgs(cmd Command, args []string) error {
+	specs := declaredArgs(cmd)
+	if specs == nil {
+		return nil
+	}
+	for i := len(args); i < len(specs); i++ {
+		if specs[i].required {
+			return fmt.Errorf("missing required argument %s", strings.TrimSuffix(specs[i].text, "..."))
+		}
+	}
+	if len(args) > len(specs) && !specs[len(specs)-1].variadic {
+		return fmt.Errorf("unexpected argument %q", args[len(specs)])
+	}
+	return nil
+}
+
+// usage returns the usage of cmd or, if it is empty and cmd is an
+// ArgsDeclarer, a usage line naming its flags and arguments.
+func usage(cmd Command) string {
+	u := cmd.Usage()
+	if strings.TrimSpace(u) != "" {
+		return u
+	}
+	specs := declaredArgs(cmd)
+	if specs == nil {
+		return u
+	}
+	line := []string{cmd.Name()}
+	f := flag.NewFlagSet(cmd.Name(), flag.ContinueOnError)
+	cmd.SetFlags(f)
+	defer forget(f)
+	hasFlags := false
+	f.VisitAll(func(*flag.Flag) { hasFlags = true })
+	if hasFlags {
+		line = append(line, "[<flags>]")
+	}
+	for _, spec := range specs {
+		line = append(line, spec.text)
+	}
+	return strings.Join(line, " ") + ":\n"
+}
+
+// ParseArgs sets the exported fields of the struct pointed to by v to the
+// positional arguments remaining in f, in field order, converting each
+// to the type of its field: a string, bool, integer, floating-point
+// number, time.Duration, or type implementing encoding.TextUnmarshaler.
+// A last field that is a slice of one of these takes all remaining
+// arguments. For example:
+//
+//	var args struct {
+//		Src   string
+//		Dst   string
+//		Count int `arg:"count,optional"`
+//		Rest  []string
+//	}
+//	if err := subcommands.ParseArgs(f, &args); err != nil {
+//		fmt.Fprintln(os.Stderr, err)
+//		return subcommands.ExitUsageError
+//	}
+//
+// A field is named in errors by its lowercased name, or the name in its
+// arg tag. Fields are required unless tagged optional, and a tag of "-"
+// skips the field. ParseArgs returns an error naming the first required
+// argument missing, an argument not expected, or an argument that
+// cannot be converted. It panics if v is not a pointer to a struct, or
+// if a slice field is followed by another field, which could never be
+// set.
+func ParseArgs(f *flag.FlagSet, v interface{}) error {
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Pointer || rv.Elem().Kind() != reflect.Struct {
+		panic("subcommands: ParseArgs of non-pointer to struct " + rv.Type().String())
+	}
+	rv = rv.Elem()
+	fields := argFields(rv.Type())
+	args := f.Args()
+	for _, field := range fields {
+		fv := rv.Field(field.index)
+		if isArgSlice(fv.Type()) {
+			if len(args) == 0 && !field.optional {
+				return fmt.Errorf("missing required argument <%s>", field.name)
+			}
+			s := reflect.MakeSlice(fv.Type(), len(args), len(args))
+			for j, arg := range args {
+				if err := setArg(s.Index(j), arg); err != nil {
+					return fmt.Errorf("invalid argument <%s> %q: %v", field.name, arg, err)
+				}
+			}
+			fv.Set(s)
+			return nil
+		}
+		if len(args) == 0 {
+			if !field.optional {
+				return fmt.Errorf("missing required argument <%s>", field.name)
+			}
+			continue
+		}
+		if err := setArg(fv, args[0]); err != nil {
+			return fmt.Errorf("invalid argument <%s> %q: %v", field.name, args[0], err)
+		}
+		args = args[1:]
+	}
+	if len(args) > 0 {
+		return fmt.Errorf("unexpected argument %q", args[0])
+	}
+	return nil
+}
+
+// An argField is a struct field set by ParseArgs.
+type argField struct {
+	index    int
+	name     string
+	optional bool
+}
+
+// argFields returns the fields of the struct type t that ParseArgs sets,
+// in order. It panics if a slice field is not the last of them.
+func argFields(t reflect.Type) []argField {
+	var fields []argField
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		name, opts, _ := strings.Cut(field.Tag.Get("arg"), ",")
+		if !field.IsExported() || name == "-" {
+			continue
+		}
+		if name == "" {
+			name = strings.ToLower(field.Name)
+		}
+		if n := len(fields); n > 0 && isArgSlice(t.Field(fields[n-1].index).Type) {
+			panic(fmt.Sprintf("subcommands: ParseArgs of %s: slice field %s takes all remaining arguments but is followed by %s",
+				t, t.Field(fields[n-1].index).Name, field.Name))
+		}
+		fields = append(fields, argField{index: i, name: name, optional: opts == "optional"})
+	}
+	return fields
+}
+
+// isArgSlice reports whether ParseArgs sets a field of type t to all
+// remaining arguments: whether it is a slice other than []byte or a
+// type implementing encoding.TextUnmarshaler, such as net.IP.
+func isArgSlice(t reflect.Type) bool {
+	if reflect.PointerTo(t).Implements(reflect.TypeOf((*encoding.TextUnmarshaler)(nil)).Elem()) {
+		return false
+	}
+	return t.Kind() == reflect.Slice && t != reflect.TypeOf([]byte(nil))
+}
+
+// setArg sets v to arg, converted to the type of v.
+func setArg(v reflect.Value, arg string) error {
+	if u, ok := v.Addr().Interface().(encoding.TextUnmarshaler); ok {
+		return u.UnmarshalText([]byte(arg))
+	}
+	if v.Type() == reflect.TypeOf(time.Duration(0)) {
+		d, err := time.ParseDuration(arg)
+		if err != nil {
+			return errors.New("want a duration, such as 1m30s")
+		}
+		v.SetInt(int64(d))
+		return nil
+	}
+	switch v.Kind() {
+	case reflect.String:
+		v.SetString(arg)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(arg)
+		if err != nil {
+			return errors.New("want true or false")
+		}
+		v.SetBool(b)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		n, err := strconv.ParseInt(arg, 0, v.Type().Bits())
+		if err != nil {
+			return errors.New("want an integer")
+		}
+		v.SetInt(n)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		n, err := strconv.ParseUint(arg, 0, v.Type().Bits())
+		if err != nil {
+			return errors.New("want a non-negative integer")
+		}
+		v.SetUint(n)
+	case reflect.Float32, reflect.Float64:
+		x, err := strconv.ParseFloat(arg, v.Type().Bits())
+		if err != nil {
+			return errors.New("want a number")
+		}
+		v.SetFloat(x)
+	default:
+		panic("subcommands: ParseArgs of unsupported field type " + v.Type().String())
+	}
+	return nil
+}
@@ -0,0 +1,238 @@
+/*
+Copyright 2016 Google Inc. All Rights Reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package subcommands
+
+import (
+	"context"
+	"flag"
+	"net"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+type parsedArgs struct {
+	Src     string
+	Dst     string `arg:"dest"`
+	Count   int    `arg:"count,optional"`
+	Skipped string `arg:"-"`
+	private string
+	Rest    []string `arg:"rest,optional"`
+}
+
+func TestParseArgs(t *testing.T) {
+	tests := []struct {
+		args    []string
+		want    parsedArgs
+		wantErr string
+	}{
+		{[]string{"a", "b"}, parsedArgs{Src: "a", Dst: "b", Rest: []string{}}, ""},
+		{[]string{"a", "b", "3"}, parsedArgs{Src: "a", Dst: "b", Count: 3, Rest: []string{}}, ""},
+		{[]string{"a", "b", "0x10", "x", "y"}, parsedArgs{Src: "a", Dst: "b", Count: 16, Rest: []string{"x", "y"}}, ""},
+		{[]string{"a"}, parsedArgs{Src: "a"}, "missing required argument <dest>"},
+		{nil, parsedArgs{}, "missing required argument <src>"},
+		{[]string{"a", "b", "many"}, parsedArgs{Src: "a", Dst: "b"}, `invalid argument <count> "many": want an integer`},
+	}
+	for _, tt := range tests {
+		f := flag.NewFlagSet("test", flag.ContinueOnError)
+		f.Parse(tt.args)
+		var got parsedArgs
+		err := ParseArgs(f, &got)
+		if gotErr := errString(err); gotErr != tt.wantErr {
+			t.Errorf("ParseArgs(%q): error %q, want %q", tt.args, gotErr, tt.wantErr)
+		}
+		if err == nil && !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("ParseArgs(%q) = %+v, want %+v", tt.args, got, tt.want)
+		}
+	}
+}
+
+func TestParseArgsTypes(t *testing.T) {
+	var args struct {
+		B  bool
+		U  uint8
+		F  float64
+		D  time.Duration
+		IP net.IP
+		Ns []int
+	}
+	tests := []struct {
+		args    []string
+		wantErr string
+	}{
+		{[]string{"yes", "255", "1.5", "1m", "10.0.0.1"}, `invalid argument <b> "yes": want true or false`},
+		{[]string{"true", "256", "1.5", "1m", "10.0.0.1"}, `invalid argument <u> "256": want a non-negative integer`},
+		{[]string{"true", "1", "x", "1m", "10.0.0.1"}, `invalid argument <f> "x": want a number`},
+		{[]string{"true", "1", "1", "90", "10.0.0.1"}, `invalid argument <d> "90": want a duration, such as 1m30s`},
+		{[]string{"true", "1", "1", "1m", "host"}, `invalid argument <ip> "host": invalid IP address: host`},
+		{[]string{"true", "1", "1", "1m", "10.0.0.1", "1", "two"}, `invalid argument <ns> "two": want an integer`},
+		{[]string{"true", "1", "1", "1m", "10.0.0.1"}, "missing required argument <ns>"},
+		{[]string{"true", "255", "1.5", "1m30s", "10.0.0.1", "1", "2"}, ""},
+	}
+	for _, tt := range tests {
+		f := flag.NewFlagSet("test", flag.ContinueOnError)
+		f.Parse(tt.args)
+		if got := errString(ParseArgs(f, &args)); got != tt.wantErr {
+			t.Errorf("ParseArgs(%q): error %q, want %q", tt.args, got, tt.wantErr)
+		}
+	}
+	if !args.B || args.U != 255 || args.F != 1.5 || args.D != 90*time.Second || !args.IP.Equal(net.IPv4(10, 0, 0, 1)) || !reflect.DeepEqual(args.Ns, []int{1, 2}) {
+		t.Errorf("ParseArgs set %+v", args)
+	}
+}
+
+func TestParseArgsUnexpected(t *testing.T) {
+	var args struct{ Name string }
+	f := flag.NewFlagSet("test", flag.ContinueOnError)
+	f.Parse([]string{"a", "b"})
+	if got, want := errString(ParseArgs(f, &args)), `unexpected argument "b"`; got != want {
+		t.Errorf("error %q, want %q", got, want)
+	}
+}
+
+func TestParseArgsPanics(t *testing.T) {
+	tests := []struct {
+		name string
+		v    interface{}
+		want string
+	}{
+		{"non-pointer", struct{ A string }{}, "non-pointer to struct"},
+		{"pointer to non-struct", new(string), "non-pointer to struct"},
+		{"slice not last", &struct {
+			Files []string
+			Dst   string
+		}{}, "slice field Files takes all remaining arguments but is followed by Dst"},
+		{"slice before optional", &struct {
+			Files []string
+			Dst   string `arg:"dst,optional"`
+		}{}, "slice field Files takes all remaining arguments but is followed by Dst"},
+		{"unsupported type", &struct{ C complex128 }{}, "unsupported field type complex128"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			defer func() {
+				msg, _ := recover().(string)
+				if !strings.Contains(msg, tt.want) {
+					t.Errorf("panic %q, want it to contain %q", msg, tt.want)
+				}
+			}()
+			f := flag.NewFlagSet("test", flag.ContinueOnError)
+			f.Parse([]string{"x"})
+			ParseArgs(f, tt.v)
+		})
+	}
+}
+
+// An argsCommand is a testCommand declaring its positional arguments.
+type argsCommand struct {
+	testCommand
+	declared string
+}
+
+func (c *argsCommand) Args() string  { return c.declared }
+func (c *argsCommand) Usage() string { return "" }
+
+func TestCheckArgs(t *testing.T) {
+	tests := []struct {
+		declared string
+		args     []string
+		wantErr  string
+	}{
+		{"<src> <dst>", []string{"a", "b"}, ""},
+		{"<src> <dst>", []string{"a"}, "missing required argument <dst>"},
+		{"<src> <dst>", []string{"a", "b", "c"}, `unexpected argument "c"`},
+		{"<src> [dst]", []string{"a"}, ""},
+		{"<files>...", nil, "missing required argument <files>"},
+		{"<files>...", []string{"a", "b", "c"}, ""},
+		{"[files...]", nil, ""},
+	}
+	for _, tt := range tests {
+		cmd := &argsCommand{testCommand{name: "copy"}, tt.declared}
+		if got := errString(checkArgs(cmd, tt.args)); got != tt.wantErr {
+			t.Errorf("%q: checkArgs(%q) = %q, want %q", tt.declared, tt.args, got, tt.wantErr)
+		}
+		if got := errString(checkArgs(Alias("cp", cmd), tt.args)); got != tt.wantErr {
+			t.Errorf("%q: checkArgs(alias, %q) = %q, want %q", tt.declared, tt.args, got, tt.wantErr)
+		}
+	}
+	if err := checkArgs(&testCommand{name: "any"}, []string{"a", "b"}); err != nil {
+		t.Errorf("checkArgs of a command declaring no arguments: %v", err)
+	}
+}
+
+func TestUsageArgs(t *testing.T) {
+	withFlag := func(f *flag.FlagSet) { f.Bool("n", false, "") }
+	tests := []struct {
+		cmd  Command
+		want string
+	}{
+		{&argsCommand{testCommand{name: "copy"}, "<src> <dst>"}, "copy <src> <dst>:\n"},
+		{&argsCommand{testCommand{name: "copy", flags: withFlag}, "<src> [dst...]"}, "copy [<flags>] <src> [dst...]:\n"},
+		{&testCommand{name: "copy"}, "copy:\n\tA command for tests.\n"},
+	}
+	for _, tt := range tests {
+		if got := usage(tt.cmd); got != tt.want {
+			t.Errorf("usage = %q, want %q", got, tt.want)
+		}
+	}
+}
+
+func TestExecuteDeclaredArgs(t *testing.T) {
+	tests := []struct {
+		mode       UsageErrorMode
+		args       []string
+		wantStatus ExitStatus
+		wantStderr string
+	}{
+		{UsageLine, []string{"a", "b"}, ExitSuccess, ""},
+		{UsageLine, []string{"a", "b", "c", "d"}, ExitSuccess, ""},
+		{UsageLine, []string{"a"}, ExitUsageError, "tool: copy: missing required argument <dst>\n"},
+		{UsageFull, nil, ExitUsageError, "missing required argument <src>\ncopy [<flags>] <src> <dst> [more...]:\n  -n\tdry run\n"},
+	}
+	for _, tt := range tests {
+		cdr, stdout, stderr := newTestCommander()
+		cdr.UsageErrors = tt.mode
+		cdr.Register(cdr.HelpCommand(), "")
+		cp := &argsCommand{testCommand{name: "copy", flags: func(f *flag.FlagSet) { f.Bool("n", false, "dry run") }}, "<src> <dst> [more...]"}
+		cdr.Register(cp, "")
+		if status := cdr.Run(context.Background(), "copy", tt.args); status != tt.wantStatus {
+			t.Errorf("%q: status %v, want %v", tt.args, status, tt.wantStatus)
+		}
+		if ran := cp.runs > 0; ran != (tt.wantStatus == ExitSuccess) {
+			t.Errorf("%q: ran %v", tt.args, ran)
+		}
+		if got := stderr.String(); !strings.HasPrefix(got, tt.wantStderr) || tt.wantStderr == "" && got != "" {
+			t.Errorf("%q: stderr %q, want it to start with %q", tt.args, got, tt.wantStderr)
+		}
+
+		// Help shows the usage line naming the arguments.
+		stdout.Reset()
+		cdr.Run(context.Background(), "help", []string{"copy"})
+		if want := "copy [<flags>] <src> <dst> [more...]:\n"; !strings.HasPrefix(stdout.String(), want) {
+			t.Errorf("help copy printed %q, want it to start with %q", stdout.String(), want)
+		}
+	}
+}
+
+// errString returns the message of err, or "" if it is nil.
+func errString(err error) string {
+	if err == nil {
+		return ""
+	}
+	return err.Error()
+}
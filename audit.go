@@ -0,0 +1,69 @@
+/*
+Copyright 2016 Google Inc. All Rights Reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package subcommands
+
+import (
+	"context"
+	"flag"
+	"time"
+)
+
+// An AuditRecord describes one execution of a command.
+type AuditRecord struct {
+	Command  string            `json:"command"`         // Command is the name the command was invoked as.
+	Group    string            `json:"group,omitempty"` // Group is the group the command is registered in.
+	Flags    map[string]string `json:"flags"`           // Flags holds the values of the flags that were set, with secret values replaced by Redacted.
+	Args     []string          `json:"args"`            // Args holds the arguments remaining after the flags.
+	Start    time.Time         `json:"start"`           // Start is when the command started.
+	Duration time.Duration     `json:"duration"`        // Duration is how long the command ran.
+	Status   ExitStatus        `json:"status"`          // Status is the status the command returned.
+}
+
+// Audit returns a Middleware that calls sink with an AuditRecord after
+// each command returns, to keep a trail of executions. Values of flags
+// marked by MarkSecret, or named by a command implementing
+// SecretFlagger, are redacted.
+//
+//	enc := json.NewEncoder(logFile)
+//	cdr.Use(subcommands.Audit(func(r subcommands.AuditRecord) { enc.Encode(r) }))
+func Audit(sink func(AuditRecord)) Middleware {
+	return func(next Executor) Executor {
+		return func(ctx context.Context, cmd Command, f *flag.FlagSet, args ...interface{}) ExitStatus {
+			rec := AuditRecord{
+				Command: cmd.Name(),
+				Group:   GroupFromContext(ctx),
+				Flags:   redactedFlags(f),
+				Args:    f.Args(),
+				Start:   time.Now(),
+			}
+			rec.Status = next(ctx, cmd, f, args...)
+			rec.Duration = time.Since(rec.Start)
+			sink(rec)
+			return rec.Status
+		}
+	}
+}
+
+// redactedFlags returns the values of the flags set in f, with the
+// values of secret flags replaced by Redacted.
+func redactedFlags(f *flag.FlagSet) map[string]string {
+	flags := make(map[string]string)
+	f.Visit(func(fl *flag.Flag) {
+		flags[fl.Name] = shown(f, fl.Name, fl.Value.String())
+	})
+	return flags
+}
@@ -0,0 +1,85 @@
+/*
+Copyright 2016 Google Inc. All Rights Reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package subcommands
+
+import (
+	"flag"
+	"reflect"
+	"testing"
+)
+
+// secretCommand is a testCommand with secret flags.
+type secretCommand struct {
+	testCommand
+	secrets []string
+}
+
+func (c *secretCommand) SecretFlags() []string { return c.secrets }
+
+func TestAudit(t *testing.T) {
+	tests := []struct {
+		args []string
+		want AuditRecord
+	}{
+		{
+			[]string{"login", "-user=ann", "-password=hunter2", "x"},
+			AuditRecord{Command: "login", Group: "auth", Flags: map[string]string{"user": "ann", "password": Redacted}, Args: []string{"x"}, Status: ExitSuccess},
+		},
+		{
+			[]string{"signin", "-token", "abc"},
+			AuditRecord{Command: "signin", Group: "auth", Flags: map[string]string{"token": Redacted}, Args: []string{}, Status: ExitSuccess},
+		},
+		{
+			[]string{"print", "-n"},
+			AuditRecord{Command: "print", Flags: map[string]string{"n": "true"}, Args: []string{}, Status: ExitSuccess},
+		},
+		{
+			[]string{"fail"},
+			AuditRecord{Command: "fail", Flags: map[string]string{}, Args: []string{}, Status: ExitFailure},
+		},
+	}
+	for _, tt := range tests {
+		cdr, _, _ := newTestCommander()
+		login := &secretCommand{
+			testCommand: testCommand{name: "login", flags: func(f *flag.FlagSet) {
+				f.String("user", "", "")
+				f.String("password", "", "")
+				f.String("token", "", "")
+				MarkSecret(f, "token")
+			}},
+			secrets: []string{"password"},
+		}
+		cdr.Register(login, "auth")
+		cdr.Register(Alias("signin", login), "auth")
+		cdr.Register(&testCommand{name: "fail", status: ExitFailure}, "")
+		var got []AuditRecord
+		cdr.Use(Audit(func(r AuditRecord) { got = append(got, r) }))
+
+		execute(t, cdr, tt.args...)
+		if len(got) != 1 {
+			t.Fatalf("%q: got %d records, want 1", tt.args, len(got))
+		}
+		rec := got[0]
+		if rec.Start.IsZero() || rec.Duration < 0 {
+			t.Errorf("%q: start %v, duration %v", tt.args, rec.Start, rec.Duration)
+		}
+		rec.Start, rec.Duration = tt.want.Start, tt.want.Duration
+		if !reflect.DeepEqual(rec, tt.want) {
+			t.Errorf("%q: recorded %+v, want %+v", tt.args, rec, tt.want)
+		}
+	}
+}
@@ -0,0 +1,70 @@
+/*
+Copyright 2016 Google Inc. All Rights Reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package subcommands
+
+import (
+	"context"
+	"flag"
+)
+
+// A PermissionRequirer is a Command that may only be executed by
+// callers holding certain permissions, as checked by the Authorize
+// middleware.
+type PermissionRequirer interface {
+	// RequiredPermissions returns the names of the permissions needed
+	// to execute the command.
+	RequiredPermissions() []string
+}
+
+// An Authorizer reports whether the caller executing cmd with ctx holds
+// the permissions perms, returning a non-nil error saying why not if it
+// does not.
+type Authorizer func(ctx context.Context, cmd Command, perms []string) error
+
+// Authorize returns a Middleware that calls authorize before executing
+// each command implementing PermissionRequirer with a non-empty list of
+// permissions. If authorize returns an error, the command is not
+// executed: "permission denied" and the error are printed to the Error
+// of the Commander, and ExitFailure is returned.
+//
+//	cdr.Use(subcommands.Authorize(func(ctx context.Context, cmd subcommands.Command, perms []string) error {
+//		for _, p := range perms {
+//			if !user.Has(p) {
+//				return fmt.Errorf("%s is required", p)
+//			}
+//		}
+//		return nil
+//	}))
+func Authorize(authorize Authorizer) Middleware {
+	return func(next Executor) Executor {
+		return func(ctx context.Context, cmd Command, f *flag.FlagSet, args ...interface{}) ExitStatus {
+			pr, ok := dealias(cmd).(PermissionRequirer)
+			if !ok {
+				return next(ctx, cmd, f, args...)
+			}
+			perms := pr.RequiredPermissions()
+			if len(perms) == 0 {
+				return next(ctx, cmd, f, args...)
+			}
+			if err := authorize(ctx, cmd, perms); err != nil {
+				Logf(ctx, LevelError, "%s: permission denied: %v", cmd.Name(), err)
+				return ExitFailure
+			}
+			return next(ctx, cmd, f, args...)
+		}
+	}
+}
@@ -0,0 +1,89 @@
+/*
+Copyright 2016 Google Inc. All Rights Reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package subcommands
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"testing"
+)
+
+// permCommand is a testCommand requiring perms.
+type permCommand struct {
+	testCommand
+	perms []string
+}
+
+func (c *permCommand) RequiredPermissions() []string { return c.perms }
+
+func TestAuthorize(t *testing.T) {
+	held := map[string]bool{"read": true}
+	tests := []struct {
+		name       string
+		wantStatus ExitStatus
+		wantRun    bool
+		wantAsked  []string
+		wantStderr string
+	}{
+		{name: "plain", wantStatus: ExitSuccess, wantRun: true},
+		{name: "open", wantStatus: ExitSuccess, wantRun: true},
+		{name: "read", wantStatus: ExitSuccess, wantRun: true, wantAsked: []string{"read"}},
+		{name: "write", wantStatus: ExitFailure, wantAsked: []string{"read", "write"}, wantStderr: "tool: write: permission denied: write is required\n"},
+		{name: "put", wantStatus: ExitFailure, wantAsked: []string{"read", "write"}, wantStderr: "tool: put: permission denied: write is required\n"},
+	}
+	for _, tt := range tests {
+		cdr, _, stderr := newTestCommander()
+		cmds := map[string]*testCommand{}
+		register := func(cmd Command, c *testCommand) {
+			cdr.Register(cmd, "")
+			cmds[cmd.Name()] = c
+		}
+		plain := &testCommand{name: "plain"}
+		register(plain, plain)
+		open := &permCommand{testCommand: testCommand{name: "open"}}
+		register(open, &open.testCommand)
+		read := &permCommand{testCommand: testCommand{name: "read"}, perms: []string{"read"}}
+		register(read, &read.testCommand)
+		write := &permCommand{testCommand: testCommand{name: "write"}, perms: []string{"read", "write"}}
+		register(write, &write.testCommand)
+		register(Alias("put", write), &write.testCommand)
+
+		var asked []string
+		cdr.Use(Authorize(func(ctx context.Context, cmd Command, perms []string) error {
+			asked = perms
+			for _, p := range perms {
+				if !held[p] {
+					return fmt.Errorf("%s is required", p)
+				}
+			}
+			return nil
+		}))
+		if status := execute(t, cdr, tt.name); status != tt.wantStatus {
+			t.Errorf("%s: status %v, want %v", tt.name, status, tt.wantStatus)
+		}
+		if ran := cmds[tt.name].runs > 0; ran != tt.wantRun {
+			t.Errorf("%s: ran %v, want %v", tt.name, ran, tt.wantRun)
+		}
+		if !reflect.DeepEqual(asked, tt.wantAsked) {
+			t.Errorf("%s: asked for %q, want %q", tt.name, asked, tt.wantAsked)
+		}
+		if got := stderr.String(); got != tt.wantStderr {
+			t.Errorf("%s: stderr %q, want %q", tt.name, got, tt.wantStderr)
+		}
+	}
+}
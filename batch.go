@@ -0,0 +1,239 @@
+/*
+Copyright 2016 Google Inc. All Rights Reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package subcommands
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync"
+)
+
+// A batcher is a Command implementing a "batch" command for a given
+// Commander.
+type batcher struct {
+	cdr      *Commander
+	parallel int
+}
+
+func (b *batcher) Name() string     { return "batch" }
+func (b *batcher) Synopsis() string { return "run subcommands listed in files" }
+func (b *batcher) SetFlags(f *flag.FlagSet) {
+	f.IntVar(&b.parallel, "parallel", 1, "run up to `N` command lines at once")
+}
+func (b *batcher) Usage() string {
+	return `batch [-parallel N] [<file>...]:
+	Run each line of the files, or of the standard input if there are
+	none, as a subcommand and its arguments, split at white space. Blank
+	lines and lines starting with # are ignored. With -parallel, up to N
+	lines of different subcommands run at once, and each line of their
+	output is prefixed with the number of the command line. The exit
+	status is the highest status of any command line.
+`
+}
+
+// A batchLine is a command line read by a batcher.
+type batchLine struct {
+	n    int // position of the line among all command lines, from 1
+	argv []string
+}
+
+func (b *batcher) Execute(ctx context.Context, f *flag.FlagSet, args ...interface{}) ExitStatus {
+	cdr := b.cdr.executing(ctx)
+	if b.parallel < 1 {
+		cdr.Errorf("batch: -parallel must be at least 1")
+		return ExitUsageError
+	}
+	lines, err := readBatch(f.Args())
+	if err != nil {
+		cdr.Errorf("batch: %v", err)
+		return ExitFailure
+	}
+
+	if b.parallel == 1 {
+		worst := ExitSuccess
+		for _, line := range lines {
+			if status := cdr.dispatch(ctx, line.argv, args...); status > worst {
+				worst = status
+			}
+		}
+		return worst
+	}
+
+	var (
+		mu    sync.Mutex // guards worst, and writes to cdr.Output and cdr.Error
+		worst = ExitSuccess
+		wg    sync.WaitGroup
+		sem   = make(chan struct{}, b.parallel)
+		// Commands keep their flags in their own fields, so each
+		// Command runs one command line at a time, unless it is
+		// cloned for each.
+		running = make(map[Command]*sync.Mutex)
+	)
+	for _, line := range lines {
+		line := line
+		var cmdMu *sync.Mutex
+		if cmd := cdr.resolve(cdr.expandAlias(line.argv)[0]); cmd != nil && !cloneable(cmd) {
+			if running[cmd] == nil {
+				running[cmd] = new(sync.Mutex)
+			}
+			cmdMu = running[cmd]
+		}
+		sem <- struct{}{}
+		wg.Add(1)
+		go func() {
+			defer func() { <-sem; wg.Done() }()
+			if cmdMu != nil {
+				cmdMu.Lock()
+				defer cmdMu.Unlock()
+			}
+			prefix := fmt.Sprintf("[%d] ", line.n)
+			stdout := &prefixWriter{mu: &mu, w: cdr.Output, prefix: prefix}
+			stderr := &prefixWriter{mu: &mu, w: cdr.Error, prefix: prefix}
+			status := cdr.withWriters(stdout, stderr).dispatch(ctx, line.argv, args...)
+			stdout.Flush()
+			stderr.Flush()
+			mu.Lock()
+			if status > worst {
+				worst = status
+			}
+			mu.Unlock()
+		}()
+	}
+	wg.Wait()
+	return worst
+}
+
+// withWriters returns a copy of cdr writing to stdout and stderr, to run
+// one of the command lines of a batch at once with the others. Commands,
+// the builtins among them, reach it through CommanderFromContext. It
+// shares the top-level flags of cdr, set when the batch command was
+// executed, and leaves them alone.
+func (cdr *Commander) withWriters(stdout, stderr io.Writer) *Commander {
+	c := *cdr
+	c.Output, c.Error = stdout, stderr
+	c.sharedFlags = true
+	return &c
+}
+
+// topUsage returns the function explaining the top-level usage of cdr.
+func (cdr *Commander) topUsage() func() {
+	if cdr.sharedFlags {
+		return func() { cdr.Explain(cdr.Error) }
+	}
+	return cdr.topFlags.Usage
+}
+
+// readBatch reads the command lines in the named files, or in the
+// standard input if there are none.
+func readBatch(paths []string) ([]batchLine, error) {
+	var lines []batchLine
+	if len(paths) == 0 {
+		var err error
+		if lines, err = scanBatch(os.Stdin); err != nil {
+			return nil, err
+		}
+	}
+	for _, path := range paths {
+		file, err := os.Open(path)
+		if err != nil {
+			return nil, err
+		}
+		more, err := scanBatch(file)
+		file.Close()
+		if err != nil {
+			return nil, fmt.Errorf("%s: %v", path, err)
+		}
+		lines = append(lines, more...)
+	}
+	for i := range lines {
+		lines[i].n = i + 1
+	}
+	return lines, nil
+}
+
+// scanBatch reads the command lines in r.
+func scanBatch(r io.Reader) ([]batchLine, error) {
+	var lines []batchLine
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		argv := strings.Fields(scanner.Text())
+		if len(argv) == 0 || strings.HasPrefix(argv[0], "#") {
+			continue
+		}
+		lines = append(lines, batchLine{argv: argv})
+	}
+	return lines, scanner.Err()
+}
+
+// A prefixWriter writes each complete line written to it to w, preceded
+// by prefix, holding mu while it writes.
+type prefixWriter struct {
+	mu     *sync.Mutex
+	w      io.Writer
+	prefix string
+	buf    []byte
+}
+
+func (p *prefixWriter) Write(b []byte) (int, error) {
+	p.buf = append(p.buf, b...)
+	i := bytes.LastIndexByte(p.buf, '\n')
+	if i < 0 {
+		return len(b), nil
+	}
+	err := p.write(p.buf[:i+1])
+	p.buf = p.buf[i+1:]
+	return len(b), err
+}
+
+// Flush writes any incomplete last line.
+func (p *prefixWriter) Flush() error {
+	if len(p.buf) == 0 {
+		return nil
+	}
+	err := p.write(append(p.buf, '\n'))
+	p.buf = nil
+	return err
+}
+
+// write writes the lines in b, each preceded by p.prefix.
+func (p *prefixWriter) write(b []byte) error {
+	var out []byte
+	for _, line := range bytes.SplitAfter(b, []byte("\n")) {
+		if len(line) > 0 {
+			out = append(append(out, p.prefix...), line...)
+		}
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	_, err := p.w.Write(out)
+	return err
+}
+
+// BatchCommand returns a Command which implements a "batch" subcommand,
+// which runs the subcommands listed in files, optionally several at
+// once. For the output of command lines run at once to be told apart,
+// commands must write to the Output and Error of the Commander returned
+// by CommanderFromContext rather than to os.Stdout and os.Stderr.
+func (cdr *Commander) BatchCommand() Command {
+	return &batcher{cdr: cdr}
+}
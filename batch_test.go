@@ -0,0 +1,130 @@
+/*
+Copyright 2016 Google Inc. All Rights Reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package subcommands
+
+import (
+	"bytes"
+	"context"
+	"flag"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestBatch(t *testing.T) {
+	tests := []struct {
+		name       string
+		lines      string
+		parallel   string
+		wantStatus ExitStatus
+		wantStdout []string
+		wantStderr []string
+	}{
+		{
+			name:       "sequential",
+			lines:      "# comment\ncommands\n\nhelp commands\n",
+			parallel:   "1",
+			wantStatus: ExitSuccess,
+			wantStdout: []string{"help\ncommands\nbatch\n", "Print a list of all commands."},
+		},
+		{
+			name:       "parallel builtins",
+			lines:      "commands\nhelp commands\n",
+			parallel:   "2",
+			wantStatus: ExitSuccess,
+			wantStdout: []string{"[1] help\n[1] commands\n[1] batch\n", "[2] commands:\n", "[2] \tPrint a list of all commands."},
+		},
+		{
+			name:       "parallel unknown",
+			lines:      "commands\nnosuch\n",
+			parallel:   "2",
+			wantStatus: ExitUsageError,
+			wantStdout: []string{"[1] help\n"},
+			wantStderr: []string{"[2] Usage: tool <flags> <subcommand> <subcommand args>\n"},
+		},
+		{
+			name:       "parallel help not understood",
+			lines:      "help nosuch\ncommands\n",
+			parallel:   "2",
+			wantStatus: ExitUsageError,
+			wantStderr: []string{"[1] tool: help: subcommand nosuch not understood\n"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "lines")
+			if err := os.WriteFile(path, []byte(tt.lines), 0o666); err != nil {
+				t.Fatal(err)
+			}
+			var stdout, stderr bytes.Buffer
+			cdr := NewCommander(flag.NewFlagSet("tool", flag.ContinueOnError), "tool")
+			cdr.Output, cdr.Error = &stdout, &stderr
+			cdr.Register(cdr.HelpCommand(), "")
+			cdr.Register(cdr.CommandsCommand(), "")
+			cdr.Register(cdr.BatchCommand(), "")
+
+			status := cdr.Run(context.Background(), "batch", []string{"-parallel", tt.parallel, path})
+			if status != tt.wantStatus {
+				t.Errorf("status %v, want %v; stderr:\n%s", status, tt.wantStatus, stderr.String())
+			}
+			for _, want := range tt.wantStdout {
+				if !strings.Contains(stdout.String(), want) {
+					t.Errorf("stdout:\n%s\nwant it to contain %q", stdout.String(), want)
+				}
+			}
+			for _, want := range tt.wantStderr {
+				if !strings.Contains(stderr.String(), want) {
+					t.Errorf("stderr:\n%s\nwant it to contain %q", stderr.String(), want)
+				}
+			}
+		})
+	}
+}
+
+func TestScanBatch(t *testing.T) {
+	tests := []struct {
+		in   string
+		want [][]string
+	}{
+		{"", nil},
+		{"a b\n", [][]string{{"a", "b"}}},
+		{"  a   b  \n\n# c d\nd\n", [][]string{{"a", "b"}, {"d"}}},
+		{"a\n#\n  # x\nb", [][]string{{"a"}, {"b"}}},
+	}
+	for _, tt := range tests {
+		lines, err := scanBatch(strings.NewReader(tt.in))
+		if err != nil {
+			t.Errorf("scanBatch(%q): %v", tt.in, err)
+			continue
+		}
+		var got [][]string
+		for _, line := range lines {
+			got = append(got, line.argv)
+		}
+		if len(got) != len(tt.want) {
+			t.Errorf("scanBatch(%q) = %q, want %q", tt.in, got, tt.want)
+			continue
+		}
+		for i := range got {
+			if strings.Join(got[i], " ") != strings.Join(tt.want[i], " ") {
+				t.Errorf("scanBatch(%q) = %q, want %q", tt.in, got, tt.want)
+				break
+			}
+		}
+	}
+}
@@ -0,0 +1,144 @@
+/*
+Copyright 2016 Google Inc. All Rights Reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package cobra adapts commands written for github.com/spf13/cobra to
+// subcommands, so that a tool can move from one to the other a command
+// at a time:
+//
+//	subcommands.Register(cobra.Command(versionCmd), "")
+//
+// Only the command itself is adapted, not its own subcommands; adapt
+// those separately and Mount a Commander holding them if needed.
+package cobra
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"strings"
+
+	"github.com/google/subcommands"
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+)
+
+// Command returns a subcommands.Command running c. Its flags, local and
+// inherited, are defined under both their names and their shorthands;
+// its synopsis is c.Short and its usage is c.UseLine() followed by
+// c.Long. Execute validates the arguments with c.Args and runs the
+// PreRun, Run and PostRun functions of c, or their E variants, with the
+// context of the invocation set on c. An error is reported through the
+// Commander, like those of other commands, as ExitFailure.
+func Command(c *cobra.Command) subcommands.Command {
+	return &command{c}
+}
+
+type command struct {
+	c *cobra.Command
+}
+
+func (cmd *command) Name() string     { return cmd.c.Name() }
+func (cmd *command) Synopsis() string { return cmd.c.Short }
+
+func (cmd *command) Usage() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "%s:\n", cmd.c.UseLine())
+	long := strings.TrimSpace(cmd.c.Long)
+	if long == "" {
+		long = cmd.c.Short
+	}
+	for _, line := range strings.Split(long, "\n") {
+		fmt.Fprintf(&b, "\t%s\n", line)
+	}
+	return b.String()
+}
+
+func (cmd *command) SetFlags(f *flag.FlagSet) {
+	define := func(pf *pflag.Flag) {
+		if f.Lookup(pf.Name) != nil {
+			return
+		}
+		var v flag.Value = pf.Value
+		if pf.Value.Type() == "bool" {
+			v = boolValue{pf.Value}
+		}
+		f.Var(v, pf.Name, pf.Usage)
+		if pf.Shorthand != "" && f.Lookup(pf.Shorthand) == nil {
+			f.Var(v, pf.Shorthand, "shorthand for -"+pf.Name)
+		}
+	}
+	cmd.c.LocalFlags().VisitAll(define)
+	cmd.c.InheritedFlags().VisitAll(define)
+}
+
+// A boolValue is a pflag.Value of type bool, which may be set without a
+// value.
+type boolValue struct {
+	pflag.Value
+}
+
+func (boolValue) IsBoolFlag() bool { return true }
+
+func (b boolValue) String() string {
+	if b.Value == nil { // the zero value, as made by flag.PrintDefaults
+		return "false"
+	}
+	return b.Value.String()
+}
+
+func (cmd *command) Execute(ctx context.Context, f *flag.FlagSet, _ ...interface{}) subcommands.ExitStatus {
+	c := cmd.c
+	c.SetContext(ctx)
+	if err := run(c, f.Args()); err != nil {
+		if cdr := subcommands.CommanderFromContext(ctx); cdr != nil {
+			cdr.Errorf("%s: %v", c.Name(), err)
+		} else {
+			fmt.Fprintf(c.ErrOrStderr(), "%s: %v\n", c.Name(), err)
+		}
+		return subcommands.ExitFailure
+	}
+	return subcommands.ExitSuccess
+}
+
+// run runs c with args as cobra would once the flags of c are parsed.
+func run(c *cobra.Command, args []string) error {
+	if err := c.ValidateArgs(args); err != nil {
+		return err
+	}
+	if c.PreRunE != nil {
+		if err := c.PreRunE(c, args); err != nil {
+			return err
+		}
+	} else if c.PreRun != nil {
+		c.PreRun(c, args)
+	}
+	switch {
+	case c.RunE != nil:
+		if err := c.RunE(c, args); err != nil {
+			return err
+		}
+	case c.Run != nil:
+		c.Run(c, args)
+	default:
+		return fmt.Errorf("command is not runnable")
+	}
+	if c.PostRunE != nil {
+		return c.PostRunE(c, args)
+	} else if c.PostRun != nil {
+		c.PostRun(c, args)
+	}
+	return nil
+}
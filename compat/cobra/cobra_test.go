@@ -0,0 +1,143 @@
+/*
+Copyright 2016 Google Inc. All Rights Reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cobra
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"reflect"
+	"testing"
+
+	"github.com/google/subcommands"
+	"github.com/spf13/cobra"
+)
+
+func TestCommand(t *testing.T) {
+	tests := []struct {
+		name       string
+		args       []string
+		runErr     error
+		noRun      bool
+		wantStatus subcommands.ExitStatus
+		wantCalls  []string
+		wantStderr string
+	}{
+		{
+			name:       "long flags",
+			args:       []string{"-verbose", "-count=3", "-region=eu", "x"},
+			wantStatus: subcommands.ExitSuccess,
+			wantCalls:  []string{"pre [x]", "run [x] true 3 eu", "post [x]"},
+		},
+		{
+			name:       "shorthands",
+			args:       []string{"-v", "-c", "2", "x"},
+			wantStatus: subcommands.ExitSuccess,
+			wantCalls:  []string{"pre [x]", "run [x] true 2 us", "post [x]"},
+		},
+		{
+			name:       "invalid args",
+			args:       []string{"x", "y"},
+			wantStatus: subcommands.ExitFailure,
+			wantStderr: "tool: deploy: accepts 1 arg(s), received 2\n",
+		},
+		{
+			name:       "run fails",
+			args:       []string{"x"},
+			runErr:     errors.New("no route"),
+			wantStatus: subcommands.ExitFailure,
+			wantCalls:  []string{"pre [x]", "run [x] false 1 us"},
+			wantStderr: "tool: deploy: no route\n",
+		},
+		{
+			name:       "not runnable",
+			args:       []string{"x"},
+			noRun:      true,
+			wantStatus: subcommands.ExitFailure,
+			wantCalls:  []string{"pre [x]"},
+			wantStderr: "tool: deploy: command is not runnable\n",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var calls []string
+			root := &cobra.Command{Use: "tool"}
+			region := root.PersistentFlags().String("region", "us", "region to use")
+			c := &cobra.Command{
+				Use:   "deploy TARGET",
+				Short: "Deploy a target",
+				Long:  "Deploy builds and deploys TARGET.\nIt waits for it to start.",
+				Args:  cobra.ExactArgs(1),
+				PreRun: func(c *cobra.Command, args []string) {
+					calls = append(calls, "pre "+fmtArgs(args))
+				},
+				PostRunE: func(c *cobra.Command, args []string) error {
+					calls = append(calls, "post "+fmtArgs(args))
+					return nil
+				},
+			}
+			verbose := c.Flags().BoolP("verbose", "v", false, "be verbose")
+			count := c.Flags().IntP("count", "c", 1, "number of copies")
+			if !tt.noRun {
+				c.RunE = func(c *cobra.Command, args []string) error {
+					if c.Context() == nil {
+						t.Error("RunE called without a context")
+					}
+					calls = append(calls, fmt.Sprintf("run %s %v %d %s", fmtArgs(args), *verbose, *count, *region))
+					return tt.runErr
+				}
+			}
+			root.AddCommand(c)
+
+			var stderr bytes.Buffer
+			cdr := subcommands.NewCommander(flag.NewFlagSet("tool", flag.ContinueOnError), "tool")
+			cdr.Error = &stderr
+			cdr.Register(Command(c), "")
+			if status := cdr.Run(context.Background(), "deploy", tt.args); status != tt.wantStatus {
+				t.Errorf("status %v, want %v", status, tt.wantStatus)
+			}
+			if !reflect.DeepEqual(calls, tt.wantCalls) {
+				t.Errorf("calls %q, want %q", calls, tt.wantCalls)
+			}
+			if got := stderr.String(); got != tt.wantStderr {
+				t.Errorf("stderr %q, want %q", got, tt.wantStderr)
+			}
+		})
+	}
+}
+
+func TestCommandHelp(t *testing.T) {
+	c := &cobra.Command{Use: "version", Short: "Print the version", Run: func(*cobra.Command, []string) {}}
+	cmd := Command(c)
+	if got, want := cmd.Name(), "version"; got != want {
+		t.Errorf("Name() = %q, want %q", got, want)
+	}
+	if got, want := cmd.Synopsis(), "Print the version"; got != want {
+		t.Errorf("Synopsis() = %q, want %q", got, want)
+	}
+	if got, want := cmd.Usage(), "version:\n\tPrint the version\n"; got != want {
+		t.Errorf("Usage() without Long = %q, want %q", got, want)
+	}
+	c.Long = "Version prints the version\nof the tool.\n"
+	if got, want := cmd.Usage(), "version:\n\tVersion prints the version\n\tof the tool.\n"; got != want {
+		t.Errorf("Usage() = %q, want %q", got, want)
+	}
+}
+
+func fmtArgs(args []string) string { return fmt.Sprint(args) }
@@ -0,0 +1,13 @@
+module github.com/google/subcommands/compat/cobra
+
+go 1.21
+
+replace github.com/google/subcommands => ../..
+
+require (
+	github.com/google/subcommands v0.0.0-00010101000000-000000000000
+	github.com/spf13/cobra v1.10.2
+	github.com/spf13/pflag v1.0.9
+)
+
+require github.com/inconshreveable/mousetrap v1.1.0 // indirect
@@ -0,0 +1,465 @@
+/*
+Copyright 2016 Google Inc. All Rights Reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package subcommands
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// A CompletionDirective tells a completion script what to do besides
+// offering the completion candidates.
+type CompletionDirective int
+
+const (
+	CompleteDefault CompletionDirective = 0 // Complete file names if there are no candidates.
+
+	CompleteNoSpace CompletionDirective = 1 << (iota - 1) // Do not add a space after the completed word.
+	CompleteNoFiles                                       // Do not complete file names if there are no candidates.
+	CompleteFileExt                                       // Complete the names of files with the extensions given as candidates, and directories.
+	CompleteDirs                                          // Complete the names of directories only.
+)
+
+// A valueCompletion is how the values of a flag are completed.
+type valueCompletion struct {
+	directive CompletionDirective
+	exts      []string
+}
+
+// valueCompletions records the flags marked by MarkFileFlag and
+// MarkDirFlag.
+var valueCompletions = struct {
+	sync.Mutex
+	m map[*flag.FlagSet]map[string]valueCompletion
+}{m: make(map[*flag.FlagSet]map[string]valueCompletion)}
+
+// setValueCompletion sets how the values of the named flag in f are
+// completed.
+func setValueCompletion(f *flag.FlagSet, name string, vc valueCompletion) {
+	valueCompletions.Lock()
+	defer valueCompletions.Unlock()
+	if valueCompletions.m[f] == nil {
+		valueCompletions.m[f] = make(map[string]valueCompletion)
+	}
+	valueCompletions.m[f][name] = vc
+}
+
+// MarkFileFlag marks the named flag in f as taking the name of a file,
+// so that shell completion offers the names of files, only those with
+// one of the given extensions ("yaml" or ".yaml") if any are given.
+// Commands call it from SetFlags.
+func MarkFileFlag(f *flag.FlagSet, name string, exts ...string) {
+	vc := valueCompletion{directive: CompleteDefault}
+	if len(exts) > 0 {
+		vc.directive = CompleteFileExt
+		for _, ext := range exts {
+			vc.exts = append(vc.exts, strings.TrimPrefix(ext, "."))
+		}
+	}
+	setValueCompletion(f, name, vc)
+}
+
+// MarkDirFlag marks the named flag in f as taking the name of a
+// directory, so that shell completion offers only the names of
+// directories. Commands call it from SetFlags.
+func MarkDirFlag(f *flag.FlagSet, name string) {
+	setValueCompletion(f, name, valueCompletion{directive: CompleteDirs})
+}
+
+// A completer is a Command implementing the "__complete" command for a
+// given Commander.
+type completer Commander
+
+func (c *completer) Name() string           { return "__complete" }
+func (c *completer) Synopsis() string       { return "complete a command line" }
+func (c *completer) SetFlags(*flag.FlagSet) {}
+func (c *completer) Usage() string {
+	return `__complete -- [<word>...] <partial word>:
+	Print the completions of the partial word of the command line made
+	of the words, one per line, each optionally followed by a tab and a
+	description, and then a line holding a colon and the completion
+	directive, a number. The completion scripts printed by the
+	completion command run it at each press of the tab key.
+`
+}
+
+func (c *completer) Execute(ctx context.Context, f *flag.FlagSet, _ ...interface{}) ExitStatus {
+	cdr := (*Commander)(c).executing(ctx)
+	words, toComplete := f.Args(), ""
+	if len(words) > 0 {
+		words, toComplete = words[:len(words)-1], words[len(words)-1]
+	}
+	candidates, directive := cdr.complete(ctx, words, toComplete)
+	for _, c := range candidates {
+		fmt.Fprintln(cdr.Output, c)
+	}
+	fmt.Fprintf(cdr.Output, ":%d\n", directive)
+	return ExitSuccess
+}
+
+// CompleteCommand returns a Command which implements the "__complete"
+// subcommand run by the scripts of CompletionCommand to complete
+// command lines. Like all commands whose names begin with "__", it is
+// not listed in help output.
+func (cdr *Commander) CompleteCommand() Command {
+	return (*completer)(cdr)
+}
+
+// hidden reports whether cmd is left out of listings of commands, which
+// it is if its name begins with "__".
+func hidden(cmd Command) bool {
+	return strings.HasPrefix(cmd.Name(), "__")
+}
+
+// complete returns the completions of toComplete, the word following
+// words on a command line, and the directive for them. Each completion
+// may be followed by a tab and a description.
+func (cdr *Commander) complete(ctx context.Context, words []string, toComplete string) ([]string, CompletionDirective) {
+	rest, fl := scanFlags(cdr.topFlags, words)
+	if fl != nil {
+		return completeFlagValue(cdr.topFlags, fl, "", toComplete)
+	}
+	if len(rest) == 0 {
+		if strings.HasPrefix(toComplete, "-") {
+			return completeFlag(cdr.topFlags, toComplete)
+		}
+		return cdr.completeCommand(toComplete), CompleteNoFiles
+	}
+
+	rest = cdr.expandAlias(rest)
+	cmd := cdr.resolve(rest[0])
+	if cmd == nil {
+		return nil, CompleteNoFiles
+	}
+	if child := mounted(cmd); child != nil {
+		return child.complete(ctx, rest[1:], toComplete)
+	}
+	f := flag.NewFlagSet(cmd.Name(), flag.ContinueOnError)
+	cmd.SetFlags(f)
+	defer forget(f)
+	rest, fl = scanFlags(f, rest[1:])
+	if fl != nil {
+		return completeFlagValue(f, fl, "", toComplete)
+	}
+	if len(rest) == 0 && strings.HasPrefix(toComplete, "-") {
+		return completeFlag(f, toComplete)
+	}
+	if ac, ok := dealias(cmd).(ArgCompleter); ok {
+		return completeArg(ctx, ac, toComplete)
+	}
+	return nil, CompleteDefault
+}
+
+// An ArgCompleter is a Command that completes its positional arguments,
+// such as the names of resources it acts on, in place of the file names
+// offered by default.
+type ArgCompleter interface {
+	// ValidArgs returns the values of the argument being completed that
+	// begin with toComplete, or nil to offer file names. Each may be
+	// followed by a tab and a description.
+	ValidArgs(ctx context.Context, toComplete string) []string
+}
+
+// completeArg returns the completions of toComplete offered by ac.
+func completeArg(ctx context.Context, ac ArgCompleter, toComplete string) ([]string, CompletionDirective) {
+	valid := ac.ValidArgs(ctx, toComplete)
+	if valid == nil {
+		return nil, CompleteDefault
+	}
+	var candidates []string
+	for _, v := range valid {
+		if strings.HasPrefix(v, toComplete) {
+			candidates = append(candidates, v)
+		}
+	}
+	return candidates, CompleteNoFiles
+}
+
+// completeCommand returns the names of the listed commands and the
+// aliases loaded by LoadAliases beginning with prefix.
+func (cdr *Commander) completeCommand(prefix string) []string {
+	var candidates []string
+	cdr.VisitCommands(func(_ *CommandGroup, cmd Command) {
+		if name := cmd.Name(); strings.HasPrefix(name, prefix) && !hidden(cmd) {
+			candidates = append(candidates, name+"\t"+cdr.synopsis(cmd))
+		}
+	})
+	var aliases []string
+	for name, expansion := range cdr.userAliases {
+		if strings.HasPrefix(name, prefix) && cdr.Lookup(name) == nil {
+			aliases = append(aliases, name+"\t"+strings.Join(expansion, " "))
+		}
+	}
+	sort.Strings(aliases)
+	return append(candidates, aliases...)
+}
+
+// scanFlags skips over the flags in f at the start of words, as
+// f.Parse would, and returns the words that follow them. If the last
+// word is a flag that takes its value from the next word, it returns
+// that flag.
+func scanFlags(f *flag.FlagSet, words []string) ([]string, *flag.Flag) {
+	for i := 0; i < len(words); i++ {
+		w := words[i]
+		if len(w) < 2 || w[0] != '-' || w == "--" {
+			return words[i:], nil
+		}
+		name := strings.TrimLeft(w, "-")
+		if strings.Contains(name, "=") {
+			continue
+		}
+		if fl := f.Lookup(name); fl != nil && !isBoolFlag(fl) {
+			if i++; i == len(words) {
+				return nil, fl
+			}
+		}
+	}
+	return nil, nil
+}
+
+// isBoolFlag reports whether fl may be given without a value.
+func isBoolFlag(fl *flag.Flag) bool {
+	b, ok := fl.Value.(interface{ IsBoolFlag() bool })
+	return ok && b.IsBoolFlag()
+}
+
+// completeFlag returns the completions of toComplete, a word beginning
+// with one or two dashes, as a flag in f or, if it holds "=", as the
+// value of one.
+func completeFlag(f *flag.FlagSet, toComplete string) ([]string, CompletionDirective) {
+	dashes := "-"
+	if strings.HasPrefix(toComplete, "--") {
+		dashes = "--"
+	}
+	prefix := strings.TrimPrefix(toComplete, dashes)
+	if i := strings.Index(prefix, "="); i >= 0 {
+		fl := f.Lookup(prefix[:i])
+		if fl == nil {
+			return nil, CompleteNoFiles
+		}
+		return completeFlagValue(f, fl, toComplete[:len(dashes)+i+1], prefix[i+1:])
+	}
+	var candidates []string
+	f.VisitAll(func(fl *flag.Flag) {
+		if strings.HasPrefix(fl.Name, prefix) {
+			candidates = append(candidates, dashes+fl.Name+"\t"+firstLine(fl.Usage))
+		}
+	})
+	return candidates, CompleteNoFiles
+}
+
+// A Chooser is a flag.Value that accepts only a few values, such as
+// the formats "json", "yaml" and "table". Shell completion offers its
+// choices as the values of the flag, and help output lists them.
+type Chooser interface {
+	flag.Value
+
+	// Choices returns the values accepted by Set.
+	Choices() []string
+}
+
+// completeFlagValue returns the completions of toComplete as the value
+// of fl in f, each preceded by prefix.
+func completeFlagValue(f *flag.FlagSet, fl *flag.Flag, prefix, toComplete string) ([]string, CompletionDirective) {
+	if c, ok := fl.Value.(Chooser); ok {
+		var candidates []string
+		for _, choice := range c.Choices() {
+			if strings.HasPrefix(choice, toComplete) {
+				candidates = append(candidates, prefix+choice)
+			}
+		}
+		return candidates, CompleteNoFiles
+	}
+	valueCompletions.Lock()
+	vc := valueCompletions.m[f][fl.Name]
+	valueCompletions.Unlock()
+	return vc.exts, vc.directive
+}
+
+// A completionScripter is a Command implementing a "completion" command
+// for a given Commander.
+type completionScripter Commander
+
+func (c *completionScripter) Name() string           { return "completion" }
+func (c *completionScripter) Synopsis() string       { return "print a shell completion script" }
+func (c *completionScripter) SetFlags(*flag.FlagSet) {}
+func (c *completionScripter) Usage() string {
+	return `completion bash|zsh|fish:
+	Print a script that sets up the completion of command lines in the
+	given shell, for example by adding to ~/.bashrc:
+		source <(tool completion bash)
+`
+}
+
+func (c *completionScripter) Execute(ctx context.Context, f *flag.FlagSet, _ ...interface{}) ExitStatus {
+	cdr := (*Commander)(c).executing(ctx)
+	if f.NArg() != 1 {
+		f.Usage()
+		return ExitUsageError
+	}
+	script, ok := completionScripts[f.Arg(0)]
+	if !ok {
+		cdr.Errorf("completion: unsupported shell %q", f.Arg(0))
+		return ExitUsageError
+	}
+	r := strings.NewReplacer("NAME", cdr.name, "FUNC", shellIdent.ReplaceAllString(cdr.name, "_"))
+	fmt.Fprint(cdr.Output, r.Replace(script))
+	return ExitSuccess
+}
+
+// CompletionCommand returns a Command which implements a "completion"
+// subcommand, which prints a completion script for bash, zsh or fish.
+// The scripts complete command lines by running the "__complete"
+// subcommand, which must be registered as well:
+//
+//	subcommands.Register(subcommands.DefaultCommander.CompletionCommand(), "")
+//	subcommands.Register(subcommands.DefaultCommander.CompleteCommand(), "")
+//
+// Since the candidates are computed by the program as the user types,
+// the scripts need not be regenerated when commands or flags change.
+func (cdr *Commander) CompletionCommand() Command {
+	return (*completionScripter)(cdr)
+}
+
+// shellIdent matches the characters not allowed in shell function names.
+var shellIdent = regexp.MustCompile(`[^A-Za-z0-9_]`)
+
+// completionScripts holds the completion scripts by shell. NAME is
+// replaced by the name of the program, and FUNC by a form of it usable
+// in function names.
+var completionScripts = map[string]string{
+	"bash": `# bash completion for NAME
+_FUNC_complete() {
+	local line=${COMP_LINE:0:COMP_POINT} cur="" words
+	read -r -a words <<<"$line"
+	if [[ $line != *[[:space:]] ]]; then
+		cur=${words[${#words[@]}-1]}
+		unset 'words[${#words[@]}-1]'
+	fi
+	local out
+	out=$("${words[0]}" __complete -- "${words[@]:1}" "$cur" 2>/dev/null) || return
+	local directive=${out##*:}
+	out=${out%:*}
+
+	# Readline replaces only the text after the last word break.
+	local wb=""
+	if [[ $cur == *["$COMP_WORDBREAKS"]* ]]; then
+		wb=${cur%"${cur##*["$COMP_WORDBREAKS"]}"}
+	fi
+	COMPREPLY=()
+	local c e IFS=$'\n'
+	if (( directive & 8 )); then
+		compopt -o filenames
+		COMPREPLY=($(compgen -d -- "${cur#"$wb"}"))
+		return
+	fi
+	if (( directive & 4 )); then
+		compopt -o filenames
+		for c in $(compgen -f -- "${cur#"$wb"}"); do
+			for e in $out; do
+				if [[ -d $c || $c == *."$e" ]]; then
+					COMPREPLY+=("$c")
+					break
+				fi
+			done
+		done
+		return
+	fi
+	for c in $out; do
+		c=${c%%$'\t'*}
+		COMPREPLY+=("${c#"$wb"}")
+	done
+	if (( directive & 1 )); then
+		compopt -o nospace
+	fi
+	if (( ${#COMPREPLY[@]} == 0 && !(directive & 2) )); then
+		compopt -o filenames
+		COMPREPLY=($(compgen -f -- "${cur#"$wb"}"))
+	fi
+}
+complete -F _FUNC_complete NAME
+`,
+	"zsh": `#compdef NAME
+# zsh completion for NAME
+_FUNC() {
+	local out directive line
+	local -a lines candidates opts
+	out=$("${words[1]}" __complete -- "${(@)words[2,CURRENT-1]}" "${words[CURRENT]}" 2>/dev/null) || return
+	lines=("${(@f)out}")
+	directive=${lines[-1]#:}
+	lines=("${(@)lines[1,-2]}")
+	if (( directive & 12 || (${#lines} == 0 && !(directive & 2)) )); then
+		compset -P '-*='
+	fi
+	if (( directive & 8 )); then
+		_files -/
+		return
+	fi
+	if (( directive & 4 )); then
+		_files -g "*.(${(j:|:)lines})"
+		return
+	fi
+	for line in "${lines[@]}"; do
+		if [[ $line == *$'\t'* ]]; then
+			candidates+=("${${line%%$'\t'*}//:/\\:}:${line#*$'\t'}")
+		else
+			candidates+=("${line//:/\\:}")
+		fi
+	done
+	if (( directive & 1 )); then
+		opts+=(-S '')
+	fi
+	if (( ${#candidates} )); then
+		_describe -t values completions candidates "${opts[@]}"
+	elif (( !(directive & 2) )); then
+		_files
+	fi
+}
+compdef _FUNC NAME
+`,
+	"fish": `# fish completion for NAME
+function __FUNC_complete
+	set -l args (commandline -opc)
+	set -l cur (commandline -ct)
+	set -l out ($args[1] __complete -- $args[2..-1] $cur 2>/dev/null)
+	or return
+	set -l directive (string replace -r '^:' '' -- $out[-1])
+	set -e out[-1]
+	if test (math "bitand($directive, 8)") -ne 0
+		__fish_complete_directories $cur
+		return
+	end
+	if test (math "bitand($directive, 4)") -ne 0
+		for ext in $out
+			__fish_complete_suffix .$ext
+		end
+		return
+	end
+	if test (count $out) -eq 0; and test (math "bitand($directive, 2)") -eq 0
+		__fish_complete_path $cur
+		return
+	end
+	printf '%s\n' $out
+end
+complete -c NAME -f -a '(__FUNC_complete)'
+`,
+}
@@ -0,0 +1,220 @@
+/*
+Copyright 2016 Google Inc. All Rights Reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package subcommands
+
+import (
+	"bytes"
+	"context"
+	"flag"
+	"strings"
+	"testing"
+)
+
+// completions runs the __complete command of cdr with words and returns
+// the lines it prints to stdout, the Output of cdr.
+func completions(t *testing.T, cdr *Commander, stdout *bytes.Buffer, words ...string) []string {
+	t.Helper()
+	if status := cdr.Run(context.Background(), "__complete", append([]string{"--"}, words...)); status != ExitSuccess {
+		t.Fatalf("completing %q: status %v", words, status)
+	}
+	return strings.Split(strings.TrimSuffix(stdout.String(), "\n"), "\n")
+}
+
+func TestComplete(t *testing.T) {
+	tests := []struct {
+		words []string
+		want  []string
+	}{
+		{[]string{""}, []string{"print\tprint args", "help\tdescribe subcommands and their syntax", "get\tget a resource", ":2"}},
+		{[]string{"g"}, []string{"get\tget a resource", ":2"}},
+		{[]string{"x"}, []string{":2"}},
+		{[]string{"-"}, []string{"-level\tlog level", "-v\tverbose", ":2"}},
+		{[]string{"--l"}, []string{"--level\tlog level", ":2"}},
+		{[]string{"-v", "pr"}, []string{"print\tprint args", ":2"}},
+		{[]string{"-level", ""}, []string{":0"}},
+		{[]string{"-level", "info", "pr"}, []string{"print\tprint args", ":2"}},
+		{[]string{"print", "-"}, []string{"-n\tno newline", ":2"}},
+		{[]string{"print", "-n", "-"}, []string{"-n\tno newline", ":2"}},
+		{[]string{"print", "a", "-"}, []string{":0"}},
+		{[]string{"print", ""}, []string{":0"}},
+		{[]string{"nosuch", ""}, []string{":2"}},
+		{[]string{"-nosuch", ""}, []string{"print\tprint args", "help\tdescribe subcommands and their syntax", "get\tget a resource", ":2"}},
+	}
+	for _, tt := range tests {
+		cdr, stdout, _ := newTestCommander()
+		cdr.topFlags.Bool("v", false, "verbose")
+		cdr.topFlags.String("level", "", "log level")
+		cdr.Register(cdr.HelpCommand(), "")
+		cdr.Register(cdr.CompleteCommand(), "")
+		cdr.Register(&testCommand{name: "get", synopsis: "get a resource"}, "")
+		if got := completions(t, cdr, stdout, tt.words...); strings.Join(got, "\n") != strings.Join(tt.want, "\n") {
+			t.Errorf("completing %q: got %q, want %q", tt.words, got, tt.want)
+		}
+	}
+}
+
+func TestCompleteHidden(t *testing.T) {
+	cdr, stdout, _ := newTestCommander()
+	cdr.Register(cdr.HelpCommand(), "")
+	cdr.Register(cdr.CommandsCommand(), "")
+	cdr.Register(cdr.CompleteCommand(), "")
+	for _, name := range []string{"help", "commands"} {
+		stdout.Reset()
+		cdr.Run(context.Background(), name, nil)
+		if strings.Contains(stdout.String(), "__complete") {
+			t.Errorf("%s lists __complete:\n%s", name, stdout)
+		}
+	}
+	stdout.Reset()
+	cdr.Run(context.Background(), "help", []string{"__complete"})
+	if !strings.HasPrefix(stdout.String(), "__complete -- [<word>...] <partial word>:\n") {
+		t.Errorf("help __complete printed %q", stdout)
+	}
+}
+
+func TestCompleteFileFlags(t *testing.T) {
+	tests := []struct {
+		words []string
+		want  []string
+	}{
+		{[]string{"-config", ""}, []string{"yaml", "yml", ":4"}},
+		{[]string{"-config=c"}, []string{"yaml", "yml", ":4"}},
+		{[]string{"-cache", ""}, []string{":8"}},
+		{[]string{"apply", "-f", ""}, []string{":0"}},
+		{[]string{"apply", "-o", ""}, []string{":8"}},
+		{[]string{"apply", "-f", "x", "-o", ""}, []string{":8"}},
+		{[]string{"apply", "-name", ""}, []string{":0"}},
+	}
+	for _, tt := range tests {
+		cdr, stdout, _ := newTestCommander()
+		cdr.topFlags.String("config", "", "")
+		MarkFileFlag(cdr.topFlags, "config", ".yaml", "yml")
+		cdr.topFlags.String("cache", "", "")
+		MarkDirFlag(cdr.topFlags, "cache")
+		cdr.Register(cdr.CompleteCommand(), "")
+		cdr.Register(&testCommand{name: "apply", flags: func(f *flag.FlagSet) {
+			f.String("f", "", "")
+			MarkFileFlag(f, "f")
+			f.String("o", "", "")
+			MarkDirFlag(f, "o")
+			f.String("name", "", "")
+		}}, "")
+		if got := completions(t, cdr, stdout, tt.words...); strings.Join(got, "\n") != strings.Join(tt.want, "\n") {
+			t.Errorf("completing %q: got %q, want %q", tt.words, got, tt.want)
+		}
+	}
+}
+
+// A choice is a Chooser accepting json, yaml or table.
+type choice string
+
+func (c *choice) String() string     { return string(*c) }
+func (c *choice) Set(s string) error { *c = choice(s); return nil }
+func (c *choice) Choices() []string  { return []string{"json", "yaml", "table"} }
+
+func TestCompleteChoices(t *testing.T) {
+	tests := []struct {
+		words []string
+		want  []string
+	}{
+		{[]string{"-format", ""}, []string{"json", "yaml", "table", ":2"}},
+		{[]string{"-format", "y"}, []string{"yaml", ":2"}},
+		{[]string{"-format", "x"}, []string{":2"}},
+		{[]string{"-format=t"}, []string{"-format=table", ":2"}},
+		{[]string{"--format=j"}, []string{"--format=json", ":2"}},
+		{[]string{"show", "-as", "j"}, []string{"json", ":2"}},
+		{[]string{"show", "-as="}, []string{"-as=json", "-as=yaml", "-as=table", ":2"}},
+	}
+	for _, tt := range tests {
+		cdr, stdout, _ := newTestCommander()
+		format := choice("table")
+		cdr.topFlags.Var(&format, "format", "output format")
+		cdr.Register(cdr.CompleteCommand(), "")
+		cdr.Register(&testCommand{name: "show", flags: func(f *flag.FlagSet) {
+			as := choice("json")
+			f.Var(&as, "as", "")
+		}}, "")
+		if got := completions(t, cdr, stdout, tt.words...); strings.Join(got, "\n") != strings.Join(tt.want, "\n") {
+			t.Errorf("completing %q: got %q, want %q", tt.words, got, tt.want)
+		}
+	}
+
+	// Help lists the choices.
+	cdr, _, stderr := newTestCommander()
+	cdr.Register(&testCommand{name: "show", flags: func(f *flag.FlagSet) {
+		as := choice("json")
+		f.Var(&as, "as", "output `format`")
+	}}, "")
+	cdr.Register(cdr.HelpCommand(), "")
+	cdr.Run(context.Background(), "show", []string{"-h"})
+	if want := "  -as format\n    \toutput format (one of: json|yaml|table) (default json)\n"; !strings.HasSuffix(stderr.String(), want) {
+		t.Errorf("help printed %q, want it to end with %q", stderr.String(), want)
+	}
+}
+
+// argCompleter is a testCommand completing its arguments from valid.
+type argCompleter struct {
+	testCommand
+	valid []string
+	got   []string // the arguments of ValidArgs
+}
+
+func (c *argCompleter) ValidArgs(ctx context.Context, toComplete string) []string {
+	c.got = append(c.got, toComplete)
+	return c.valid
+}
+
+func TestCompleteArgs(t *testing.T) {
+	envs := []string{"prod\tproduction", "staging", "stable"}
+	tests := []struct {
+		valid []string
+		words []string
+		want  []string
+	}{
+		{envs, []string{"deploy", ""}, []string{"prod\tproduction", "staging", "stable", ":2"}},
+		{envs, []string{"deploy", "sta"}, []string{"staging", "stable", ":2"}},
+		{envs, []string{"deploy", "prod", "st"}, []string{"staging", "stable", ":2"}},
+		{envs, []string{"deploy", "x"}, []string{":2"}},
+		{envs, []string{"d", "p"}, []string{"prod\tproduction", ":2"}},
+		{envs, []string{"deploy", "-"}, []string{"-region\tregion", ":2"}},
+		{envs, []string{"deploy", "-region", "st"}, []string{":0"}},
+		{envs, []string{"deploy", "-region", "eu", "st"}, []string{"staging", "stable", ":2"}},
+		{nil, []string{"deploy", "st"}, []string{":0"}},
+	}
+	for _, tt := range tests {
+		cdr, stdout, _ := newTestCommander()
+		cdr.Register(cdr.CompleteCommand(), "")
+		deploy := &argCompleter{
+			testCommand: testCommand{name: "deploy", flags: func(f *flag.FlagSet) { f.String("region", "", "region") }},
+			valid:       tt.valid,
+		}
+		cdr.Register(deploy, "")
+		cdr.Register(Alias("d", deploy), "")
+		if got := completions(t, cdr, stdout, tt.words...); strings.Join(got, "\n") != strings.Join(tt.want, "\n") {
+			t.Errorf("completing %q: got %q, want %q", tt.words, got, tt.want)
+		}
+		// ValidArgs is consulted only for positional arguments.
+		toComplete := tt.words[len(tt.words)-1]
+		wantGot := ""
+		if tt.words[len(tt.words)-2] != "-region" && !strings.HasPrefix(toComplete, "-") {
+			wantGot = toComplete
+		}
+		if strings.Join(deploy.got, "\n") != wantGot {
+			t.Errorf("completing %q: ValidArgs called with %q, want %q", tt.words, deploy.got, wantGot)
+		}
+	}
+}
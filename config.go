@@ -0,0 +1,274 @@
+/*
+Copyright 2016 Google Inc. All Rights Reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package subcommands
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"sort"
+)
+
+// ConfigFlag defines a top-level flag with the given name that names a
+// JSON configuration file of flag defaults. The file holds an object
+// whose scalar (or array) members set top-level flags and whose object
+// members set the flags of the subcommand they are named after, which
+// for a subcommand registered through Alias is the command it aliases:
+//
+//	{
+//		"verbose": true,
+//		"print": {"capitalize": true}
+//	}
+//
+// Execute reads the file after selecting a subcommand and before
+// parsing its flags, so that values given on the command line take
+// precedence, unless SetPrecedence says otherwise. An array sets a flag
+// once for each of its elements. It must be called before the top-level
+// flags are parsed.
+func (cdr *Commander) ConfigFlag(name string) {
+	cdr.configFlag = name
+	cdr.topFlags.StringVar(&cdr.configPath, name, "", "read flag defaults from this JSON `file`")
+}
+
+// applyDefaults sets the top-level flags that were not set on the
+// command line, and the flags in f of cmd before it is parsed, from the
+// configuration sources that take precedence below the command line.
+// It leaves alone top-level flags shared with another Commander.
+func (cdr *Commander) applyDefaults(cmd Command, f *flag.FlagSet) error {
+	if cdr.sharedFlags {
+		return cdr.applyLayers(cmd, f, nil, cdr.layers(true))
+	}
+	set := make(map[string]bool)
+	cdr.topFlags.Visit(func(fl *flag.Flag) {
+		set[fl.Name] = true
+		// A flag with a recorded source was set by an earlier dispatch,
+		// as in a chain or batch; only the others come from the command
+		// line.
+		if Provenance(cdr.topFlags, fl.Name) == SourceDefault {
+			record(cdr.topFlags, fl.Name, SourceCommandLine)
+		}
+	})
+	return cdr.applyLayers(cmd, f, set, cdr.layers(true))
+}
+
+// applyOverrides sets the top-level flags, and the flags in f of cmd
+// after it is parsed, from the configuration sources that take
+// precedence above the command line. Like applyDefaults, it leaves alone
+// top-level flags shared with another Commander.
+func (cdr *Commander) applyOverrides(cmd Command, f *flag.FlagSet) error {
+	set := map[string]bool{}
+	if cdr.sharedFlags {
+		set = nil
+	}
+	return cdr.applyLayers(cmd, f, set, cdr.layers(false))
+}
+
+// applyLayers sets the top-level flags not in set, unless set is nil,
+// and the flags in f of cmd, from each of the configuration sources in
+// turn.
+func (cdr *Commander) applyLayers(cmd Command, f *flag.FlagSet, set map[string]bool, sources []Source) error {
+	for _, src := range sources {
+		var err error
+		switch src {
+		case SourceConfig:
+			err = cdr.applyConfig(cmd, f, set)
+		case SourceEnv:
+			err = cdr.applyEnv(cmd, f, set)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// defaultPrecedence is the precedence of configuration sources used
+// unless SetPrecedence is called.
+var defaultPrecedence = []Source{SourceCommandLine, SourceEnv, SourceConfig}
+
+// SetPrecedence sets the precedence of the sources of flag values,
+// highest first. It must list each of SourceCommandLine, SourceEnv and
+// SourceConfig exactly once. The default is
+//
+//	cdr.SetPrecedence(SourceCommandLine, SourceEnv, SourceConfig)
+func (cdr *Commander) SetPrecedence(sources ...Source) error {
+	seen := make(map[Source]bool)
+	for _, src := range sources {
+		switch src {
+		case SourceCommandLine, SourceEnv, SourceConfig:
+		default:
+			return fmt.Errorf("subcommands: invalid source %d in precedence", src)
+		}
+		if seen[src] {
+			return fmt.Errorf("subcommands: source %v listed twice in precedence", src)
+		}
+		seen[src] = true
+	}
+	for _, src := range defaultPrecedence {
+		if !seen[src] {
+			return fmt.Errorf("subcommands: source %v missing from precedence", src)
+		}
+	}
+	cdr.precedence = sources
+	return nil
+}
+
+// Precedence returns the precedence of the sources of flag values,
+// highest first.
+func (cdr *Commander) Precedence() []Source {
+	if cdr.precedence == nil {
+		return defaultPrecedence
+	}
+	return cdr.precedence
+}
+
+// layers returns the configuration sources that take precedence below
+// the command line if below is set, or above it otherwise, lowest first.
+func (cdr *Commander) layers(below bool) []Source {
+	var sources []Source
+	isBelow := false
+	for _, src := range cdr.Precedence() {
+		if src == SourceCommandLine {
+			isBelow = true
+			continue
+		}
+		if isBelow == below {
+			sources = append([]Source{src}, sources...)
+		}
+	}
+	return sources
+}
+
+// applyConfig sets the top-level flags not in set, unless set is nil,
+// and the flags in f of cmd, from the configuration file named by the
+// flag defined by ConfigFlag, if any.
+func (cdr *Commander) applyConfig(cmd Command, f *flag.FlagSet, set map[string]bool) error {
+	if cdr.configPath == "" {
+		return nil
+	}
+	config, err := readConfig(cdr.configPath)
+	if err != nil {
+		return err
+	}
+
+	cmdName := dealias(cmd).Name()
+	for _, key := range sortedKeys(config) {
+		value := config[key]
+		if section, ok := value.(map[string]interface{}); ok {
+			if key != cmdName {
+				if !cdr.mayRegister(key) {
+					return fmt.Errorf("%s: unknown subcommand %q", cdr.configPath, key)
+				}
+				continue
+			}
+			for _, name := range sortedKeys(section) {
+				if err := setConfigFlag(f, name, section[name]); err != nil {
+					return fmt.Errorf("%s: %s.%v", cdr.configPath, key, err)
+				}
+			}
+			continue
+		}
+		if set == nil || set[key] || key == cdr.configFlag {
+			continue
+		}
+		if err := setConfigFlag(cdr.topFlags, key, value); err != nil {
+			return fmt.Errorf("%s: %v", cdr.configPath, err)
+		}
+	}
+	return nil
+}
+
+// readConfig reads the JSON configuration file at path.
+func readConfig(path string) (map[string]interface{}, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	var config map[string]interface{}
+	if err := dec.Decode(&config); err != nil {
+		if serr, ok := err.(*json.SyntaxError); ok {
+			line, col := position(data, serr.Offset)
+			return nil, fmt.Errorf("%s:%d:%d: %v", path, line, col, err)
+		}
+		if terr, ok := err.(*json.UnmarshalTypeError); ok {
+			line, col := position(data, terr.Offset)
+			return nil, fmt.Errorf("%s:%d:%d: configuration must be a JSON object", path, line, col)
+		}
+		return nil, fmt.Errorf("%s: %v", path, err)
+	}
+	return config, nil
+}
+
+// position returns the line and column of the byte of data that
+// encoding/json reports an error at offset for: the last of the offset
+// bytes it had read.
+func position(data []byte, offset int64) (line, col int) {
+	if offset > int64(len(data)) {
+		offset = int64(len(data))
+	}
+	if offset > 0 {
+		offset--
+	}
+	before := data[:offset]
+	line = bytes.Count(before, []byte("\n")) + 1
+	col = len(before) - bytes.LastIndexByte(before, '\n')
+	return line, col
+}
+
+// setConfigFlag sets the flag in f with the given name to a value read
+// from a configuration file.
+func setConfigFlag(f *flag.FlagSet, name string, value interface{}) error {
+	if f.Lookup(name) == nil {
+		return fmt.Errorf("%s: flag not defined", name)
+	}
+	values, ok := value.([]interface{})
+	if !ok {
+		values = []interface{}{value}
+	}
+	for _, v := range values {
+		var s string
+		switch v := v.(type) {
+		case nil:
+			continue
+		case string:
+			s = v
+		case json.Number, bool:
+			s = fmt.Sprint(v)
+		default:
+			return fmt.Errorf("%s: unsupported value %v", name, v)
+		}
+		if err := f.Set(name, s); err != nil {
+			return fmt.Errorf("%s: invalid value %q: %v", name, shown(f, name, s), err)
+		}
+		record(f, name, SourceConfig)
+	}
+	return nil
+}
+
+// sortedKeys returns the keys of m in lexicographical order.
+func sortedKeys(m map[string]interface{}) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
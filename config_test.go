@@ -0,0 +1,227 @@
+/*
+Copyright 2016 Google Inc. All Rights Reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package subcommands
+
+import (
+	"bytes"
+	"context"
+	"flag"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestConfig(t *testing.T) {
+	tests := []struct {
+		name           string
+		config         string
+		args           []string // after -config
+		wantStatus     ExitStatus
+		wantVerbose    bool
+		wantCapitalize bool
+	}{
+		{"empty", `{}`, []string{"print"}, ExitSuccess, false, false},
+		{"top-level flag", `{"verbose": true}`, []string{"print"}, ExitSuccess, true, false},
+		{"section", `{"print": {"capitalize": true}}`, []string{"print"}, ExitSuccess, false, true},
+		{"section through alias", `{"print": {"capitalize": true}}`, []string{"p"}, ExitSuccess, false, true},
+		{"alias is not a section", `{"p": {"capitalize": true}}`, []string{"print"}, ExitSuccess, false, false},
+		{"command line wins", `{"verbose": true, "print": {"capitalize": true}}`, []string{"-verbose=false", "print", "-capitalize=false"}, ExitSuccess, false, false},
+		{"other section", `{"list": {"capitalize": true}}`, []string{"print"}, ExitSuccess, false, false},
+		{"unknown section", `{"nosuch": {}}`, []string{"print"}, ExitUsageError, false, false},
+		{"unknown flag", `{"print": {"nosuch": 1}}`, []string{"print"}, ExitUsageError, false, false},
+		{"malformed", `{"verbose": }`, []string{"print"}, ExitUsageError, false, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "config.json")
+			if err := os.WriteFile(path, []byte(tt.config), 0o666); err != nil {
+				t.Fatal(err)
+			}
+			cdr := NewCommander(flag.NewFlagSet("tool", flag.ContinueOnError), "tool")
+			cdr.Output, cdr.Error = io.Discard, io.Discard
+			verbose := cdr.topFlags.Bool("verbose", false, "")
+			cdr.ConfigFlag("config")
+			var capitalize bool
+			print := &testCommand{
+				name:  "print",
+				flags: func(f *flag.FlagSet) { f.BoolVar(&capitalize, "capitalize", false, "") },
+			}
+			cdr.Register(print, "")
+			cdr.Register(Alias("p", print), "")
+			cdr.Register(&testCommand{name: "list"}, "")
+
+			if err := cdr.topFlags.Parse(append([]string{"-config", path}, tt.args...)); err != nil {
+				t.Fatal(err)
+			}
+			if status := cdr.Execute(context.Background()); status != tt.wantStatus {
+				t.Errorf("status %v, want %v", status, tt.wantStatus)
+			}
+			if tt.wantStatus != ExitSuccess {
+				return
+			}
+			if *verbose != tt.wantVerbose || capitalize != tt.wantCapitalize {
+				t.Errorf("verbose %v, capitalize %v; want %v, %v", *verbose, capitalize, tt.wantVerbose, tt.wantCapitalize)
+			}
+		})
+	}
+}
+
+// A stringList is a flag.Value collecting each value it is set to.
+type stringList []string
+
+func (l *stringList) String() string     { return strings.Join(*l, ",") }
+func (l *stringList) Set(s string) error { *l = append(*l, s); return nil }
+
+func TestConfigValues(t *testing.T) {
+	tests := []struct {
+		config     string
+		wantStderr string // after "tool: <path>"
+		wantTags   []string
+		wantJobs   int
+	}{
+		{`{"print": {"tag": ["a", "b"], "jobs": 3}}`, "", []string{"a", "b"}, 3},
+		{`{"print": {"tag": "a", "jobs": null}}`, "", []string{"a"}, 1},
+		{`{"print": {"jobs": "x"}}`, `: print.jobs: invalid value "x": parse error` + "\n", nil, 1},
+		{`{"print": {"jobs": {"n": 1}}}`, ": print.jobs: unsupported value map[n:1]\n", nil, 1},
+		{`{"jobs": 2}`, ": jobs: flag not defined\n", nil, 1},
+		{"{\n  \"print\": {\n    \"jobs\": 3,\n  }\n}", ":4:3: invalid character '}' looking for beginning of object key string\n", nil, 1},
+		{`["jobs"]`, ":1:1: configuration must be a JSON object\n", nil, 1},
+	}
+	for _, tt := range tests {
+		path := filepath.Join(t.TempDir(), "config.json")
+		if err := os.WriteFile(path, []byte(tt.config), 0o666); err != nil {
+			t.Fatal(err)
+		}
+		var stderr bytes.Buffer
+		cdr := NewCommander(flag.NewFlagSet("tool", flag.ContinueOnError), "tool")
+		cdr.Output, cdr.Error = io.Discard, &stderr
+		cdr.ConfigFlag("config")
+		var tags stringList
+		var jobs int
+		cdr.Register(&testCommand{
+			name: "print",
+			flags: func(f *flag.FlagSet) {
+				f.Var(&tags, "tag", "")
+				f.IntVar(&jobs, "jobs", 1, "")
+			},
+		}, "")
+
+		if err := cdr.topFlags.Parse([]string{"-config", path, "print"}); err != nil {
+			t.Fatal(err)
+		}
+		cdr.Execute(context.Background())
+		wantStderr := ""
+		if tt.wantStderr != "" {
+			wantStderr = "tool: " + path + tt.wantStderr
+		}
+		if got := stderr.String(); got != wantStderr {
+			t.Errorf("%s: stderr %q, want %q", tt.config, got, wantStderr)
+		}
+		if tt.wantStderr != "" {
+			continue
+		}
+		if !reflect.DeepEqual([]string(tags), tt.wantTags) || jobs != tt.wantJobs {
+			t.Errorf("%s: tags %q, jobs %d; want %q, %d", tt.config, tags, jobs, tt.wantTags, tt.wantJobs)
+		}
+	}
+}
+
+func TestSetPrecedence(t *testing.T) {
+	tests := []struct {
+		sources []Source
+		wantErr string
+	}{
+		{[]Source{SourceCommandLine, SourceEnv, SourceConfig}, ""},
+		{[]Source{SourceConfig, SourceCommandLine, SourceEnv}, ""},
+		{[]Source{SourceCommandLine, SourceEnv}, "subcommands: source config file missing from precedence"},
+		{[]Source{SourceCommandLine, SourceEnv, SourceEnv, SourceConfig}, "subcommands: source environment listed twice in precedence"},
+		{[]Source{SourceCommandLine, SourceEnv, SourceConfig, SourceDefault}, "subcommands: invalid source 0 in precedence"},
+	}
+	for _, tt := range tests {
+		cdr := NewCommander(flag.NewFlagSet("tool", flag.ContinueOnError), "tool")
+		err := cdr.SetPrecedence(tt.sources...)
+		if got := errString(err); got != tt.wantErr {
+			t.Errorf("SetPrecedence(%v): error %q, want %q", tt.sources, got, tt.wantErr)
+		}
+		want := tt.sources
+		if err != nil {
+			want = []Source{SourceCommandLine, SourceEnv, SourceConfig}
+		}
+		if got := cdr.Precedence(); !reflect.DeepEqual(got, want) {
+			t.Errorf("SetPrecedence(%v): Precedence() = %v, want %v", tt.sources, got, want)
+		}
+	}
+}
+
+func TestPrecedence(t *testing.T) {
+	tests := []struct {
+		sources []Source
+		args    []string
+		want    string // the value of -name
+		wantSrc Source
+	}{
+		{nil, []string{"-name=cli"}, "cli", SourceCommandLine},
+		{nil, nil, "env", SourceEnv},
+		{[]Source{SourceCommandLine, SourceConfig, SourceEnv}, nil, "config", SourceConfig},
+		{[]Source{SourceEnv, SourceCommandLine, SourceConfig}, []string{"-name=cli"}, "env", SourceEnv},
+		{[]Source{SourceConfig, SourceEnv, SourceCommandLine}, []string{"-name=cli"}, "config", SourceConfig},
+		{[]Source{SourceEnv, SourceConfig, SourceCommandLine}, []string{"-name=cli"}, "env", SourceEnv},
+	}
+	for _, tt := range tests {
+		t.Setenv("TOOL_PRINT_NAME", "env")
+		t.Setenv("TOOL_VERBOSE", "env")
+		path := writeFile(t, "config.json", `{"verbose": "config", "print": {"name": "config"}}`)
+		cdr := NewCommander(flag.NewFlagSet("tool", flag.ContinueOnError), "tool")
+		cdr.Output, cdr.Error = io.Discard, io.Discard
+		verbose := cdr.topFlags.String("verbose", "", "")
+		cdr.ConfigFlag("config")
+		cdr.BindEnv()
+		if tt.sources != nil {
+			if err := cdr.SetPrecedence(tt.sources...); err != nil {
+				t.Fatal(err)
+			}
+		}
+		var name string
+		var src Source
+		cdr.Register(&testCommand{
+			name:  "print",
+			flags: func(f *flag.FlagSet) { f.StringVar(&name, "name", "", "") },
+			execute: func(_ context.Context, f *flag.FlagSet) ExitStatus {
+				src = Provenance(f, "name")
+				return ExitSuccess
+			},
+		}, "")
+
+		topArgs := []string{"-config", path}
+		for _, arg := range tt.args {
+			topArgs = append(topArgs, strings.Replace(arg, "-name", "-verbose", 1))
+		}
+		args := append(append(topArgs, "print"), tt.args...)
+		if status := execute(t, cdr, args...); status != ExitSuccess {
+			t.Fatalf("%v %q: status %v", tt.sources, args, status)
+		}
+		if name != tt.want || src != tt.wantSrc {
+			t.Errorf("%v %q: -name %q from %v, want %q from %v", tt.sources, args, name, src, tt.want, tt.wantSrc)
+		}
+		if *verbose != tt.want || Provenance(cdr.topFlags, "verbose") != tt.wantSrc {
+			t.Errorf("%v %q: -verbose %q from %v, want %q from %v", tt.sources, args, *verbose, Provenance(cdr.topFlags, "verbose"), tt.want, tt.wantSrc)
+		}
+	}
+}
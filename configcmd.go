@@ -0,0 +1,132 @@
+/*
+Copyright 2016 Google Inc. All Rights Reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package subcommands
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"strings"
+	"text/tabwriter"
+)
+
+// A configShower is a Command implementing a "config" command for a
+// given Commander.
+type configShower struct {
+	cdr    *Commander
+	asJSON bool
+}
+
+func (c *configShower) Name() string     { return "config" }
+func (c *configShower) Synopsis() string { return "show the effective configuration" }
+func (c *configShower) SetFlags(f *flag.FlagSet) {
+	f.BoolVar(&c.asJSON, "json", false, "print the configuration as JSON")
+}
+func (c *configShower) Usage() string {
+	return `config [-json] [<subcommand> [<subcommand flags>]]:
+	With an argument, print every flag of <subcommand> with the value
+	it would run with, given the flags that follow, and where that value
+	comes from. Else, print the same for the top-level flags. The
+	sources of values are listed first, highest precedence first.
+`
+}
+
+// A flagSetting describes the effective value of a flag.
+type flagSetting struct {
+	Flag   string `json:"flag"`
+	Value  string `json:"value"`
+	Source string `json:"source"`
+	Env    string `json:"env,omitempty"`
+}
+
+func (c *configShower) Execute(ctx context.Context, f *flag.FlagSet, _ ...interface{}) ExitStatus {
+	cdr := c.cdr.executing(ctx)
+	fs, cmd := cdr.topFlags, Command(nil)
+	if f.NArg() > 0 {
+		if cmd = cdr.Lookup(f.Arg(0)); cmd == nil {
+			cdr.Errorf("config: subcommand %s not understood", f.Arg(0))
+			return ExitFailure
+		}
+		fs = flag.NewFlagSet(cmd.Name(), flag.ContinueOnError)
+		fs.SetOutput(cdr.Error)
+		cmd.SetFlags(fs)
+		defer forget(fs)
+		markSecretFlags(cmd, fs)
+		if err := cdr.applyLayers(cmd, fs, nil, cdr.layers(true)); err != nil {
+			cdr.Errorf("%v", err)
+			return ExitFailure
+		}
+		if err := parse(fs, f.Args()[1:], cdr.flagParser); err != nil {
+			return ExitUsageError
+		}
+		if err := cdr.applyLayers(cmd, fs, nil, cdr.layers(false)); err != nil {
+			cdr.Errorf("%v", err)
+			return ExitFailure
+		}
+		if err := applyLazyDefaults(fs); err != nil {
+			cdr.Errorf("%v", err)
+			return ExitFailure
+		}
+	}
+
+	var precedence []string
+	for _, src := range cdr.Precedence() {
+		precedence = append(precedence, src.String())
+	}
+
+	var settings []flagSetting
+	fs.VisitAll(func(fl *flag.Flag) {
+		s := flagSetting{
+			Flag:   fl.Name,
+			Value:  shown(fs, fl.Name, fl.Value.String()),
+			Source: Provenance(fs, fl.Name).String(),
+		}
+		if cdr.envBound {
+			s.Env = cdr.EnvName(cmd, fl.Name)
+		}
+		settings = append(settings, s)
+	})
+
+	if c.asJSON {
+		enc := json.NewEncoder(cdr.Output)
+		enc.SetIndent("", "  ")
+		config := struct {
+			Precedence []string      `json:"precedence"`
+			Flags      []flagSetting `json:"flags"`
+		}{precedence, settings}
+		if err := enc.Encode(config); err != nil {
+			cdr.Errorf("config: %v", err)
+			return ExitFailure
+		}
+		return ExitSuccess
+	}
+	fmt.Fprintf(cdr.Output, "Precedence: %s\n", strings.Join(precedence, " > "))
+	tw := tabwriter.NewWriter(cdr.Output, 0, 8, 2, ' ', 0)
+	for _, s := range settings {
+		fmt.Fprintf(tw, "-%s\t%s\t(%s)\n", s.Flag, s.Value, s.Source)
+	}
+	tw.Flush()
+	return ExitSuccess
+}
+
+// ConfigCommand returns a Command which implements a "config"
+// subcommand, which shows the value each flag would have and where it
+// would come from.
+func (cdr *Commander) ConfigCommand() Command {
+	return &configShower{cdr: cdr}
+}
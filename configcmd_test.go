@@ -0,0 +1,98 @@
+/*
+Copyright 2016 Google Inc. All Rights Reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package subcommands
+
+import (
+	"context"
+	"strings"
+	"testing"
+)
+
+func TestConfigCommand(t *testing.T) {
+	tests := []struct {
+		args       []string
+		wantStatus ExitStatus
+		wantStdout string
+		wantStderr string
+	}{
+		{nil, ExitSuccess, "Precedence: command line > environment > config file\n" +
+			"-config CONFIG (command line)\n" +
+			"-project p1 (config file)\n", ""},
+		{[]string{"print"}, ExitSuccess, "Precedence: command line > environment > config file\n" +
+			"-n true (environment)\n", ""},
+		{[]string{"print", "-n=false"}, ExitSuccess, "Precedence: command line > environment > config file\n" +
+			"-n false (command line)\n", ""},
+		{[]string{"-json", "print"}, ExitSuccess, `{
+  "precedence": [
+    "command line",
+    "environment",
+    "config file"
+  ],
+  "flags": [
+    {
+      "flag": "n",
+      "value": "true",
+      "source": "environment",
+      "env": "TOOL_PRINT_N"
+    }
+  ]
+}
+`, ""},
+		{[]string{"nosuch"}, ExitFailure, "", "tool: config: subcommand nosuch not understood\n"},
+		{[]string{"print", "-x"}, ExitUsageError, "", "flag provided but not defined: -x\n"},
+	}
+	for _, tt := range tests {
+		t.Setenv("TOOL_PRINT_N", "true")
+		path := writeFile(t, "config.json", `{"project": "p1"}`)
+		cdr, stdout, stderr := newTestCommander()
+		cdr.topFlags.String("project", "", "")
+		cdr.ConfigFlag("config")
+		cdr.BindEnv()
+		cdr.Register(cdr.ConfigCommand(), "")
+		if err := cdr.topFlags.Parse([]string{"-config", path}); err != nil {
+			t.Fatal(err)
+		}
+
+		if status := cdr.Run(context.Background(), "config", tt.args); status != tt.wantStatus {
+			t.Errorf("config %q: status %v, want %v", tt.args, status, tt.wantStatus)
+		}
+		got := stdout.String()
+		if !strings.HasPrefix(got, "{") {
+			// The columns of the table are as wide as the path of the
+			// config file.
+			got = collapseSpace(strings.Replace(got, path, "CONFIG", 1))
+		}
+		if got != tt.wantStdout {
+			t.Errorf("config %q: stdout\n%s\nwant\n%s", tt.args, got, tt.wantStdout)
+		}
+		if !strings.HasPrefix(stderr.String(), tt.wantStderr) {
+			t.Errorf("config %q: stderr %q, want it to begin %q", tt.args, stderr.String(), tt.wantStderr)
+		}
+	}
+}
+
+// collapseSpace replaces each run of spaces within the lines of s with a
+// single space.
+func collapseSpace(s string) string {
+	lines := strings.SplitAfter(s, "\n")
+	for i, line := range lines {
+		if strings.HasSuffix(line, "\n") {
+			lines[i] = strings.Join(strings.Fields(line), " ") + "\n"
+		}
+	}
+	return strings.Join(lines, "")
+}
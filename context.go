@@ -0,0 +1,89 @@
+/*
+Copyright 2016 Google Inc. All Rights Reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package subcommands
+
+import "context"
+
+// An invocation describes the command a Commander is executing.
+type invocation struct {
+	cdr   *Commander
+	name  string
+	group string
+}
+
+// invocationKey is the context key for the invocation.
+type invocationKey struct{}
+
+// withInvocation returns a copy of ctx carrying the Commander executing
+// cmd of the named group.
+func withInvocation(ctx context.Context, cdr *Commander, cmd Command, group string) context.Context {
+	return context.WithValue(ctx, invocationKey{}, &invocation{cdr, cmd.Name(), group})
+}
+
+// CommanderFromContext returns the Commander executing the command that
+// was passed ctx, or nil if there is none. It lets code deep inside a
+// command reach the Commander, for example to explain the command with
+// ExplainCommand.
+func CommanderFromContext(ctx context.Context) *Commander {
+	if inv, ok := ctx.Value(invocationKey{}).(*invocation); ok {
+		return inv.cdr
+	}
+	return nil
+}
+
+// executing returns the Commander executing the command that was passed
+// ctx if it is a copy of cdr, or cdr otherwise. The builtins print
+// through it, so that a copy writing elsewhere, as made by the batch
+// builtin and the serve package for each command line, gets their
+// output rather than cdr.
+func (cdr *Commander) executing(ctx context.Context) *Commander {
+	if c := CommanderFromContext(ctx); c != nil && c.topFlags == cdr.topFlags {
+		return c
+	}
+	return cdr
+}
+
+// NameFromContext returns the name of the command being executed with
+// ctx, as it was invoked, or "" if there is none.
+func NameFromContext(ctx context.Context) string {
+	if inv, ok := ctx.Value(invocationKey{}).(*invocation); ok {
+		return inv.name
+	}
+	return ""
+}
+
+// GroupFromContext returns the name of the group of the command being
+// executed with ctx. It returns "" if there is none, which is also the
+// name of the unnamed group.
+func GroupFromContext(ctx context.Context) string {
+	if inv, ok := ctx.Value(invocationKey{}).(*invocation); ok {
+		return inv.group
+	}
+	return ""
+}
+
+// groupOf returns the name of the group cmd is registered in.
+func (cdr *Commander) groupOf(cmd Command) string {
+	for _, group := range cdr.commands {
+		for _, c := range group.commands {
+			if c == cmd {
+				return group.name
+			}
+		}
+	}
+	return ""
+}
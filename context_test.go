@@ -0,0 +1,63 @@
+/*
+Copyright 2016 Google Inc. All Rights Reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package subcommands
+
+import (
+	"context"
+	"flag"
+	"testing"
+)
+
+func TestFromContext(t *testing.T) {
+	tests := []struct {
+		args      []string
+		wantName  string
+		wantGroup string
+	}{
+		{[]string{"show"}, "show", ""},
+		{[]string{"get"}, "get", "remote"},
+		{[]string{"fetch"}, "fetch", "remote"},
+	}
+	for _, tt := range tests {
+		cdr, _, _ := newTestCommander()
+		var gotCdr *Commander
+		var gotName, gotGroup string
+		record := func(ctx context.Context, f *flag.FlagSet) ExitStatus {
+			gotCdr, gotName, gotGroup = CommanderFromContext(ctx), NameFromContext(ctx), GroupFromContext(ctx)
+			return ExitSuccess
+		}
+		cdr.Register(&testCommand{name: "show", execute: record}, "")
+		get := &testCommand{name: "get", execute: record}
+		cdr.Register(get, "remote")
+		cdr.Register(Alias("fetch", get), "remote")
+
+		if status := execute(t, cdr, tt.args...); status != ExitSuccess {
+			t.Fatalf("%q: status %v", tt.args, status)
+		}
+		if gotCdr != cdr {
+			t.Errorf("%q: CommanderFromContext returned %p, want %p", tt.args, gotCdr, cdr)
+		}
+		if gotName != tt.wantName || gotGroup != tt.wantGroup {
+			t.Errorf("%q: name %q, group %q; want %q, %q", tt.args, gotName, gotGroup, tt.wantName, tt.wantGroup)
+		}
+	}
+
+	ctx := context.Background()
+	if cdr, name, group := CommanderFromContext(ctx), NameFromContext(ctx), GroupFromContext(ctx); cdr != nil || name != "" || group != "" {
+		t.Errorf("outside a command: %p, %q, %q; want nil, \"\", \"\"", cdr, name, group)
+	}
+}
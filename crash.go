@@ -0,0 +1,104 @@
+/*
+Copyright 2016 Google Inc. All Rights Reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package subcommands
+
+import (
+	"flag"
+	"fmt"
+	"runtime/debug"
+	"strings"
+	"time"
+)
+
+// A CrashInfo describes a command that panicked.
+type CrashInfo struct {
+	Command string      // Command is the name of the command.
+	Args    []string    // Args are the arguments of the command, with the values of secret flags replaced by Redacted.
+	Panic   interface{} // Panic is the value the command panicked with.
+	Stack   []byte      // Stack is the stack trace of the panic.
+	Time    time.Time   // Time is when the command panicked.
+}
+
+// SetCrashHandler makes the Commander recover from a panic in a command,
+// or in the middleware and hooks around it, and call handler with a
+// description of the crash, so that the program can write a crash log
+// or ask the user to file a bug. The Commander then prints the panic
+// value and returns ExitFailure. The commands implementing CleanUpper are
+// cleaned up first. A nil handler restores the default, where panics are
+// not recovered.
+//
+//	cdr.SetCrashHandler(func(info subcommands.CrashInfo) {
+//		os.WriteFile("crash.log", info.Stack, 0o600)
+//		fmt.Fprintln(os.Stderr, "Please report this bug, attaching crash.log.")
+//	})
+func (cdr *Commander) SetCrashHandler(handler func(info CrashInfo)) {
+	cdr.crashHandler = handler
+}
+
+// recoverCrash recovers from a panic in cmd, executed with argv, reports
+// it to the crash handler and sets *status to ExitFailure. It must be
+// deferred.
+func (cdr *Commander) recoverCrash(cmd Command, argv []string, status *ExitStatus) {
+	p := recover()
+	if p == nil {
+		return
+	}
+	info := CrashInfo{
+		Command: cmd.Name(),
+		Args:    redactArgs(cmd, argv),
+		Panic:   p,
+		Stack:   debug.Stack(),
+		Time:    time.Now(),
+	}
+	cdr.last.Reason, cdr.last.Err = CommandError, fmt.Errorf("panic: %v", p)
+	cdr.crashHandler(info)
+	cdr.Errorf("%s: panic: %v", cmd.Name(), p)
+	*status = ExitFailure
+}
+
+// redactArgs returns argv, the arguments of cmd, with the values of its
+// secret flags replaced by Redacted.
+func redactArgs(cmd Command, argv []string) []string {
+	f := flag.NewFlagSet(cmd.Name(), flag.ContinueOnError)
+	cmd.SetFlags(f)
+	defer forget(f)
+	markSecretFlags(cmd, f)
+
+	args := append([]string(nil), argv...)
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+		if arg == "--" || len(arg) < 2 || arg[0] != '-' {
+			break
+		}
+		name, value, hasValue := strings.Cut(strings.TrimLeft(arg, "-"), "=")
+		fl := f.Lookup(name)
+		if fl == nil || !IsSecret(f, name) {
+			if fl != nil && !hasValue && !isBoolFlag(fl) {
+				i++ // skip the value
+			}
+			continue
+		}
+		switch {
+		case hasValue:
+			args[i] = strings.TrimSuffix(arg, value) + Redacted
+		case !isBoolFlag(fl) && i+1 < len(args):
+			i++
+			args[i] = Redacted
+		}
+	}
+	return args
+}
@@ -0,0 +1,136 @@
+/*
+Copyright 2016 Google Inc. All Rights Reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package subcommands
+
+import (
+	"context"
+	"flag"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestCrashHandler(t *testing.T) {
+	tests := []struct {
+		name       string
+		args       []string
+		panics     bool
+		wantStatus ExitStatus
+		wantArgs   []string // given to the handler, if called
+		wantStderr string
+	}{
+		{
+			name:       "no panic",
+			args:       []string{"-password=x"},
+			wantStatus: ExitSuccess,
+		},
+		{
+			name:       "panic",
+			args:       []string{"-user", "ann", "-password", "hunter2", "x"},
+			panics:     true,
+			wantStatus: ExitFailure,
+			wantArgs:   []string{"-user", "ann", "-password", Redacted, "x"},
+			wantStderr: "tool: login: panic: oops\n",
+		},
+		{
+			name:       "panic with flag values",
+			args:       []string{"--password=hunter2", "-v", "--", "-password=x"},
+			panics:     true,
+			wantStatus: ExitFailure,
+			wantArgs:   []string{"--password=" + Redacted, "-v", "--", "-password=x"},
+			wantStderr: "tool: login: panic: oops\n",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cdr, _, stderr := newTestCommander()
+			var crashes []CrashInfo
+			cdr.SetCrashHandler(func(info CrashInfo) { crashes = append(crashes, info) })
+			login := &secretCommand{
+				testCommand: testCommand{name: "login", flags: func(f *flag.FlagSet) {
+					f.String("user", "", "")
+					f.String("password", "", "")
+					f.Bool("v", false, "")
+				}, execute: func(context.Context, *flag.FlagSet) ExitStatus {
+					if tt.panics {
+						panic("oops")
+					}
+					return ExitSuccess
+				}},
+				secrets: []string{"password"},
+			}
+			cdr.Register(login, "")
+
+			if status := cdr.Run(context.Background(), "login", tt.args); status != tt.wantStatus {
+				t.Errorf("status %v, want %v", status, tt.wantStatus)
+			}
+			if got := stderr.String(); got != tt.wantStderr {
+				t.Errorf("stderr %q, want %q", got, tt.wantStderr)
+			}
+			if !tt.panics {
+				if len(crashes) != 0 {
+					t.Errorf("handler called with %+v, want no calls", crashes)
+				}
+				return
+			}
+			if len(crashes) != 1 {
+				t.Fatalf("handler called %d times, want 1", len(crashes))
+			}
+			info := crashes[0]
+			if info.Command != "login" || info.Panic != "oops" || info.Time.IsZero() {
+				t.Errorf("handler got command %q, panic %v, time %v", info.Command, info.Panic, info.Time)
+			}
+			if !reflect.DeepEqual(info.Args, tt.wantArgs) {
+				t.Errorf("handler got args %q, want %q", info.Args, tt.wantArgs)
+			}
+			if !strings.Contains(string(info.Stack), "TestCrashHandler") {
+				t.Errorf("stack does not show the test:\n%s", info.Stack)
+			}
+			if err := cdr.LastError(); err == nil || err.Error() != "panic: oops" {
+				t.Errorf("LastError() = %v, want panic: oops", err)
+			}
+		})
+	}
+}
+
+func TestNoCrashHandler(t *testing.T) {
+	cdr, _, _ := newTestCommander()
+	cdr.Register(&testCommand{name: "boom", execute: func(context.Context, *flag.FlagSet) ExitStatus {
+		panic("oops")
+	}}, "")
+	defer func() {
+		if p := recover(); p != "oops" {
+			t.Errorf("recovered %v, want oops", p)
+		}
+	}()
+	cdr.Run(context.Background(), "boom", nil)
+	t.Error("Run returned, want a panic")
+}
+
+func TestCrashHandlerCleanUp(t *testing.T) {
+	cdr, _, _ := newTestCommander()
+	cmd := &lifecycleCommand{testCommand: testCommand{name: "boom"}, panics: true}
+	cdr.Register(cmd, "")
+	var callsAtCrash []string
+	cdr.SetCrashHandler(func(CrashInfo) { callsAtCrash = append([]string(nil), cmd.calls...) })
+	if status := cdr.Run(context.Background(), "boom", nil); status != ExitFailure {
+		t.Errorf("status %v, want %v", status, ExitFailure)
+	}
+	if !strings.Contains(strings.Join(callsAtCrash, ","), "cleanup failure") {
+		t.Errorf("calls before the handler %q, want a clean-up", callsAtCrash)
+	}
+}
@@ -0,0 +1,109 @@
+/*
+Copyright 2016 Google Inc. All Rights Reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package debugcli serves the help of a Commander over HTTP, in the
+// manner of expvar, so that a long-running service embedding a
+// Commander can document its command line at /debug/cli. The paths it
+// serves, relative to where the handler is installed, are
+//
+//	/              the top-level help
+//	/help/NAME     the help for the subcommand or group NAME
+//	/schema.json   the JSON Schema of the config file
+//	/graph.dot     the Graphviz graph of the subcommands
+//
+// DocsCommand serves the same pages from a subcommand, for previewing
+// the help of a build of a program.
+package debugcli
+
+import (
+	"bytes"
+	"net/http"
+	"strings"
+	"sync"
+
+	"github.com/google/subcommands"
+)
+
+// Handler returns an http.Handler serving the help of cdr, for paths
+// relative to where it is installed.
+func Handler(cdr *subcommands.Commander) http.Handler {
+	return &handler{cdr: cdr}
+}
+
+// Register installs Handler(cdr) at /debug/cli/ on http.DefaultServeMux.
+func Register(cdr *subcommands.Commander) {
+	http.Handle("/debug/cli/", http.StripPrefix("/debug/cli", Handler(cdr)))
+}
+
+type handler struct {
+	mu  sync.Mutex // serializes rendering, which sorts the commands of cdr
+	cdr *subcommands.Commander
+}
+
+func (h *handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	cdr := h.cdr
+
+	var buf bytes.Buffer
+	contentType := "text/plain; charset=utf-8"
+	switch path := strings.TrimPrefix(r.URL.Path, "/"); {
+	case path == "":
+		cdr.Explain(&buf)
+	case path == "schema.json":
+		if err := cdr.ConfigSchema(&buf); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		contentType = "application/json"
+	case path == "graph.dot":
+		if err := cdr.CommandGraph(&buf, false); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		contentType = "text/vnd.graphviz"
+	case strings.HasPrefix(path, "help/"):
+		if !h.explain(&buf, strings.TrimPrefix(path, "help/")) {
+			http.NotFound(w, r)
+			return
+		}
+	default:
+		http.NotFound(w, r)
+		return
+	}
+	w.Header().Set("Content-Type", contentType)
+	w.Write(buf.Bytes())
+}
+
+// explain writes the help for the subcommand or group named name to buf,
+// and reports whether there is one.
+func (h *handler) explain(buf *bytes.Buffer, name string) bool {
+	if cmd := h.cdr.Lookup(name); cmd != nil {
+		h.cdr.ExplainCommand(buf, cmd)
+		return true
+	}
+	var group *subcommands.CommandGroup
+	h.cdr.VisitGroups(func(g *subcommands.CommandGroup) {
+		if g.Name() == name && name != "" {
+			group = g
+		}
+	})
+	if group == nil {
+		return false
+	}
+	h.cdr.ExplainGroup(buf, group)
+	return true
+}
@@ -0,0 +1,80 @@
+/*
+Copyright 2016 Google Inc. All Rights Reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package debugcli
+
+import (
+	"context"
+	"flag"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/google/subcommands"
+)
+
+// deployCmd is a Command for the tests.
+type deployCmd struct{ dryRun bool }
+
+func (*deployCmd) Name() string     { return "deploy" }
+func (*deployCmd) Synopsis() string { return "deploy the service" }
+func (*deployCmd) Usage() string    { return "deploy [-dry-run]:\n\tDeploy the service.\n" }
+func (d *deployCmd) SetFlags(f *flag.FlagSet) {
+	f.BoolVar(&d.dryRun, "dry-run", false, "print what would be done")
+}
+func (*deployCmd) Execute(context.Context, *flag.FlagSet, ...interface{}) subcommands.ExitStatus {
+	return subcommands.ExitSuccess
+}
+
+func TestHandler(t *testing.T) {
+	cdr := subcommands.NewCommander(flag.NewFlagSet("tool", flag.ContinueOnError), "tool")
+	cdr.Register(&deployCmd{}, "release")
+	h := Handler(cdr)
+
+	tests := []struct {
+		path            string
+		wantCode        int
+		wantContentType string
+		wantBody        string
+	}{
+		{"/", http.StatusOK, "text/plain; charset=utf-8", "deploy the service"},
+		{"/help/deploy", http.StatusOK, "text/plain; charset=utf-8", "-dry-run"},
+		{"/help/release", http.StatusOK, "text/plain; charset=utf-8", "deploy"},
+		{"/help/nosuch", http.StatusNotFound, "", ""},
+		{"/help/", http.StatusNotFound, "", ""},
+		{"/schema.json", http.StatusOK, "application/json", `"dry-run"`},
+		{"/graph.dot", http.StatusOK, "text/vnd.graphviz", "deploy"},
+		{"/nosuch", http.StatusNotFound, "", ""},
+	}
+	for _, tt := range tests {
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, httptest.NewRequest("GET", tt.path, nil))
+		if rec.Code != tt.wantCode {
+			t.Errorf("GET %s: code %d, want %d", tt.path, rec.Code, tt.wantCode)
+			continue
+		}
+		if tt.wantCode != http.StatusOK {
+			continue
+		}
+		if got := rec.Header().Get("Content-Type"); got != tt.wantContentType {
+			t.Errorf("GET %s: Content-Type %q, want %q", tt.path, got, tt.wantContentType)
+		}
+		if body := rec.Body.String(); !strings.Contains(body, tt.wantBody) {
+			t.Errorf("GET %s: body\n%s\nwant it to contain %q", tt.path, body, tt.wantBody)
+		}
+	}
+}
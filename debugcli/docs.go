@@ -0,0 +1,78 @@
+/*
+Copyright 2016 Google Inc. All Rights Reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package debugcli
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"net"
+	"net/http"
+
+	"github.com/google/subcommands"
+)
+
+// A docsServer is a Command implementing a "docs" command for a given
+// Commander.
+type docsServer struct {
+	cdr  *subcommands.Commander
+	addr string
+}
+
+func (d *docsServer) Name() string     { return "docs" }
+func (d *docsServer) Synopsis() string { return "preview the help of this program in a browser" }
+func (d *docsServer) SetFlags(f *flag.FlagSet) {
+	f.StringVar(&d.addr, "addr", "localhost:6060", "serve on `host:port`")
+}
+func (d *docsServer) Usage() string {
+	return `docs serve [-addr host:port]:
+	Serve the help of every subcommand over HTTP until interrupted. The
+	pages show the help of the running program: they are not regenerated
+	when its source changes, so to preview edits, rebuild the program and
+	run docs serve again.
+`
+}
+
+func (d *docsServer) Execute(ctx context.Context, f *flag.FlagSet, _ ...interface{}) subcommands.ExitStatus {
+	if f.NArg() != 1 || f.Arg(0) != "serve" {
+		f.Usage()
+		return subcommands.ExitUsageError
+	}
+	l, err := net.Listen("tcp", d.addr)
+	if err != nil {
+		d.cdr.Errorf("docs: %v", err)
+		return subcommands.ExitFailure
+	}
+	fmt.Fprintf(d.cdr.Output, "Serving help at http://%s/\n", l.Addr())
+	srv := &http.Server{Handler: Handler(d.cdr)}
+	stop := context.AfterFunc(ctx, func() { srv.Shutdown(context.Background()) })
+	defer stop()
+	if err := srv.Serve(l); !errors.Is(err, http.ErrServerClosed) {
+		d.cdr.Errorf("docs: %v", err)
+		return subcommands.ExitFailure
+	}
+	return subcommands.ExitSuccess
+}
+
+// DocsCommand returns a Command which implements a "docs serve"
+// subcommand, which serves the help of cdr on a local HTTP port, for
+// authors of commands to preview their help. It serves the help of the
+// running program, so it must be restarted after each rebuild.
+func DocsCommand(cdr *subcommands.Commander) subcommands.Command {
+	return &docsServer{cdr: cdr}
+}
@@ -0,0 +1,141 @@
+/*
+Copyright 2016 Google Inc. All Rights Reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package subcommands
+
+import (
+	"flag"
+	"fmt"
+	"reflect"
+	"sort"
+	"sync"
+)
+
+// lazyDefaults records the defaults set by LazyDefault.
+var lazyDefaults = struct {
+	sync.Mutex
+	m map[*flag.FlagSet]map[string]func() (string, error)
+}{m: make(map[*flag.FlagSet]map[string]func() (string, error))}
+
+// defaultTexts records the texts set by SetDefaultText.
+var defaultTexts = struct {
+	sync.Mutex
+	m map[*flag.FlagSet]map[string]string
+}{m: make(map[*flag.FlagSet]map[string]string)}
+
+// LazyDefault sets the default of the named flag in f to the value
+// returned by value, for defaults that are costly or may fail to
+// compute, such as the region of the current cloud configuration. After
+// the flags of a command are parsed, and set from the environment and
+// config files, the Commander calls value only if the flag was not set,
+// and sets the flag to the result. If value fails, the command is not
+// executed. Help shows placeholder, as set by SetDefaultText, in place
+// of the default. Commands call it from SetFlags:
+//
+//	func (c *deployCmd) SetFlags(f *flag.FlagSet) {
+//		f.StringVar(&c.region, "region", "", "region to deploy to")
+//		subcommands.LazyDefault(f, "region", "auto-detected", currentRegion)
+//	}
+func LazyDefault(f *flag.FlagSet, name, placeholder string, value func() (string, error)) {
+	lazyDefaults.Lock()
+	if lazyDefaults.m[f] == nil {
+		lazyDefaults.m[f] = make(map[string]func() (string, error))
+	}
+	lazyDefaults.m[f][name] = value
+	lazyDefaults.Unlock()
+	SetDefaultText(f, name, placeholder)
+}
+
+// SetDefaultText sets the text shown in help, in parentheses, in place
+// of the default of the named flag in f, for defaults that are too long
+// to show or must not be shown, such as a built-in certificate or a
+// token. The default itself is unchanged. Commands call it from
+// SetFlags:
+//
+//	subcommands.SetDefaultText(f, "ca-bundle", "see docs")
+//	subcommands.SetDefaultText(f, "token", "default ****")
+//
+// An empty text shows the default again.
+func SetDefaultText(f *flag.FlagSet, name, text string) {
+	defaultTexts.Lock()
+	defer defaultTexts.Unlock()
+	if text == "" {
+		delete(defaultTexts.m[f], name)
+		return
+	}
+	if defaultTexts.m[f] == nil {
+		defaultTexts.m[f] = make(map[string]string)
+	}
+	defaultTexts.m[f][name] = text
+}
+
+// defaultText returns the text set by SetDefaultText for the named flag
+// in f, if there is one.
+func defaultText(f *flag.FlagSet, name string) (string, bool) {
+	defaultTexts.Lock()
+	defer defaultTexts.Unlock()
+	text, ok := defaultTexts.m[f][name]
+	return text, ok
+}
+
+// applyLazyDefaults sets the flags in f with defaults set by LazyDefault
+// that were not set otherwise, in name order. The flags are left unset
+// as far as f.Visit and Provenance are concerned.
+func applyLazyDefaults(f *flag.FlagSet) error {
+	lazyDefaults.Lock()
+	var names []string
+	for name := range lazyDefaults.m[f] {
+		names = append(names, name)
+	}
+	lazyDefaults.Unlock()
+	sort.Strings(names)
+	for _, name := range names {
+		fl := f.Lookup(name)
+		if fl == nil || Provenance(f, name) != SourceDefault {
+			continue
+		}
+		lazyDefaults.Lock()
+		lazy := lazyDefaults.m[f][name]
+		lazyDefaults.Unlock()
+		value, err := lazy()
+		if err != nil {
+			return fmt.Errorf("cannot determine default of -%s: %v", name, err)
+		}
+		if err := fl.Value.Set(value); err != nil {
+			return fmt.Errorf("invalid default %q for flag -%s: %v", shown(f, name, value), name, err)
+		}
+	}
+	return nil
+}
+
+// zeroString returns the string form of the zero value of the type of v,
+// which flag.FlagSet.PrintDefaults does not show as a default, or "" if
+// that cannot be determined.
+func zeroString(v flag.Value) (s string) {
+	defer func() {
+		if recover() != nil {
+			s = ""
+		}
+	}()
+	t := reflect.TypeOf(v)
+	var z reflect.Value
+	if t.Kind() == reflect.Pointer {
+		z = reflect.New(t.Elem())
+	} else {
+		z = reflect.Zero(t)
+	}
+	return z.Interface().(flag.Value).String()
+}
@@ -0,0 +1,166 @@
+/*
+Copyright 2016 Google Inc. All Rights Reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package subcommands
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"strings"
+	"testing"
+)
+
+func TestLazyDefault(t *testing.T) {
+	tests := []struct {
+		name       string
+		args       []string
+		env        string // TOOL_DEPLOY_REGION, with BindEnv
+		value      string // returned by the lazy default
+		err        error  // returned by the lazy default
+		wantStatus ExitStatus
+		wantCalls  int
+		wantRegion string
+		wantStderr string
+	}{
+		{name: "unset", value: "eu", wantStatus: ExitSuccess, wantCalls: 1, wantRegion: "eu"},
+		{name: "command line", args: []string{"-region=us"}, value: "eu", wantStatus: ExitSuccess, wantRegion: "us"},
+		{name: "set to its zero value", args: []string{"-region="}, value: "eu", wantStatus: ExitSuccess},
+		{name: "environment", env: "asia", value: "eu", wantStatus: ExitSuccess, wantRegion: "asia"},
+		{name: "fails", err: errors.New("no configuration"), wantStatus: ExitFailure, wantCalls: 1, wantStderr: "tool: deploy: cannot determine default of -region: no configuration\n"},
+		{name: "invalid", value: "mars", wantStatus: ExitFailure, wantCalls: 1, wantStderr: "tool: deploy: invalid default \"mars\" for flag -region: unknown region\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cdr, _, stderr := newTestCommander()
+			if tt.env != "" {
+				t.Setenv("TOOL_DEPLOY_REGION", tt.env)
+				cdr.BindEnv()
+			}
+			calls := 0
+			var region string
+			var source Source
+			cdr.Register(&testCommand{
+				name: "deploy",
+				flags: func(f *flag.FlagSet) {
+					f.Var(regionValue{&region}, "region", "region to deploy to")
+					LazyDefault(f, "region", "auto-detected", func() (string, error) {
+						calls++
+						return tt.value, tt.err
+					})
+				},
+				execute: func(_ context.Context, f *flag.FlagSet) ExitStatus {
+					source = Provenance(f, "region")
+					return ExitSuccess
+				},
+			}, "")
+			if status := cdr.Run(context.Background(), "deploy", tt.args); status != tt.wantStatus {
+				t.Errorf("status %v, want %v", status, tt.wantStatus)
+			}
+			if calls != tt.wantCalls {
+				t.Errorf("default computed %d times, want %d", calls, tt.wantCalls)
+			}
+			if tt.wantStatus == ExitSuccess && region != tt.wantRegion {
+				t.Errorf("region %q, want %q", region, tt.wantRegion)
+			}
+			if tt.name == "unset" && source != SourceDefault {
+				t.Errorf("lazy default from %v, want %v", source, SourceDefault)
+			}
+			if got := stderr.String(); got != tt.wantStderr {
+				t.Errorf("stderr %q, want %q", got, tt.wantStderr)
+			}
+		})
+	}
+}
+
+// A regionValue is a flag.Value accepting only known regions, or "".
+type regionValue struct{ p *string }
+
+func (v regionValue) String() string {
+	if v.p == nil {
+		return ""
+	}
+	return *v.p
+}
+
+func (v regionValue) Set(s string) error {
+	switch s {
+	case "", "eu", "us", "asia":
+		*v.p = s
+		return nil
+	}
+	return errors.New("unknown region")
+}
+
+func TestLazyDefaultHelp(t *testing.T) {
+	cdr, _, stderr := newTestCommander()
+	cdr.Register(&testCommand{name: "deploy", flags: func(f *flag.FlagSet) {
+		f.String("region", "", "region to deploy to")
+		LazyDefault(f, "region", "auto-detected", func() (string, error) {
+			t.Error("help computed the lazy default")
+			return "", nil
+		})
+	}}, "")
+	cdr.Run(context.Background(), "deploy", []string{"-h"})
+	if want := "  -region string\n    \tregion to deploy to (auto-detected)\n"; !strings.HasSuffix(stderr.String(), want) {
+		t.Errorf("help printed %q, want it to end with %q", stderr.String(), want)
+	}
+}
+
+func TestSetDefaultText(t *testing.T) {
+	const bundle = "-----BEGIN CERTIFICATE-----"
+	tests := []struct {
+		name string
+		text string
+		args []string // run with help
+		want string   // the end of the help output
+	}{
+		{"command", "see docs", []string{"help", "tls"}, "  -ca string\n    \tCA bundle (see docs)\n"},
+		{"command restored", "", []string{"help", "tls"}, "  -ca string\n    \tCA bundle (default \"" + bundle + "\")\n"},
+		{"top level", "see docs", []string{"flags"}, "  -key string\n    \tclient key (see docs)\n"},
+		{"important", "see docs", []string{"help"}, "  -key=see docs: client key\n"},
+		{"important restored", "", []string{"help"}, "  -key=" + bundle + ": client key\n"},
+	}
+	for _, tt := range tests {
+		cdr, stdout, stderr := newTestCommander()
+		cdr.Register(cdr.HelpCommand(), "")
+		cdr.Register(cdr.FlagsCommand(), "")
+		key := cdr.topFlags.String("key", bundle, "client key")
+		SetDefaultText(cdr.topFlags, "key", "placeholder")
+		SetDefaultText(cdr.topFlags, "key", tt.text)
+		cdr.ImportantFlag("key")
+		var ca string
+		cdr.Register(&testCommand{
+			name: "tls",
+			flags: func(f *flag.FlagSet) {
+				f.StringVar(&ca, "ca", bundle, "CA bundle")
+				SetDefaultText(f, "ca", "placeholder")
+				SetDefaultText(f, "ca", tt.text)
+			},
+		}, "")
+		cdr.Run(context.Background(), tt.args[0], tt.args[1:])
+		// The flags builtin prints the top-level flags to their output.
+		if got := stdout.String() + stderr.String(); !strings.HasSuffix(got, tt.want) {
+			t.Errorf("%s: help printed %q, want it to end with %q", tt.name, got, tt.want)
+		}
+
+		// The default applies all the same.
+		cdr.Run(context.Background(), "tls", nil)
+		if ca != bundle || *key != bundle {
+			t.Errorf("%s: -ca %q, -key %q; want both %q", tt.name, ca, *key, bundle)
+		}
+	}
+}
@@ -0,0 +1,136 @@
+/*
+Copyright 2016 Google Inc. All Rights Reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package subcommands
+
+import (
+	"context"
+	"flag"
+	"fmt"
+)
+
+// A CheckStatus is the outcome of a Check.
+type CheckStatus int
+
+const (
+	CheckPass CheckStatus = iota // Nothing is wrong.
+	CheckWarn                    // Something may be wrong, but the program can work.
+	CheckFail                    // Something is wrong that stops the program from working.
+)
+
+func (s CheckStatus) String() string {
+	switch s {
+	case CheckPass:
+		return "ok"
+	case CheckWarn:
+		return "warn"
+	case CheckFail:
+		return "FAIL"
+	}
+	return "unknown"
+}
+
+// A CheckResult is the result of a Check.
+type CheckResult struct {
+	Status  CheckStatus // Status is the outcome of the check.
+	Message string      // Message describes what the check found.
+	Hint    string      // Hint says how to fix what is wrong, if anything is.
+}
+
+// A Check is a diagnostic run by the doctor builtin, such as whether a
+// server can be reached or credentials have expired.
+type Check struct {
+	Name string                                // Name identifies the check in the report.
+	Run  func(ctx context.Context) CheckResult // Run performs the check.
+}
+
+// A CheckProvider is a Command with checks for the doctor builtin to
+// run, such as of the services the command depends on.
+type CheckProvider interface {
+	// Checks returns the checks of the command.
+	Checks() []Check
+}
+
+// AddChecks adds checks for the doctor builtin to run, before those of
+// the commands implementing CheckProvider.
+func (cdr *Commander) AddChecks(checks ...Check) {
+	cdr.checks = append(cdr.checks, checks...)
+}
+
+// A doctor is a Command implementing a "doctor" command for a given
+// Commander.
+type doctor Commander
+
+func (d *doctor) Name() string           { return "doctor" }
+func (d *doctor) Synopsis() string       { return "check for problems with this program's setup" }
+func (d *doctor) SetFlags(*flag.FlagSet) {}
+func (d *doctor) Usage() string {
+	return `doctor:
+	Run the diagnostic checks of this program and of its subcommands,
+	and report whether each passed, with hints for fixing those that
+	did not. The exit status is 1 if any check failed.
+`
+}
+
+func (d *doctor) Execute(ctx context.Context, f *flag.FlagSet, _ ...interface{}) ExitStatus {
+	cdr := (*Commander)(d).executing(ctx)
+	if f.NArg() != 0 {
+		f.Usage()
+		return ExitUsageError
+	}
+	checks := append([]Check(nil), cdr.checks...)
+	cdr.VisitCommands(func(_ *CommandGroup, cmd Command) {
+		if _, ok := cmd.(*aliaser); ok {
+			return
+		}
+		if p, ok := dealias(cmd).(CheckProvider); ok {
+			checks = append(checks, p.Checks()...)
+		}
+	})
+	if len(checks) == 0 {
+		fmt.Fprintln(cdr.Output, "No checks to run.")
+		return ExitSuccess
+	}
+
+	var counts [3]int
+	for _, check := range checks {
+		r := check.Run(ctx)
+		if r.Status >= CheckPass && r.Status <= CheckFail {
+			counts[r.Status]++
+		}
+		label := "[" + r.Status.String() + "]"
+		fmt.Fprintf(cdr.Output, "%-6s %s", label, check.Name)
+		if r.Message != "" {
+			fmt.Fprintf(cdr.Output, ": %s", r.Message)
+		}
+		fmt.Fprintln(cdr.Output)
+		if r.Hint != "" && r.Status != CheckPass {
+			fmt.Fprintf(cdr.Output, "%-6s hint: %s\n", "", r.Hint)
+		}
+	}
+	fmt.Fprintf(cdr.Output, "\n%d passed, %d with warnings, %d failed\n", counts[CheckPass], counts[CheckWarn], counts[CheckFail])
+	if counts[CheckFail] > 0 {
+		return ExitFailure
+	}
+	return ExitSuccess
+}
+
+// DoctorCommand returns a Command which implements a "doctor"
+// subcommand, which runs the checks added by AddChecks and those of the
+// commands implementing CheckProvider.
+func (cdr *Commander) DoctorCommand() Command {
+	return (*doctor)(cdr)
+}
@@ -0,0 +1,94 @@
+/*
+Copyright 2016 Google Inc. All Rights Reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package subcommands
+
+import (
+	"context"
+	"testing"
+)
+
+// checkCommand is a testCommand with checks for the doctor builtin.
+type checkCommand struct {
+	testCommand
+	checks []Check
+}
+
+func (c *checkCommand) Checks() []Check { return c.checks }
+
+// result returns a Check named name always returning r.
+func result(name string, r CheckResult) Check {
+	return Check{name, func(context.Context) CheckResult { return r }}
+}
+
+func TestDoctor(t *testing.T) {
+	tests := []struct {
+		name       string
+		checks     []Check // added by AddChecks
+		cmdChecks  []Check // of a CheckProvider command
+		args       []string
+		wantStatus ExitStatus
+		want       string
+	}{
+		{
+			name:       "no checks",
+			wantStatus: ExitSuccess,
+			want:       "No checks to run.\n",
+		},
+		{
+			name:       "all pass",
+			checks:     []Check{result("config", CheckResult{CheckPass, "found", "unused"})},
+			cmdChecks:  []Check{result("server", CheckResult{Status: CheckPass})},
+			wantStatus: ExitSuccess,
+			want:       "[ok]   config: found\n[ok]   server\n\n2 passed, 0 with warnings, 0 failed\n",
+		},
+		{
+			name:       "warning",
+			checks:     []Check{result("token", CheckResult{CheckWarn, "expires tomorrow", "run login"})},
+			wantStatus: ExitSuccess,
+			want:       "[warn] token: expires tomorrow\n       hint: run login\n\n0 passed, 1 with warnings, 0 failed\n",
+		},
+		{
+			name:       "failure",
+			checks:     []Check{result("config", CheckResult{Status: CheckPass})},
+			cmdChecks:  []Check{result("server", CheckResult{CheckFail, "unreachable", "check the network"})},
+			wantStatus: ExitFailure,
+			want:       "[ok]   config\n[FAIL] server: unreachable\n       hint: check the network\n\n1 passed, 0 with warnings, 1 failed\n",
+		},
+		{
+			name:       "extra argument",
+			args:       []string{"extra"},
+			wantStatus: ExitUsageError,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cdr, stdout, _ := newTestCommander()
+			cdr.Register(cdr.DoctorCommand(), "")
+			cdr.AddChecks(tt.checks...)
+			cmd := &checkCommand{testCommand{name: "serve"}, tt.cmdChecks}
+			cdr.Register(cmd, "")
+			// An alias runs no checks of its own.
+			cdr.Register(Alias("s", cmd), "")
+			if status := cdr.Run(context.Background(), "doctor", tt.args); status != tt.wantStatus {
+				t.Errorf("status %v, want %v", status, tt.wantStatus)
+			}
+			if got := stdout.String(); got != tt.want {
+				t.Errorf("printed %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
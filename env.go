@@ -0,0 +1,170 @@
+/*
+Copyright 2016 Google Inc. All Rights Reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package subcommands
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// BindEnv binds each flag to an environment variable, named by
+// cdr.EnvName, that sets the flag when it is not set on the command
+// line. By default the name is derived from the commander, subcommand
+// and flag names, as in MYTOOL_PRINT_CAPITALIZE for the -capitalize
+// flag of the print subcommand of mytool, or MYTOOL_VERBOSE for the
+// top-level -verbose flag. Environment variables take precedence over
+// a configuration file read through ConfigFlag, and the variable for
+// each flag is shown in its help.
+func (cdr *Commander) BindEnv() {
+	cdr.envBound = true
+}
+
+// envName derives the name of the environment variable for the flag
+// with the given name of cmd, or of the top level if cmd is nil. An
+// alias shares the variables of the command it aliases.
+func (cdr *Commander) envName(cmd Command, name string) string {
+	parts := []string{cdr.DisplayName()}
+	if cmd != nil {
+		parts = append(parts, dealias(cmd).Name())
+	}
+	parts = append(parts, name)
+	return strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'A' && r <= 'Z', r >= '0' && r <= '9':
+			return r
+		case r >= 'a' && r <= 'z':
+			return r - 'a' + 'A'
+		}
+		return '_'
+	}, strings.Join(parts, "_"))
+}
+
+// applyEnv sets the top-level flags not in set, unless set is nil, and
+// the flags in f of cmd, from the environment variables bound to them,
+// if BindEnv has been called.
+func (cdr *Commander) applyEnv(cmd Command, f *flag.FlagSet, set map[string]bool) error {
+	if !cdr.envBound {
+		return nil
+	}
+	var err error
+	apply := func(cmd Command, f *flag.FlagSet) func(*flag.Flag) {
+		return func(fl *flag.Flag) {
+			if err != nil || (cmd == nil && set[fl.Name]) {
+				return
+			}
+			key := cdr.EnvName(cmd, fl.Name)
+			value, ok := cdr.LookupEnv(key)
+			if !ok {
+				return
+			}
+			if serr := f.Set(fl.Name, value); serr != nil {
+				err = fmt.Errorf("environment variable %s: invalid value %q: %v", key, shown(f, fl.Name, value), serr)
+				return
+			}
+			record(f, fl.Name, SourceEnv)
+		}
+	}
+	if set != nil {
+		cdr.topFlags.VisitAll(apply(nil, cdr.topFlags))
+	}
+	f.VisitAll(apply(cmd, f))
+	return err
+}
+
+// LoadDotEnv reads environment variables for the commander from the
+// named dotenv files, or from ".env" in the current directory if none
+// are named. Each line of a file has the form
+//
+//	[export] KEY=value
+//
+// where the value may be enclosed in single or double quotes, and
+// blank lines and lines starting with '#' are ignored. Missing files
+// are not an error. The variables are not added to the process
+// environment: they are seen only through LookupEnv, where real
+// environment variables take precedence over them, and earlier files
+// take precedence over later ones.
+func (cdr *Commander) LoadDotEnv(paths ...string) error {
+	if len(paths) == 0 {
+		paths = []string{".env"}
+	}
+	if cdr.dotEnv == nil {
+		cdr.dotEnv = make(map[string]string)
+	}
+	for _, path := range paths {
+		cdr.dotEnvFiles = append(cdr.dotEnvFiles, path)
+		if err := readDotEnv(path, cdr.dotEnv); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// LookupEnv returns the value of the environment variable named by key,
+// as found in the process environment or else in the files read by
+// LoadDotEnv. The boolean is false if the variable is in neither.
+func (cdr *Commander) LookupEnv(key string) (string, bool) {
+	if value, ok := os.LookupEnv(key); ok {
+		return value, true
+	}
+	value, ok := cdr.dotEnv[key]
+	return value, ok
+}
+
+// readDotEnv adds the variables in the dotenv file at path to env,
+// except for those env already holds.
+func readDotEnv(path string, env map[string]string) error {
+	file, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	for n := 1; scanner.Scan(); n++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		line = strings.TrimPrefix(line, "export ")
+		i := strings.Index(line, "=")
+		if i < 0 {
+			return fmt.Errorf("%s:%d: expected KEY=value", path, n)
+		}
+		key, value := strings.TrimSpace(line[:i]), strings.TrimSpace(line[i+1:])
+		if key == "" || strings.ContainsAny(key, " \t") {
+			return fmt.Errorf("%s:%d: invalid variable name %q", path, n, key)
+		}
+		if len(value) >= 2 && (value[0] == '"' || value[0] == '\'') && value[len(value)-1] == value[0] {
+			value = value[1 : len(value)-1]
+		} else if j := strings.Index(value, " #"); j >= 0 {
+			value = strings.TrimSpace(value[:j])
+		}
+		if _, ok := env[key]; !ok {
+			env[key] = value
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return fmt.Errorf("%s: %v", path, err)
+	}
+	return nil
+}
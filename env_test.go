@@ -0,0 +1,173 @@
+/*
+Copyright 2016 Google Inc. All Rights Reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package subcommands
+
+import (
+	"context"
+	"flag"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestBindEnv(t *testing.T) {
+	tests := []struct {
+		name           string
+		env            map[string]string
+		unbound        bool // do not call BindEnv
+		args           []string
+		wantStatus     ExitStatus
+		wantVerbose    bool
+		wantCapitalize bool
+	}{
+		{"none", nil, false, []string{"print"}, ExitSuccess, false, false},
+		{"top-level flag", map[string]string{"TOOL_VERBOSE": "1"}, false, []string{"print"}, ExitSuccess, true, false},
+		{"command flag", map[string]string{"TOOL_PRINT_CAPITALIZE": "true"}, false, []string{"print"}, ExitSuccess, false, true},
+		{"command flag through alias", map[string]string{"TOOL_PRINT_CAPITALIZE": "true"}, false, []string{"p"}, ExitSuccess, false, true},
+		{"alias has no variables", map[string]string{"TOOL_P_CAPITALIZE": "true"}, false, []string{"p"}, ExitSuccess, false, false},
+		{"command line wins", map[string]string{"TOOL_VERBOSE": "1", "TOOL_PRINT_CAPITALIZE": "1"}, false, []string{"-verbose=false", "print", "-capitalize=false"}, ExitSuccess, false, false},
+		{"unbound", map[string]string{"TOOL_VERBOSE": "1"}, true, []string{"print"}, ExitSuccess, false, false},
+		{"invalid value", map[string]string{"TOOL_PRINT_CAPITALIZE": "maybe"}, false, []string{"print"}, ExitUsageError, false, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for k, v := range tt.env {
+				t.Setenv(k, v)
+			}
+			cdr := NewCommander(flag.NewFlagSet("tool", flag.ContinueOnError), "tool")
+			cdr.Output, cdr.Error = io.Discard, io.Discard
+			verbose := cdr.topFlags.Bool("verbose", false, "")
+			if !tt.unbound {
+				cdr.BindEnv()
+			}
+			var capitalize bool
+			print := &testCommand{
+				name:  "print",
+				flags: func(f *flag.FlagSet) { f.BoolVar(&capitalize, "capitalize", false, "") },
+			}
+			cdr.Register(print, "")
+			cdr.Register(Alias("p", print), "")
+
+			if err := cdr.topFlags.Parse(tt.args); err != nil {
+				t.Fatal(err)
+			}
+			if status := cdr.Execute(context.Background()); status != tt.wantStatus {
+				t.Errorf("status %v, want %v", status, tt.wantStatus)
+			}
+			if tt.wantStatus != ExitSuccess {
+				return
+			}
+			if *verbose != tt.wantVerbose || capitalize != tt.wantCapitalize {
+				t.Errorf("verbose %v, capitalize %v; want %v, %v", *verbose, capitalize, tt.wantVerbose, tt.wantCapitalize)
+			}
+		})
+	}
+}
+
+func TestEnvName(t *testing.T) {
+	cdr := NewCommander(flag.NewFlagSet("my-tool", flag.ContinueOnError), "my-tool")
+	print := &testCommand{name: "print"}
+	tests := []struct {
+		cmd  Command
+		flag string
+		want string
+	}{
+		{nil, "verbose", "MY_TOOL_VERBOSE"},
+		{print, "capitalize", "MY_TOOL_PRINT_CAPITALIZE"},
+		{Alias("p", print), "capitalize", "MY_TOOL_PRINT_CAPITALIZE"},
+		{print, "dry-run", "MY_TOOL_PRINT_DRY_RUN"},
+	}
+	for _, tt := range tests {
+		if got := cdr.EnvName(tt.cmd, tt.flag); got != tt.want {
+			t.Errorf("EnvName(%v, %q) = %q, want %q", tt.cmd, tt.flag, got, tt.want)
+		}
+	}
+}
+
+func TestLoadDotEnv(t *testing.T) {
+	tests := []struct {
+		content string
+		want    map[string]string
+		wantErr string // after the path
+	}{
+		{"", map[string]string{}, ""},
+		{"# comment\n\nA=1\nexport B = two words \n", map[string]string{"A": "1", "B": "two words"}, ""},
+		{"A=\"quoted # not a comment\"\nB='single'\nC=x # comment\nD=\n", map[string]string{"A": "quoted # not a comment", "B": "single", "C": "x", "D": ""}, ""},
+		{"A=1\nA=2\n", map[string]string{"A": "1"}, ""},
+		{"A\n", nil, ":1: expected KEY=value"},
+		{"A=1\nB C=2\n", nil, `:2: invalid variable name "B C"`},
+	}
+	for _, tt := range tests {
+		path := writeFile(t, ".env", tt.content)
+		cdr := NewCommander(flag.NewFlagSet("tool", flag.ContinueOnError), "tool")
+		err := cdr.LoadDotEnv(path)
+		if tt.wantErr != "" {
+			if err == nil || err.Error() != path+tt.wantErr {
+				t.Errorf("%q: error %v, want %s%s", tt.content, err, path, tt.wantErr)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%q: %v", tt.content, err)
+			continue
+		}
+		if !reflect.DeepEqual(cdr.dotEnv, tt.want) {
+			t.Errorf("%q: read %q, want %q", tt.content, cdr.dotEnv, tt.want)
+		}
+	}
+}
+
+func TestLookupEnv(t *testing.T) {
+	first := writeFile(t, "first.env", "TOOL_A=first\nTOOL_B=first\nTOOL_PRINT_B=first\n")
+	second := writeFile(t, "second.env", "TOOL_B=second\nTOOL_C=second\n")
+	missing := filepath.Join(t.TempDir(), "missing.env")
+	t.Setenv("TOOL_C", "process")
+
+	cdr := NewCommander(flag.NewFlagSet("tool", flag.ContinueOnError), "tool")
+	if err := cdr.LoadDotEnv(first, missing, second); err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		key    string
+		want   string
+		wantOK bool
+	}{
+		{"TOOL_A", "first", true},
+		{"TOOL_B", "first", true},
+		{"TOOL_C", "process", true},
+		{"TOOL_D", "", false},
+	}
+	for _, tt := range tests {
+		if got, ok := cdr.LookupEnv(tt.key); got != tt.want || ok != tt.wantOK {
+			t.Errorf("LookupEnv(%s) = %q, %v; want %q, %v", tt.key, got, ok, tt.want, tt.wantOK)
+		}
+	}
+	if _, ok := os.LookupEnv("TOOL_A"); ok {
+		t.Error("LoadDotEnv set TOOL_A in the process environment")
+	}
+
+	// Variables from dotenv files set flags bound by BindEnv.
+	cdr.BindEnv()
+	var b string
+	cdr.Register(&testCommand{name: "print", flags: func(f *flag.FlagSet) { f.StringVar(&b, "b", "", "") }}, "")
+	cdr.Output, cdr.Error = io.Discard, io.Discard
+	if status := cdr.Run(context.Background(), "print", nil); status != ExitSuccess || b != "first" {
+		t.Errorf("print: status %v, -b %q; want %v, %q", status, b, ExitSuccess, "first")
+	}
+}
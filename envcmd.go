@@ -0,0 +1,163 @@
+/*
+Copyright 2016 Google Inc. All Rights Reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package subcommands
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// An envPrinter is a Command implementing an "env" command for a given
+// Commander.
+type envPrinter struct {
+	cdr    *Commander
+	asJSON bool
+}
+
+func (e *envPrinter) Name() string     { return "env" }
+func (e *envPrinter) Synopsis() string { return "print the environment and files this program reads" }
+func (e *envPrinter) SetFlags(f *flag.FlagSet) {
+	f.BoolVar(&e.asJSON, "json", false, "print as JSON")
+}
+func (e *envPrinter) Usage() string {
+	return `env [-json]:
+	Print where this program looks for its settings, for reporting
+	problems: the config file and dotenv files it reads, the values of
+	the top-level flags and where they came from, and the environment
+	variables bound to flags, with their values.
+`
+}
+
+// An environment describes the settings an envPrinter prints.
+type environment struct {
+	Precedence []string          `json:"precedence"`
+	ConfigFile string            `json:"configFile,omitempty"`
+	DotEnv     []dotEnvFile      `json:"dotenv,omitempty"`
+	Flags      []flagSetting     `json:"flags"`
+	Env        map[string]string `json:"env,omitempty"`
+}
+
+// A dotEnvFile is a file named to LoadDotEnv.
+type dotEnvFile struct {
+	Path  string `json:"path"`
+	Found bool   `json:"found"`
+}
+
+func (e *envPrinter) Execute(ctx context.Context, f *flag.FlagSet, _ ...interface{}) ExitStatus {
+	cdr := e.cdr.executing(ctx)
+	if f.NArg() != 0 {
+		f.Usage()
+		return ExitUsageError
+	}
+	env := environment{ConfigFile: cdr.configPath}
+	for _, src := range cdr.Precedence() {
+		env.Precedence = append(env.Precedence, src.String())
+	}
+	for _, path := range cdr.dotEnvFiles {
+		_, err := os.Stat(path)
+		env.DotEnv = append(env.DotEnv, dotEnvFile{path, err == nil})
+	}
+	if cdr.topFlags != nil {
+		cdr.topFlags.VisitAll(func(fl *flag.Flag) {
+			env.Flags = append(env.Flags, flagSetting{
+				Flag:   fl.Name,
+				Value:  shown(cdr.topFlags, fl.Name, fl.Value.String()),
+				Source: Provenance(cdr.topFlags, fl.Name).String(),
+			})
+		})
+	}
+	if cdr.envBound {
+		env.Env = make(map[string]string)
+		bind := func(cmd Command, fs *flag.FlagSet) {
+			fs.VisitAll(func(fl *flag.Flag) {
+				key := cdr.EnvName(cmd, fl.Name)
+				value, _ := cdr.LookupEnv(key)
+				env.Env[key] = shown(fs, fl.Name, value)
+			})
+		}
+		if cdr.topFlags != nil {
+			bind(nil, cdr.topFlags)
+		}
+		cdr.VisitCommands(func(_ *CommandGroup, cmd Command) {
+			if _, ok := cmd.(*aliaser); ok || isBuiltin(cmd) || hidden(cmd) {
+				return
+			}
+			fs := flag.NewFlagSet(cmd.Name(), flag.ContinueOnError)
+			cmd.SetFlags(fs)
+			markSecretFlags(cmd, fs)
+			bind(cmd, fs)
+			forget(fs)
+		})
+	}
+
+	if e.asJSON {
+		enc := json.NewEncoder(cdr.Output)
+		enc.SetIndent("", "  ")
+		if err := enc.Encode(env); err != nil {
+			cdr.Errorf("env: %v", err)
+			return ExitFailure
+		}
+		return ExitSuccess
+	}
+	w := cdr.Output
+	fmt.Fprintf(w, "Precedence: %s\n", strings.Join(env.Precedence, " > "))
+	if cdr.configFlag != "" {
+		config := env.ConfigFile
+		if config == "" {
+			config = "(none)"
+		}
+		fmt.Fprintf(w, "Config file: %s\n", config)
+	}
+	for _, file := range env.DotEnv {
+		status := "found"
+		if !file.Found {
+			status = "not found"
+		}
+		fmt.Fprintf(w, "Dotenv file: %s (%s)\n", file.Path, status)
+	}
+	if len(env.Flags) > 0 {
+		fmt.Fprintln(w, "\nTop-level flags:")
+		for _, s := range env.Flags {
+			fmt.Fprintf(w, "-%s=%s (%s)\n", s.Flag, s.Value, s.Source)
+		}
+	}
+	if len(env.Env) > 0 {
+		fmt.Fprintln(w, "\nEnvironment:")
+		keys := make([]string, 0, len(env.Env))
+		for key := range env.Env {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		for _, key := range keys {
+			fmt.Fprintf(w, "%s=%s\n", key, strconv.Quote(env.Env[key]))
+		}
+	}
+	return ExitSuccess
+}
+
+// EnvCommand returns a Command which implements an "env" subcommand,
+// which prints the config file, dotenv files, top-level flag values and
+// environment variables the program consults, in the manner of "go env".
+func (cdr *Commander) EnvCommand() Command {
+	return &envPrinter{cdr: cdr}
+}
@@ -0,0 +1,114 @@
+/*
+Copyright 2016 Google Inc. All Rights Reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package subcommands
+
+import (
+	"bytes"
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+func TestEnvCommand(t *testing.T) {
+	tests := []struct {
+		name       string
+		args       []string
+		wantStatus ExitStatus
+		want       string
+	}{
+		{
+			name:       "text",
+			args:       []string{"-v=2", "env"},
+			wantStatus: ExitSuccess,
+			want: "" +
+				"Precedence: command line > environment > config file\n" +
+				"Config file: (none)\n" +
+				"Dotenv file: /nonexistent/.env (not found)\n" +
+				"\n" +
+				"Top-level flags:\n" +
+				"-config= (default)\n" +
+				"-token=<redacted> (environment)\n" +
+				"-v=2 (command line)\n" +
+				"\n" +
+				"Environment:\n" +
+				"TOOL_CONFIG=\"\"\n" +
+				"TOOL_PRINT_N=\"true\"\n" +
+				"TOOL_TOKEN=\"<redacted>\"\n" +
+				"TOOL_V=\"\"\n",
+		},
+		{
+			name:       "extra argument",
+			args:       []string{"env", "extra"},
+			wantStatus: ExitUsageError,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cdr, stdout, _ := newEnvCommander(t)
+			if status := execute(t, cdr, tt.args...); status != tt.wantStatus {
+				t.Errorf("status %v, want %v", status, tt.wantStatus)
+			}
+			if got := stdout.String(); got != tt.want {
+				t.Errorf("printed\n%s\nwant\n%s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestEnvCommandJSON(t *testing.T) {
+	cdr, stdout, _ := newEnvCommander(t)
+	if status := execute(t, cdr, "env", "-json"); status != ExitSuccess {
+		t.Fatalf("status %v", status)
+	}
+	var got environment
+	if err := json.Unmarshal(stdout.Bytes(), &got); err != nil {
+		t.Fatal(err)
+	}
+	want := environment{
+		Precedence: []string{"command line", "environment", "config file"},
+		DotEnv:     []dotEnvFile{{"/nonexistent/.env", false}},
+		Flags: []flagSetting{
+			{Flag: "config", Value: "", Source: "default"},
+			{Flag: "token", Value: "<redacted>", Source: "environment"},
+			{Flag: "v", Value: "0", Source: "default"},
+		},
+		Env: map[string]string{"TOOL_CONFIG": "", "TOOL_PRINT_N": "true", "TOOL_TOKEN": "<redacted>", "TOOL_V": ""},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("printed %+v, want %+v", got, want)
+	}
+}
+
+// newEnvCommander returns a test Commander with an env command, a secret
+// top-level flag set from the environment, and environment variables
+// bound to flags.
+func newEnvCommander(t *testing.T) (cdr *Commander, stdout, stderr *bytes.Buffer) {
+	t.Helper()
+	t.Setenv("TOOL_TOKEN", "s3cret")
+	t.Setenv("TOOL_PRINT_N", "true")
+	cdr, stdout, stderr = newTestCommander()
+	cdr.topFlags.String("token", "", "API token")
+	MarkSecret(cdr.topFlags, "token")
+	cdr.topFlags.Int("v", 0, "verbosity")
+	cdr.ConfigFlag("config")
+	cdr.BindEnv()
+	if err := cdr.LoadDotEnv("/nonexistent/.env"); err != nil {
+		t.Fatal(err)
+	}
+	cdr.Register(cdr.EnvCommand(), "")
+	return cdr, stdout, stderr
+}
@@ -0,0 +1,101 @@
+/*
+Copyright 2016 Google Inc. All Rights Reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package subcommands
+
+import (
+	"flag"
+	"time"
+)
+
+// An EventKind is the kind of an Event.
+type EventKind int
+
+const (
+	CommandResolved EventKind = iota // The subcommand to execute was found.
+	FlagsParsed                      // The flags and arguments of the subcommand were parsed and validated.
+	CommandStarted                   // The subcommand is about to be executed, with its middleware.
+	CommandFinished                  // The subcommand returned.
+)
+
+func (k EventKind) String() string {
+	switch k {
+	case CommandResolved:
+		return "command resolved"
+	case FlagsParsed:
+		return "flags parsed"
+	case CommandStarted:
+		return "command started"
+	case CommandFinished:
+		return "command finished"
+	}
+	return "unknown"
+}
+
+// An Event is a step in the execution of a subcommand by a Commander.
+type Event struct {
+	Kind     EventKind     // Kind is what happened.
+	Command  string        // Command is the name of the subcommand.
+	Group    string        // Group is the group of the subcommand.
+	Time     time.Time     // Time is when it happened.
+	Flags    *flag.FlagSet // Flags holds the flags of the subcommand, from FlagsParsed on; it must not be kept after the handler returns.
+	Args     []string      // Args holds the arguments remaining after the flags, from FlagsParsed on.
+	Status   ExitStatus    // Status is the status the subcommand returned, for CommandFinished.
+	Duration time.Duration // Duration is how long the subcommand ran, for CommandFinished.
+}
+
+// Subscribe adds handler to those called, in the order they were added,
+// with each Event in the execution of a subcommand by Execute or Run,
+// for plugins, metrics and logging that observe commands rather than
+// wrap them as a Middleware does. A subcommand that fails before its
+// flags are parsed produces only CommandResolved, and one that is
+// rejected after produces no CommandStarted or CommandFinished.
+//
+//	cdr.Subscribe(func(e subcommands.Event) {
+//		if e.Kind == subcommands.CommandFinished {
+//			log.Printf("%s: %v in %v", e.Command, e.Status, e.Duration)
+//		}
+//	})
+func (cdr *Commander) Subscribe(handler func(Event)) {
+	cdr.subscribers = append(cdr.subscribers, handler)
+}
+
+// finished reports that cmd, with the flags f, returned *status after
+// starting at start, to the Metrics set by SetMetrics and as a
+// CommandFinished event. It must be deferred, so that a command that
+// panics is reported, with ExitFailure, too.
+func (cdr *Commander) finished(cmd Command, f *flag.FlagSet, start time.Time, status *ExitStatus) {
+	elapsed := time.Since(start)
+	if cdr.metrics != nil {
+		cdr.metrics.ObserveDuration(cmd.Name(), elapsed)
+	}
+	cdr.emit(Event{Kind: CommandFinished, Command: cmd.Name(), Flags: f, Args: f.Args(), Status: *status, Duration: elapsed})
+}
+
+// emit sets the group of e, and its time if unset, and calls the
+// handlers added by Subscribe with it.
+func (cdr *Commander) emit(e Event) {
+	if len(cdr.subscribers) == 0 {
+		return
+	}
+	if e.Time.IsZero() {
+		e.Time = time.Now()
+	}
+	e.Group = cdr.last.Group
+	for _, handler := range cdr.subscribers {
+		handler(e)
+	}
+}
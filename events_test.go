@@ -0,0 +1,145 @@
+/*
+Copyright 2016 Google Inc. All Rights Reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package subcommands
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"reflect"
+	"testing"
+)
+
+func TestSubscribe(t *testing.T) {
+	tests := []struct {
+		args       []string
+		wantStatus ExitStatus
+		want       []string
+	}{
+		{
+			args:       []string{"get", "-v", "a", "b"},
+			wantStatus: ExitSuccess,
+			want: []string{
+				"1 command resolved get [read] []",
+				"2 command resolved get [read] []",
+				"1 flags parsed get [read] [a b] v=true",
+				"2 flags parsed get [read] [a b] v=true",
+				"1 command started get [read] [a b] v=true",
+				"2 command started get [read] [a b] v=true",
+				"1 command finished get [read] [a b] v=true success",
+				"2 command finished get [read] [a b] v=true success",
+			},
+		},
+		{
+			args:       []string{"get", "fail"},
+			wantStatus: ExitFailure,
+			want: []string{
+				"1 command resolved get [read] []",
+				"2 command resolved get [read] []",
+				"1 flags parsed get [read] [fail] v=false",
+				"2 flags parsed get [read] [fail] v=false",
+				"1 command started get [read] [fail] v=false",
+				"2 command started get [read] [fail] v=false",
+				"1 command finished get [read] [fail] v=false failure",
+				"2 command finished get [read] [fail] v=false failure",
+			},
+		},
+		{
+			args:       []string{"get", "-x"},
+			wantStatus: ExitUsageError,
+			want: []string{
+				"1 command resolved get [read] []",
+				"2 command resolved get [read] []",
+			},
+		},
+		{
+			args:       []string{"print"},
+			wantStatus: ExitSuccess,
+			want: []string{
+				"1 command resolved print [] []",
+				"2 command resolved print [] []",
+				"1 flags parsed print [] [] n=false",
+				"2 flags parsed print [] [] n=false",
+				"1 command started print [] [] n=false",
+				"2 command started print [] [] n=false",
+				"1 command finished print [] [] n=false success",
+				"2 command finished print [] [] n=false success",
+			},
+		},
+		{
+			args:       []string{"nosuch"},
+			wantStatus: ExitUsageError,
+		},
+	}
+	for _, tt := range tests {
+		cdr, _, _ := newTestCommander()
+		cdr.Register(&testCommand{
+			name:  "get",
+			flags: func(f *flag.FlagSet) { f.Bool("v", false, "verbose") },
+			execute: func(ctx context.Context, f *flag.FlagSet) ExitStatus {
+				if f.Arg(0) == "fail" {
+					return ExitFailure
+				}
+				return ExitSuccess
+			},
+		}, "read")
+		var got []string
+		for _, i := range []int{1, 2} {
+			i := i
+			cdr.Subscribe(func(e Event) {
+				s := fmt.Sprintf("%d %v %s [%s] %v", i, e.Kind, e.Command, e.Group, e.Args)
+				if e.Flags != nil {
+					e.Flags.VisitAll(func(fl *flag.Flag) { s += " " + fl.Name + "=" + fl.Value.String() })
+				}
+				if e.Kind == CommandFinished {
+					s += " " + e.Status.String()
+					if e.Duration < 0 {
+						t.Errorf("%q: negative duration %v", tt.args, e.Duration)
+					}
+				}
+				if e.Time.IsZero() {
+					t.Errorf("%q: %v has no time", tt.args, e.Kind)
+				}
+				got = append(got, s)
+			})
+		}
+		if status := execute(t, cdr, tt.args...); status != tt.wantStatus {
+			t.Errorf("%q: status %v, want %v", tt.args, status, tt.wantStatus)
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%q: events\n%q\nwant\n%q", tt.args, got, tt.want)
+		}
+	}
+}
+
+func TestEventKindString(t *testing.T) {
+	tests := []struct {
+		kind EventKind
+		want string
+	}{
+		{CommandResolved, "command resolved"},
+		{FlagsParsed, "flags parsed"},
+		{CommandStarted, "command started"},
+		{CommandFinished, "command finished"},
+		{EventKind(-1), "unknown"},
+	}
+	for _, tt := range tests {
+		if got := tt.kind.String(); got != tt.want {
+			t.Errorf("EventKind(%d).String() = %q, want %q", int(tt.kind), got, tt.want)
+		}
+	}
+}
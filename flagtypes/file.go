@@ -0,0 +1,77 @@
+/*
+Copyright 2016 Google Inc. All Rights Reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package flagtypes
+
+import (
+	"errors"
+	"flag"
+	"os"
+
+	"github.com/google/subcommands"
+)
+
+// An existingFileValue is a flag.Value holding the path of a file that
+// exists.
+type existingFileValue struct {
+	p   *string
+	def string
+}
+
+func (v existingFileValue) String() string {
+	if v.p == nil {
+		return ""
+	}
+	return *v.p
+}
+
+func (v existingFileValue) Set(s string) error {
+	fi, err := os.Stat(s)
+	switch {
+	case errors.Is(err, os.ErrNotExist):
+		return errors.New("no such file")
+	case err != nil:
+		return err
+	case fi.IsDir():
+		return errors.New("is a directory")
+	}
+	*v.p = s
+	return nil
+}
+
+// Reset sets the value to its default, for subcommands.Commander.Reset.
+func (v existingFileValue) Reset() { *v.p = v.def }
+
+// ExistingFileVar defines a file path flag in f with the given name,
+// default value and usage string. The argument p points to a string
+// variable in which to store the value of the flag. Paths that do not
+// name an existing file, or that name a directory, are rejected. The
+// default is not checked, so that it may name a file that is optional.
+// The flag is marked by subcommands.MarkFileFlag, so shells complete
+// its values as file names.
+func ExistingFileVar(f *flag.FlagSet, p *string, name, value, usage string) {
+	*p = value
+	f.Var(existingFileValue{p, value}, name, usage)
+	subcommands.MarkFileFlag(f, name)
+}
+
+// ExistingFile is like ExistingFileVar, but returns the address of a
+// string variable that stores the value of the flag.
+func ExistingFile(f *flag.FlagSet, name, value, usage string) *string {
+	p := new(string)
+	ExistingFileVar(f, p, name, value, usage)
+	return p
+}
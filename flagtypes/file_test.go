@@ -0,0 +1,84 @@
+/*
+Copyright 2016 Google Inc. All Rights Reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package flagtypes
+
+import (
+	"flag"
+	"io"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/google/subcommands"
+)
+
+func TestExistingFile(t *testing.T) {
+	dir := t.TempDir()
+	file := filepath.Join(dir, "config.yaml")
+	if err := os.WriteFile(file, nil, 0o666); err != nil {
+		t.Fatal(err)
+	}
+	missing := filepath.Join(dir, "missing.yaml")
+	tests := []struct {
+		def     string
+		args    []string
+		want    string
+		wantErr string
+	}{
+		{"", nil, "", ""},
+		{missing, nil, missing, ""}, // the default is not checked
+		{"", []string{"-config=" + file}, file, ""},
+		{"", []string{"-config=" + missing}, "", `invalid value "` + missing + `" for flag -config: no such file`},
+		{"", []string{"-config=" + dir}, "", `invalid value "` + dir + `" for flag -config: is a directory`},
+	}
+	for _, tt := range tests {
+		f := flag.NewFlagSet("tool", flag.ContinueOnError)
+		f.SetOutput(io.Discard)
+		config := ExistingFile(f, "config", tt.def, "")
+		err := f.Parse(tt.args)
+		if got := errString(err); got != tt.wantErr {
+			t.Errorf("default %q, parsing %q: error %q, want %q", tt.def, tt.args, got, tt.wantErr)
+		}
+		if *config != tt.want {
+			t.Errorf("default %q, parsing %q: value %q, want %q", tt.def, tt.args, *config, tt.want)
+		}
+	}
+}
+
+func TestReset(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "config.yaml")
+	if err := os.WriteFile(file, nil, 0o666); err != nil {
+		t.Fatal(err)
+	}
+	f := flag.NewFlagSet("tool", flag.ContinueOnError)
+	u := URL(f, "url", "https://example.com", "")
+	ip := IP(f, "ip", net.IPv4(127, 0, 0, 1), "")
+	cidr := CIDR(f, "cidr", "10.0.0.0/8", "")
+	port := Port(f, "port", 8080, "")
+	config := ExistingFile(f, "config", "", "")
+	cdr := subcommands.NewCommander(f, "tool")
+	if err := f.Parse([]string{"-url=http://localhost", "-ip=::1", "-cidr=192.0.2.0/24", "-port=9", "-config=" + file}); err != nil {
+		t.Fatal(err)
+	}
+	if err := cdr.Reset(); err != nil {
+		t.Fatalf("Reset: %v", err)
+	}
+	if u.String() != "https://example.com" || ip.String() != "127.0.0.1" || cidr.String() != "10.0.0.0/8" || *port != 8080 || *config != "" {
+		t.Errorf("after Reset: %v %v %v %v %q", u, ip, cidr, *port, *config)
+	}
+}
@@ -0,0 +1,85 @@
+/*
+Copyright 2016 Google Inc. All Rights Reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package flagtypes provides flag.Value types that validate their values
+// as they are parsed, so that commands need not check them in Execute.
+package flagtypes
+
+import (
+	"flag"
+	"fmt"
+	"strings"
+)
+
+// An enumValue is a string flag.Value limited to a set of choices.
+type enumValue struct {
+	p       *string
+	choices []string
+	def     string
+}
+
+func (e *enumValue) String() string {
+	if e.p == nil {
+		return ""
+	}
+	return *e.p
+}
+
+func (e *enumValue) Set(s string) error {
+	for _, choice := range e.choices {
+		if s == choice {
+			*e.p = s
+			return nil
+		}
+	}
+	return fmt.Errorf("want one of %s", strings.Join(e.choices, "|"))
+}
+
+// Reset sets the value to its default, for subcommands.Commander.Reset.
+func (e *enumValue) Reset() { *e.p = e.def }
+
+// Choices implements subcommands.Chooser.
+func (e *enumValue) Choices() []string { return e.choices }
+
+// EnumVar defines a string flag in f with the given name, default value
+// and usage string, whose value must be one of choices. The argument p
+// points to a string variable in which to store the value of the flag.
+// Other values are rejected as the flags are parsed, with a message
+// listing the choices. A Commander lists the choices in help, as
+// "one of: json|yaml|table", and offers them in shell completion. An
+// empty default leaves p empty; any other default must be one of
+// choices, or EnumVar panics.
+func EnumVar(f *flag.FlagSet, p *string, name string, choices []string, value, usage string) {
+	*p = ""
+	v := &enumValue{p: p, choices: choices}
+	if value != "" {
+		if err := v.Set(value); err != nil {
+			panic("flagtypes: default of -" + name + ": " + err.Error())
+		}
+	}
+	v.def = *p
+	f.Var(v, name, usage)
+}
+
+// Enum is like EnumVar, but returns the address of a string variable
+// that stores the value of the flag.
+//
+//	format := flagtypes.Enum(f, "format", []string{"json", "yaml", "table"}, "json", "output format")
+func Enum(f *flag.FlagSet, name string, choices []string, value, usage string) *string {
+	p := new(string)
+	EnumVar(f, p, name, choices, value, usage)
+	return p
+}
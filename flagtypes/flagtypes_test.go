@@ -0,0 +1,79 @@
+/*
+Copyright 2016 Google Inc. All Rights Reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package flagtypes
+
+import (
+	"flag"
+	"io"
+	"testing"
+
+	"github.com/google/subcommands"
+)
+
+func TestEnum(t *testing.T) {
+	choices := []string{"json", "yaml"}
+	tests := []struct {
+		def     string
+		args    []string
+		want    string
+		wantErr bool
+	}{
+		{"", nil, "", false},
+		{"json", nil, "json", false},
+		{"", []string{"-format=yaml"}, "yaml", false},
+		{"json", []string{"-format=yaml"}, "yaml", false},
+		{"json", []string{"-format=xml"}, "json", true},
+	}
+	for _, tt := range tests {
+		f := flag.NewFlagSet("tool", flag.ContinueOnError)
+		f.SetOutput(io.Discard)
+		format := Enum(f, "format", choices, tt.def, "output format")
+		err := f.Parse(tt.args)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("default %q, parsing %q: error %v, want error %v", tt.def, tt.args, err, tt.wantErr)
+		}
+		if *format != tt.want {
+			t.Errorf("default %q, parsing %q: value %q, want %q", tt.def, tt.args, *format, tt.want)
+		}
+	}
+}
+
+func TestEnumInvalidDefault(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Error("Enum with default xml not in json|yaml did not panic")
+		}
+	}()
+	Enum(flag.NewFlagSet("tool", flag.ContinueOnError), "format", []string{"json", "yaml"}, "xml", "")
+}
+
+func TestEnumReset(t *testing.T) {
+	for _, def := range []string{"", "json"} {
+		f := flag.NewFlagSet("tool", flag.ContinueOnError)
+		format := Enum(f, "format", []string{"json", "yaml"}, def, "output format")
+		cdr := subcommands.NewCommander(f, "tool")
+		if err := f.Parse([]string{"-format=yaml"}); err != nil {
+			t.Fatal(err)
+		}
+		if err := cdr.Reset(); err != nil {
+			t.Errorf("default %q: Reset: %v", def, err)
+		}
+		if *format != def {
+			t.Errorf("default %q: after Reset, value %q, want %q", def, *format, def)
+		}
+	}
+}
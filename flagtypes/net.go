@@ -0,0 +1,206 @@
+/*
+Copyright 2016 Google Inc. All Rights Reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package flagtypes
+
+import (
+	"errors"
+	"flag"
+	"net"
+	"net/url"
+	"strconv"
+)
+
+// A urlValue is a flag.Value holding an absolute URL.
+type urlValue struct {
+	p   *url.URL
+	def url.URL
+}
+
+func (u urlValue) String() string {
+	if u.p == nil {
+		return ""
+	}
+	return u.p.String()
+}
+
+func (u urlValue) Set(s string) error {
+	v, err := url.Parse(s)
+	if err != nil {
+		return errors.New("want an absolute URL")
+	}
+	if !v.IsAbs() {
+		return errors.New("want an absolute URL, with a scheme")
+	}
+	*u.p = *v
+	return nil
+}
+
+// Reset sets the value to its default, for subcommands.Commander.Reset.
+func (u urlValue) Reset() { *u.p = u.def }
+
+// URLVar defines a URL flag in f with the given name, default value and
+// usage string. The argument p points to a url.URL variable in which to
+// store the value of the flag. Values that are not absolute URLs are
+// rejected. An empty default leaves p the zero URL; any other default
+// must be a valid value, or URLVar panics.
+func URLVar(f *flag.FlagSet, p *url.URL, name, value, usage string) {
+	*p = url.URL{}
+	v := urlValue{p: p}
+	if value != "" {
+		if err := v.Set(value); err != nil {
+			panic("flagtypes: default of -" + name + ": " + err.Error())
+		}
+	}
+	v.def = *p
+	f.Var(v, name, usage)
+}
+
+// URL is like URLVar, but returns the address of a url.URL variable
+// that stores the value of the flag.
+func URL(f *flag.FlagSet, name, value, usage string) *url.URL {
+	p := new(url.URL)
+	URLVar(f, p, name, value, usage)
+	return p
+}
+
+// An ipValue is a flag.Value holding an IPv4 or IPv6 address.
+type ipValue struct {
+	p   *net.IP
+	def net.IP
+}
+
+func (v ipValue) String() string {
+	if v.p == nil || *v.p == nil {
+		return ""
+	}
+	return v.p.String()
+}
+
+func (v ipValue) Set(s string) error {
+	ip := net.ParseIP(s)
+	if ip == nil {
+		return errors.New("want an IPv4 or IPv6 address")
+	}
+	*v.p = ip
+	return nil
+}
+
+// Reset sets the value to its default, for subcommands.Commander.Reset.
+func (v ipValue) Reset() { *v.p = v.def }
+
+// IPVar defines an IP address flag in f with the given name, default
+// value and usage string. The argument p points to a net.IP variable in
+// which to store the value of the flag. Values that are not IPv4 or
+// IPv6 addresses are rejected.
+func IPVar(f *flag.FlagSet, p *net.IP, name string, value net.IP, usage string) {
+	*p = value
+	f.Var(ipValue{p, value}, name, usage)
+}
+
+// IP is like IPVar, but returns the address of a net.IP variable that
+// stores the value of the flag.
+func IP(f *flag.FlagSet, name string, value net.IP, usage string) *net.IP {
+	p := new(net.IP)
+	IPVar(f, p, name, value, usage)
+	return p
+}
+
+// A cidrValue is a flag.Value holding an IP network.
+type cidrValue struct {
+	p   *net.IPNet
+	def net.IPNet
+}
+
+func (v cidrValue) String() string {
+	if v.p == nil || v.p.IP == nil {
+		return ""
+	}
+	return v.p.String()
+}
+
+func (v cidrValue) Set(s string) error {
+	_, n, err := net.ParseCIDR(s)
+	if err != nil {
+		return errors.New("want a network in CIDR notation, such as 192.0.2.0/24")
+	}
+	*v.p = *n
+	return nil
+}
+
+// Reset sets the value to its default, for subcommands.Commander.Reset.
+func (v cidrValue) Reset() { *v.p = v.def }
+
+// CIDRVar defines an IP network flag in f with the given name, default
+// value and usage string. The argument p points to a net.IPNet variable
+// in which to store the value of the flag. Values that are not networks
+// in CIDR notation are rejected. An empty default leaves p the zero
+// IPNet; any other default must be a valid value, or CIDRVar panics.
+func CIDRVar(f *flag.FlagSet, p *net.IPNet, name, value, usage string) {
+	*p = net.IPNet{}
+	v := cidrValue{p: p}
+	if value != "" {
+		if err := v.Set(value); err != nil {
+			panic("flagtypes: default of -" + name + ": " + err.Error())
+		}
+	}
+	v.def = *p
+	f.Var(v, name, usage)
+}
+
+// CIDR is like CIDRVar, but returns the address of a net.IPNet variable
+// that stores the value of the flag.
+func CIDR(f *flag.FlagSet, name, value, usage string) *net.IPNet {
+	p := new(net.IPNet)
+	CIDRVar(f, p, name, value, usage)
+	return p
+}
+
+// A portValue is a flag.Value holding a TCP or UDP port number.
+type portValue struct{ p *int }
+
+func (v portValue) String() string {
+	if v.p == nil {
+		return ""
+	}
+	return strconv.Itoa(*v.p)
+}
+
+func (v portValue) Set(s string) error {
+	n, err := strconv.Atoi(s)
+	if err != nil || n < 0 || n > 65535 {
+		return errors.New("want a port number from 0 to 65535")
+	}
+	*v.p = n
+	return nil
+}
+
+// PortVar defines a port number flag in f with the given name, default
+// value and usage string. The argument p points to an int variable in
+// which to store the value of the flag. Values outside 0 to 65535 are
+// rejected; 0 conventionally asks for any free port.
+func PortVar(f *flag.FlagSet, p *int, name string, value int, usage string) {
+	*p = value
+	f.Var(portValue{p}, name, usage)
+}
+
+// Port is like PortVar, but returns the address of an int variable that
+// stores the value of the flag.
+func Port(f *flag.FlagSet, name string, value int, usage string) *int {
+	p := new(int)
+	PortVar(f, p, name, value, usage)
+	return p
+}
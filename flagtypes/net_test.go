@@ -0,0 +1,126 @@
+/*
+Copyright 2016 Google Inc. All Rights Reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package flagtypes
+
+import (
+	"flag"
+	"io"
+	"net"
+	"testing"
+)
+
+func TestNetValues(t *testing.T) {
+	// Each define function defines a flag named v in f and returns a
+	// function returning its value as a string.
+	url := func(def string) func(f *flag.FlagSet) func() string {
+		return func(f *flag.FlagSet) func() string {
+			p := URL(f, "v", def, "")
+			return p.String
+		}
+	}
+	ip := func(def net.IP) func(f *flag.FlagSet) func() string {
+		return func(f *flag.FlagSet) func() string {
+			p := IP(f, "v", def, "")
+			return func() string {
+				if *p == nil {
+					return ""
+				}
+				return p.String()
+			}
+		}
+	}
+	cidr := func(def string) func(f *flag.FlagSet) func() string {
+		return func(f *flag.FlagSet) func() string {
+			p := CIDR(f, "v", def, "")
+			return func() string {
+				if p.IP == nil {
+					return ""
+				}
+				return p.String()
+			}
+		}
+	}
+	port := func(def int) func(f *flag.FlagSet) func() string {
+		return func(f *flag.FlagSet) func() string {
+			p := Port(f, "v", def, "")
+			return func() string { return portValue{p}.String() }
+		}
+	}
+	tests := []struct {
+		name    string
+		define  func(*flag.FlagSet) func() string
+		args    []string
+		want    string
+		wantErr string
+	}{
+		{"url default", url("https://example.com/a"), nil, "https://example.com/a", ""},
+		{"url empty default", url(""), nil, "", ""},
+		{"url", url(""), []string{"-v=http://localhost:8080/x?y=1"}, "http://localhost:8080/x?y=1", ""},
+		{"relative url", url("https://example.com"), []string{"-v=/x"}, "https://example.com", `invalid value "/x" for flag -v: want an absolute URL, with a scheme`},
+		{"bad url", url(""), []string{"-v=http://[::1"}, "", `invalid value "http://[::1" for flag -v: want an absolute URL`},
+		{"ip default", ip(net.IPv4(127, 0, 0, 1)), nil, "127.0.0.1", ""},
+		{"ipv4", ip(nil), []string{"-v=192.0.2.1"}, "192.0.2.1", ""},
+		{"ipv6", ip(nil), []string{"-v=2001:db8::1"}, "2001:db8::1", ""},
+		{"bad ip", ip(nil), []string{"-v=192.0.2"}, "", `invalid value "192.0.2" for flag -v: want an IPv4 or IPv6 address`},
+		{"cidr default", cidr("10.0.0.0/8"), nil, "10.0.0.0/8", ""},
+		{"cidr", cidr(""), []string{"-v=192.0.2.7/24"}, "192.0.2.0/24", ""},
+		{"bad cidr", cidr(""), []string{"-v=192.0.2.7"}, "", `invalid value "192.0.2.7" for flag -v: want a network in CIDR notation, such as 192.0.2.0/24`},
+		{"port default", port(8080), nil, "8080", ""},
+		{"port", port(8080), []string{"-v=0"}, "0", ""},
+		{"port too large", port(8080), []string{"-v=65536"}, "8080", `invalid value "65536" for flag -v: want a port number from 0 to 65535`},
+		{"port not a number", port(8080), []string{"-v=http"}, "8080", `invalid value "http" for flag -v: want a port number from 0 to 65535`},
+	}
+	for _, tt := range tests {
+		f := flag.NewFlagSet("tool", flag.ContinueOnError)
+		f.SetOutput(io.Discard)
+		value := tt.define(f)
+		err := f.Parse(tt.args)
+		if got := errString(err); got != tt.wantErr {
+			t.Errorf("%s: error %q, want %q", tt.name, got, tt.wantErr)
+		}
+		if got := value(); got != tt.want {
+			t.Errorf("%s: value %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestNetInvalidDefault(t *testing.T) {
+	tests := []struct {
+		name   string
+		define func(*flag.FlagSet)
+	}{
+		{"url", func(f *flag.FlagSet) { URL(f, "v", "example.com", "") }},
+		{"cidr", func(f *flag.FlagSet) { CIDR(f, "v", "10.0.0.1", "") }},
+	}
+	for _, tt := range tests {
+		func() {
+			defer func() {
+				if recover() == nil {
+					t.Errorf("%s: invalid default did not panic", tt.name)
+				}
+			}()
+			tt.define(flag.NewFlagSet("tool", flag.ContinueOnError))
+		}()
+	}
+}
+
+func errString(err error) string {
+	if err == nil {
+		return ""
+	}
+	return err.Error()
+}
@@ -0,0 +1,62 @@
+/*
+Copyright 2016 Google Inc. All Rights Reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package subcommands
+
+import "io"
+
+// A HelpFormatter renders the help output of a Commander. It lets an
+// alternate renderer (compact, JSON, colorized, ...) replace Explain,
+// ExplainGroup and ExplainCommand as a unit; see SetFormatter.
+type HelpFormatter interface {
+	// FormatTop prints a top level usage explanation.
+	FormatTop(w io.Writer, cdr *Commander)
+
+	// FormatGroup prints a command group's usage explanation.
+	FormatGroup(w io.Writer, cdr *Commander, group *CommandGroup)
+
+	// FormatCommand prints a command usage explanation.
+	FormatCommand(w io.Writer, cdr *Commander, cmd Command)
+}
+
+// DefaultFormatter is the HelpFormatter that produces a Commander's
+// standard help output. Alternate formatters may embed it to override
+// only some of its methods.
+type DefaultFormatter struct{}
+
+// FormatTop prints a brief description of all the subcommands and the
+// important top-level flags, using cdr.ExplainGroup for each group.
+func (DefaultFormatter) FormatTop(w io.Writer, cdr *Commander) {
+	cdr.explain(w)
+}
+
+// FormatGroup prints a brief description of each subcommand in group.
+func (DefaultFormatter) FormatGroup(w io.Writer, cdr *Commander, group *CommandGroup) {
+	cdr.explainGroup(w, group)
+}
+
+// FormatCommand prints the usage of cmd followed by its flags.
+func (DefaultFormatter) FormatCommand(w io.Writer, cdr *Commander, cmd Command) {
+	cdr.explainCommand(w, cmd)
+}
+
+// SetFormatter sets cdr.Explain, cdr.ExplainGroup and cdr.ExplainCommand
+// to the methods of f.
+func (cdr *Commander) SetFormatter(f HelpFormatter) {
+	cdr.Explain = func(w io.Writer) { f.FormatTop(w, cdr) }
+	cdr.ExplainGroup = func(w io.Writer, group *CommandGroup) { f.FormatGroup(w, cdr, group) }
+	cdr.ExplainCommand = func(w io.Writer, cmd Command) { f.FormatCommand(w, cdr, cmd) }
+}
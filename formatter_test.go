@@ -0,0 +1,67 @@
+/*
+Copyright 2016 Google Inc. All Rights Reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package subcommands
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"testing"
+)
+
+// compactFormatter lists the names of each group on one line.
+type compactFormatter struct{ DefaultFormatter }
+
+func (compactFormatter) FormatGroup(w io.Writer, cdr *Commander, group *CommandGroup) {
+	fmt.Fprintf(w, "[%s]", group.Name())
+	for _, cmd := range group.commands {
+		fmt.Fprintf(w, " %s", cmd.Name())
+	}
+	fmt.Fprintln(w)
+}
+
+func TestSetFormatter(t *testing.T) {
+	cdr, _, _ := newTestCommander()
+	cdr.Register(&testCommand{name: "deploy", synopsis: "ship it"}, "ops")
+
+	var def bytes.Buffer
+	cdr.Explain(&def)
+	cdr.SetFormatter(DefaultFormatter{})
+	var got bytes.Buffer
+	cdr.Explain(&got)
+	if got.String() != def.String() {
+		t.Errorf("DefaultFormatter wrote\n%s\nwant\n%s", &got, &def)
+	}
+
+	cdr.SetFormatter(compactFormatter{})
+	tests := []struct {
+		name    string
+		explain func(w io.Writer)
+		want    string
+	}{
+		{"top", cdr.Explain, "Usage: tool <flags> <subcommand> <subcommand args>\n\n[] print\n[ops] deploy\n"},
+		{"group", func(w io.Writer) { cdr.ExplainGroup(w, cdr.commands[1]) }, "[ops] deploy\n"},
+		{"command", func(w io.Writer) { cdr.ExplainCommand(w, cdr.lookup("print")) }, "print:\n\tA command for tests.\n  -n\tno newline\n"},
+	}
+	for _, tt := range tests {
+		var buf bytes.Buffer
+		tt.explain(&buf)
+		if buf.String() != tt.want {
+			t.Errorf("%s: wrote\n%q\nwant\n%q", tt.name, buf.String(), tt.want)
+		}
+	}
+}
@@ -0,0 +1,97 @@
+/*
+Copyright 2016 Google Inc. All Rights Reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package subcommands
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"io"
+	"strconv"
+)
+
+// CommandGraph writes to w a Graphviz DOT graph of the subcommands of
+// cdr: a node for the program, each named group, and each command, with
+// edges from each to the commands in it, including the subcommands of
+// mounted Commanders. Aliases are dashed, with an edge to the command
+// they stand for. If flags is set, the flags of each command are added
+// as leaves. The graph can be rendered by the dot program, as in
+// "dot -Tsvg cli.dot > cli.svg".
+func (cdr *Commander) CommandGraph(w io.Writer, flags bool) error {
+	var buf bytes.Buffer
+	root := cdr.DisplayName()
+	fmt.Fprintf(&buf, "digraph %s {\n", strconv.Quote(root))
+	fmt.Fprintf(&buf, "\trankdir=LR;\n\tnode [shape=box];\n")
+	fmt.Fprintf(&buf, "\t%s [shape=doubleoctagon];\n", strconv.Quote(root))
+	cdr.graph(&buf, root, flags)
+	fmt.Fprintf(&buf, "}\n")
+	_, err := w.Write(buf.Bytes())
+	return err
+}
+
+// graph writes the nodes and edges for the visible subcommands of cdr,
+// whose node is named id, to buf.
+func (cdr *Commander) graph(buf *bytes.Buffer, id string, flags bool) {
+	node := func(id, label, attrs string) {
+		fmt.Fprintf(buf, "\t%s [label=%s%s];\n", strconv.Quote(id), strconv.Quote(label), attrs)
+	}
+	edge := func(from, to, attrs string) {
+		if attrs != "" {
+			attrs = " [" + attrs + "]"
+		}
+		fmt.Fprintf(buf, "\t%s -> %s%s;\n", strconv.Quote(from), strconv.Quote(to), attrs)
+	}
+
+	cdr.loadGroups()
+	cdr.VisitGroups(func(group *CommandGroup) {
+		cmds := cdr.listOrder(group)
+		if len(cmds) == 0 {
+			return
+		}
+		parent := id
+		if group.name != "" {
+			parent = id + " [" + group.name + "]"
+			node(parent, group.name, ", shape=folder")
+			edge(id, parent, "")
+		}
+		for _, cmd := range cmds {
+			cmdID := id + " " + cmd.Name()
+			if a, ok := cmd.(*aliaser); ok {
+				node(cmdID, cmd.Name(), ", style=dashed")
+				edge(parent, cmdID, "style=dashed")
+				edge(cmdID, id+" "+dealias(a).Name(), `style=dashed, label="alias"`)
+				continue
+			}
+			node(cmdID, cmd.Name(), "")
+			edge(parent, cmdID, "")
+			if m := mounted(cmd); m != nil {
+				m.graph(buf, cmdID, flags)
+				continue
+			}
+			if flags {
+				f := flag.NewFlagSet(cmd.Name(), flag.ContinueOnError)
+				cmd.SetFlags(f)
+				f.VisitAll(func(fl *flag.Flag) {
+					flagID := cmdID + " -" + fl.Name
+					node(flagID, "-"+fl.Name, ", shape=plaintext")
+					edge(cmdID, flagID, "arrowhead=none")
+				})
+				forget(f)
+			}
+		}
+	})
+}
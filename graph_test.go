@@ -0,0 +1,96 @@
+/*
+Copyright 2016 Google Inc. All Rights Reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package subcommands
+
+import (
+	"bytes"
+	"flag"
+	"testing"
+)
+
+func TestCommandGraph(t *testing.T) {
+	tests := []struct {
+		flags bool
+		want  string
+	}{
+		{
+			flags: false,
+			want: `digraph "tool" {
+	rankdir=LR;
+	node [shape=box];
+	"tool" [shape=doubleoctagon];
+	"tool p" [label="p", style=dashed];
+	"tool" -> "tool p" [style=dashed];
+	"tool p" -> "tool print" [style=dashed, label="alias"];
+	"tool print" [label="print"];
+	"tool" -> "tool print";
+	"tool remote" [label="remote"];
+	"tool" -> "tool remote";
+	"tool remote add" [label="add"];
+	"tool remote" -> "tool remote add";
+	"tool [admin]" [label="admin", shape=folder];
+	"tool" -> "tool [admin]";
+	"tool purge" [label="purge"];
+	"tool [admin]" -> "tool purge";
+}
+`,
+		},
+		{
+			flags: true,
+			want: `digraph "tool" {
+	rankdir=LR;
+	node [shape=box];
+	"tool" [shape=doubleoctagon];
+	"tool p" [label="p", style=dashed];
+	"tool" -> "tool p" [style=dashed];
+	"tool p" -> "tool print" [style=dashed, label="alias"];
+	"tool print" [label="print"];
+	"tool" -> "tool print";
+	"tool print -n" [label="-n", shape=plaintext];
+	"tool print" -> "tool print -n" [arrowhead=none];
+	"tool remote" [label="remote"];
+	"tool" -> "tool remote";
+	"tool remote add" [label="add"];
+	"tool remote" -> "tool remote add";
+	"tool remote add -f" [label="-f", shape=plaintext];
+	"tool remote add" -> "tool remote add -f" [arrowhead=none];
+	"tool [admin]" [label="admin", shape=folder];
+	"tool" -> "tool [admin]";
+	"tool purge" [label="purge"];
+	"tool [admin]" -> "tool purge";
+}
+`,
+		},
+	}
+	for _, tt := range tests {
+		cdr, _, _ := newTestCommander()
+		cdr.Register(Alias("p", cdr.Lookup("print")), "")
+		cdr.Register(&testCommand{name: "__hidden"}, "")
+		remote := NewCommander(flag.NewFlagSet("remote", flag.ContinueOnError), "remote")
+		remote.Register(&testCommand{name: "add", flags: func(f *flag.FlagSet) { f.Bool("f", false, "force") }}, "")
+		cdr.Mount("remote", remote, "")
+		cdr.Register(&testCommand{name: "purge"}, "admin")
+
+		var buf bytes.Buffer
+		if err := cdr.CommandGraph(&buf, tt.flags); err != nil {
+			t.Fatal(err)
+		}
+		if got := buf.String(); got != tt.want {
+			t.Errorf("CommandGraph(flags=%v) wrote\n%s\nwant\n%s", tt.flags, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,87 @@
+/*
+Copyright 2016 Google Inc. All Rights Reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package subcommands
+
+import (
+	"io"
+	"runtime/debug"
+	"strings"
+	"text/template"
+)
+
+// SetHelpHeader sets text printed above the top-level help, such as a
+// tagline, followed by a blank line. The text is a text/template, where
+// {{.Name}} is the display name of the Commander and {{.Version}} the
+// version of the main module of the program, if known:
+//
+//	cdr.SetHelpHeader("{{.Name}} manages your cloud resources.")
+//
+// It panics if text is not a valid template. An empty text removes the
+// header.
+func (cdr *Commander) SetHelpHeader(text string) {
+	cdr.helpHeader = parseHelpText("header", text)
+}
+
+// SetHelpFooter sets text printed below the top-level help, after a
+// blank line, such as where to find documentation or support. The text
+// is a template, as for SetHelpHeader:
+//
+//	cdr.SetHelpFooter("Docs: https://example.com/{{.Name}}\nReport bugs to support@example.com.")
+func (cdr *Commander) SetHelpFooter(text string) {
+	cdr.helpFooter = parseHelpText("footer", text)
+}
+
+// SetBanner sets a banner, such as ASCII art or the product name and
+// version, printed above the top-level help and its header when the
+// help is written to a terminal, so that branded help screens do not
+// clutter logs and pipes. The text is a template, as for SetHelpHeader;
+// leading and trailing blank lines are dropped:
+//
+//	cdr.SetBanner(`
+//	  ___  ___
+//	 / _ \/ _ \   {{.Name}} {{.Version}}
+//	 \___/\___/
+//	`)
+//
+// It panics if text is not a valid template. An empty text removes the
+// banner.
+func (cdr *Commander) SetBanner(text string) {
+	cdr.banner = parseHelpText("banner", strings.Trim(text, "\n"))
+}
+
+// parseHelpText parses text as the named template, or returns nil if
+// text is empty.
+func parseHelpText(name, text string) *template.Template {
+	if text == "" {
+		return nil
+	}
+	return template.Must(template.New(name).Parse(text))
+}
+
+// printHelpText prints the text made by tmpl to w, ended with a
+// newline.
+func (cdr *Commander) printHelpText(w io.Writer, tmpl *template.Template) {
+	var b strings.Builder
+	data := struct{ Name, Version string }{Name: cdr.DisplayName()}
+	if info, ok := debug.ReadBuildInfo(); ok {
+		data.Version = info.Main.Version
+	}
+	if err := tmpl.Execute(&b, data); err != nil {
+		b.WriteString(err.Error())
+	}
+	io.WriteString(w, strings.TrimSuffix(b.String(), "\n")+"\n")
+}
@@ -0,0 +1,119 @@
+/*
+Copyright 2016 Google Inc. All Rights Reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package subcommands
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"strings"
+	"syscall"
+	"testing"
+	"time"
+	"unsafe"
+)
+
+// openTerminal opens a pseudo-terminal, returning the terminal and the
+// file reading what is written to it, or skips the test if it cannot.
+func openTerminal(t *testing.T) (tty, pty *os.File) {
+	t.Helper()
+	pty, err := os.OpenFile("/dev/ptmx", os.O_RDWR, 0)
+	if err != nil {
+		t.Skipf("no pseudo-terminals: %v", err)
+	}
+	t.Cleanup(func() { pty.Close() })
+	var unlock int32
+	if _, _, errno := syscall.Syscall(syscall.SYS_IOCTL, pty.Fd(), syscall.TIOCSPTLCK, uintptr(unsafe.Pointer(&unlock))); errno != 0 {
+		t.Skipf("unlocking pseudo-terminal: %v", errno)
+	}
+	var n uint32
+	if _, _, errno := syscall.Syscall(syscall.SYS_IOCTL, pty.Fd(), syscall.TIOCGPTN, uintptr(unsafe.Pointer(&n))); errno != 0 {
+		t.Skipf("naming pseudo-terminal: %v", errno)
+	}
+	tty, err = os.OpenFile(fmt.Sprintf("/dev/pts/%d", n), os.O_RDWR|syscall.O_NOCTTY, 0)
+	if err != nil {
+		t.Skipf("opening pseudo-terminal: %v", err)
+	}
+	t.Cleanup(func() { tty.Close() })
+	return tty, pty
+}
+
+// readTerminal closes tty and returns what was written to it, as read
+// from pty, with its line endings translated back.
+func readTerminal(tty, pty *os.File) string {
+	tty.Close()
+	pty.SetReadDeadline(time.Now().Add(time.Second))
+	var b bytes.Buffer
+	buf := make([]byte, 4096)
+	for {
+		n, err := pty.Read(buf)
+		b.Write(buf[:n])
+		if err != nil {
+			break
+		}
+	}
+	return strings.ReplaceAll(b.String(), "\r\n", "\n")
+}
+
+func TestBanner(t *testing.T) {
+	tests := []struct {
+		name       string
+		banner     string
+		header     string
+		args       []string
+		wantPrefix string
+	}{
+		{
+			name:       "none",
+			wantPrefix: "Usage: tool ",
+		},
+		{
+			name:       "banner",
+			banner:     "\n\n== {{.Name}} ==\n  logo\n\n",
+			wantPrefix: "== tool ==\n  logo\n\nUsage: tool ",
+		},
+		{
+			name:       "banner and header",
+			banner:     "== {{.Name}} ==",
+			header:     "Header",
+			wantPrefix: "== tool ==\n\nHeader\n\nUsage: tool ",
+		},
+		{
+			name:       "help for a command",
+			banner:     "== {{.Name}} ==",
+			args:       []string{"print"},
+			wantPrefix: "print:\n",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tty, pty := openTerminal(t)
+			cdr, _, _ := newTestCommander()
+			cdr.Output = tty
+			cdr.Register(cdr.HelpCommand(), "")
+			cdr.SetBanner(tt.banner)
+			cdr.SetHelpHeader(tt.header)
+			if status := cdr.Run(context.Background(), "help", tt.args); status != ExitSuccess {
+				t.Errorf("status %v, want %v", status, ExitSuccess)
+			}
+			if got := readTerminal(tty, pty); !strings.HasPrefix(got, tt.wantPrefix) {
+				t.Errorf("printed\n%s\nwant it to start with\n%s", got, tt.wantPrefix)
+			}
+		})
+	}
+}
@@ -0,0 +1,120 @@
+/*
+Copyright 2016 Google Inc. All Rights Reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package subcommands
+
+import (
+	"context"
+	"strings"
+	"testing"
+)
+
+func TestHelpHeaderFooter(t *testing.T) {
+	tests := []struct {
+		name       string
+		header     string
+		footer     string
+		args       []string
+		wantPrefix string
+		wantSuffix string
+	}{
+		{
+			name:       "none",
+			wantPrefix: "Usage: tool ",
+			wantSuffix: "\thelp             describe subcommands and their syntax\n\tprint            print args\n\n",
+		},
+		{
+			name:       "header",
+			header:     "{{.Name}} manages things.\n",
+			wantPrefix: "tool manages things.\n\nUsage: tool ",
+		},
+		{
+			name:       "footer",
+			footer:     "Docs: https://example.com/{{.Name}}\nSupport: help@example.com",
+			wantPrefix: "Usage: tool ",
+			wantSuffix: "print args\n\n\nDocs: https://example.com/tool\nSupport: help@example.com\n",
+		},
+		{
+			name:       "both",
+			header:     "Header",
+			footer:     "Footer",
+			wantPrefix: "Header\n\nUsage: tool ",
+			wantSuffix: "\n\nFooter\n",
+		},
+		{
+			name:       "removed",
+			header:     "",
+			wantPrefix: "Usage: tool ",
+		},
+		{
+			name:       "failed template",
+			header:     "{{.Nope}}",
+			wantPrefix: "template: header:1:2: executing \"header\" at <.Nope>: can't evaluate field Nope",
+		},
+		{
+			name:       "help for a command",
+			header:     "Header",
+			footer:     "Footer",
+			args:       []string{"print"},
+			wantPrefix: "print:\n\tA command for tests.\n",
+			wantSuffix: "no newline\n",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cdr, stdout, _ := newTestCommander()
+			cdr.Register(cdr.HelpCommand(), "")
+			cdr.SetHelpHeader("Replaced")
+			cdr.SetHelpHeader(tt.header)
+			cdr.SetHelpFooter(tt.footer)
+			if status := cdr.Run(context.Background(), "help", tt.args); status != ExitSuccess {
+				t.Errorf("status %v, want %v", status, ExitSuccess)
+			}
+			got := stdout.String()
+			if !strings.HasPrefix(got, tt.wantPrefix) || !strings.HasSuffix(got, tt.wantSuffix) {
+				t.Errorf("printed\n%s\nwant it to start with\n%s\nand end with\n%s", got, tt.wantPrefix, tt.wantSuffix)
+			}
+		})
+	}
+}
+
+func TestHelpTextInvalid(t *testing.T) {
+	for _, set := range []func(*Commander, string){(*Commander).SetHelpHeader, (*Commander).SetHelpFooter, (*Commander).SetBanner} {
+		func() {
+			defer func() {
+				if recover() == nil {
+					t.Error("set an invalid template without panicking")
+				}
+			}()
+			cdr, _, _ := newTestCommander()
+			set(cdr, "{{.Name")
+		}()
+	}
+}
+
+func TestBannerNotTerminal(t *testing.T) {
+	// Help written elsewhere than to a terminal has no banner.
+	cdr, stdout, _ := newTestCommander()
+	cdr.Register(cdr.HelpCommand(), "")
+	cdr.SetBanner("== {{.Name}} ==")
+	cdr.SetHelpHeader("Header")
+	if status := cdr.Run(context.Background(), "help", nil); status != ExitSuccess {
+		t.Errorf("status %v, want %v", status, ExitSuccess)
+	}
+	if got, want := stdout.String(), "Header\n\nUsage: tool "; !strings.HasPrefix(got, want) {
+		t.Errorf("printed\n%s\nwant it to start with\n%s", got, want)
+	}
+}
@@ -0,0 +1,80 @@
+/*
+Copyright 2016 Google Inc. All Rights Reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package subcommands
+
+import (
+	"context"
+	"flag"
+)
+
+// PersistentPreRun adds a hook called before every command executed by
+// cdr, or by a Commander mounted in cdr by Mount, however deeply, such as
+// to open a database connection for all the "db" subcommands. The hook
+// is passed the command to be executed and returns the context to
+// execute it with. If it returns an error, the error is reported and the
+// command is not executed. Hooks are called after the flags of the
+// command are parsed and its middleware has run, those of the outermost
+// Commander first.
+//
+//	db.PersistentPreRun(func(ctx context.Context, cmd subcommands.Command) (context.Context, error) {
+//		conn, err := sql.Open("postgres", dsn)
+//		return context.WithValue(ctx, connKey{}, conn), err
+//	})
+//	cdr.Mount("db", db, "")
+func (cdr *Commander) PersistentPreRun(hook func(ctx context.Context, cmd Command) (context.Context, error)) {
+	cdr.preRun = append(cdr.preRun, hook)
+}
+
+// PersistentPostRun adds a hook called after every command executed by
+// cdr, or by a Commander mounted in cdr, returns, with the context
+// returned by the hooks added by PersistentPreRun and the status of the
+// command, or ExitFailure if a later hook failed. They are called only if
+// the hooks added to cdr by PersistentPreRun succeeded, those of the
+// outermost Commander last.
+func (cdr *Commander) PersistentPostRun(hook func(ctx context.Context, cmd Command, status ExitStatus)) {
+	cdr.postRun = append(cdr.postRun, hook)
+}
+
+// persistentHooks wraps exec in the hooks added to cdr and the
+// Commanders it is mounted in.
+func (cdr *Commander) persistentHooks(exec Executor) Executor {
+	var lineage []*Commander
+	for c := cdr; c != nil; c = c.parent {
+		lineage = append([]*Commander{c}, lineage...)
+	}
+	for i := len(lineage) - 1; i >= 0; i-- {
+		c, next := lineage[i], exec
+		if len(c.preRun) == 0 && len(c.postRun) == 0 {
+			continue
+		}
+		exec = func(ctx context.Context, cmd Command, f *flag.FlagSet, args ...interface{}) ExitStatus {
+			for _, hook := range c.preRun {
+				var err error
+				if ctx, err = hook(ctx, cmd); err != nil {
+					cdr.Errorf("%s: %v", cmd.Name(), err)
+					return ExitFailure
+				}
+			}
+			status := next(ctx, cmd, f, args...)
+			for i := len(c.postRun) - 1; i >= 0; i-- {
+				c.postRun[i](ctx, cmd, status)
+			}
+			return status
+		}
+	}
+	return exec
+}
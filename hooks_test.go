@@ -0,0 +1,111 @@
+/*
+Copyright 2016 Google Inc. All Rights Reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package subcommands
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"reflect"
+	"testing"
+)
+
+type hookKey struct{}
+
+func TestPersistentHooks(t *testing.T) {
+	tests := []struct {
+		name       string
+		args       []string
+		fail       string // the pre-run hook that fails
+		wantStatus ExitStatus
+		wantCalls  []string
+		wantStderr string
+	}{
+		{
+			name:       "mounted",
+			args:       []string{"db", "migrate"},
+			wantStatus: ExitSuccess,
+			wantCalls:  []string{"pre tool 1", "pre tool 2", "pre db", "migrate [tool 1 tool 2 db]", "post db success", "post tool 2 success", "post tool 1 success"},
+		},
+		{
+			name:       "top level",
+			args:       []string{"print"},
+			wantStatus: ExitSuccess,
+			wantCalls:  []string{"pre tool 1", "pre tool 2", "post tool 2 success", "post tool 1 success"},
+		},
+		{
+			name:       "inner hook fails",
+			args:       []string{"db", "migrate"},
+			fail:       "db",
+			wantStatus: ExitFailure,
+			wantCalls:  []string{"pre tool 1", "pre tool 2", "pre db", "post tool 2 failure", "post tool 1 failure"},
+			wantStderr: "tool db: migrate: locked\n",
+		},
+		{
+			name:       "outer hook fails",
+			args:       []string{"db", "migrate"},
+			fail:       "tool 2",
+			wantStatus: ExitFailure,
+			wantCalls:  []string{"pre tool 1", "pre tool 2"},
+			wantStderr: "tool db: migrate: locked\n",
+		},
+	}
+	for _, tt := range tests {
+		var stderr bytes.Buffer
+		var calls []string
+		hooks := func(cdr *Commander, name string) {
+			cdr.PersistentPreRun(func(ctx context.Context, cmd Command) (context.Context, error) {
+				calls = append(calls, "pre "+name)
+				if name == tt.fail {
+					return ctx, errors.New("locked")
+				}
+				trail, _ := ctx.Value(hookKey{}).([]string)
+				return context.WithValue(ctx, hookKey{}, append(trail, name)), nil
+			})
+			cdr.PersistentPostRun(func(ctx context.Context, cmd Command, status ExitStatus) {
+				calls = append(calls, fmt.Sprintf("post %s %v", name, status))
+			})
+		}
+
+		db := NewCommander(flag.NewFlagSet("db", flag.ContinueOnError), "db")
+		db.Register(&testCommand{name: "migrate", execute: func(ctx context.Context, f *flag.FlagSet) ExitStatus {
+			calls = append(calls, fmt.Sprintf("migrate %v", ctx.Value(hookKey{})))
+			return ExitSuccess
+		}}, "")
+		hooks(db, "db")
+
+		cdr, _, _ := newTestCommander()
+		cdr.Mount("db", db, "")
+		hooks(cdr, "tool 1")
+		hooks(cdr, "tool 2")
+		for _, c := range []*Commander{cdr, db} {
+			c.Error = &stderr
+		}
+
+		if status := execute(t, cdr, tt.args...); status != tt.wantStatus {
+			t.Errorf("%s: status %v, want %v", tt.name, status, tt.wantStatus)
+		}
+		if !reflect.DeepEqual(calls, tt.wantCalls) {
+			t.Errorf("%s: calls\n%q\nwant\n%q", tt.name, calls, tt.wantCalls)
+		}
+		if got := stderr.String(); got != tt.wantStderr {
+			t.Errorf("%s: stderr %q, want %q", tt.name, got, tt.wantStderr)
+		}
+	}
+}
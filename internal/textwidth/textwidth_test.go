@@ -0,0 +1,81 @@
+/*
+Copyright 2016 Google Inc. All Rights Reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package textwidth
+
+import "testing"
+
+func TestString(t *testing.T) {
+	tests := []struct {
+		s    string
+		want int
+	}{
+		{"", 0},
+		{"build", 5},
+		{"h\u00e9llo", 5},
+		{"he\u0301llo", 5}, // combining acute accent
+		{"日本語", 6},
+		{"한국어", 6},
+		{"ｆｕｌｌ", 8},
+		{"🚀 go", 5},
+		{"👍🏽", 2},           // skin tone modifier
+		{"\u2714\ufe0f", 1}, // variation selector
+		{"a\tb", 2},
+	}
+	for _, tt := range tests {
+		if got := String(tt.s); got != tt.want {
+			t.Errorf("String(%q) = %d, want %d", tt.s, got, tt.want)
+		}
+	}
+}
+
+func TestPad(t *testing.T) {
+	tests := []struct {
+		s     string
+		width int
+		want  string
+	}{
+		{"go", 4, "go  "},
+		{"日本", 6, "日本  "},
+		{"日本", 3, "日本"},
+		{"", 2, "  "},
+	}
+	for _, tt := range tests {
+		if got := Pad(tt.s, tt.width); got != tt.want {
+			t.Errorf("Pad(%q, %d) = %q, want %q", tt.s, tt.width, got, tt.want)
+		}
+	}
+}
+
+func TestTruncate(t *testing.T) {
+	tests := []struct {
+		s     string
+		width int
+		want  string
+	}{
+		{"build", 10, "build"},
+		{"build", 3, "bui"},
+		{"build", 0, ""},
+		{"日本語", 4, "日本"},
+		{"日本語", 5, "日本"},
+		{"he\u0301llo", 2, "he\u0301"},
+	}
+	for _, tt := range tests {
+		if got := Truncate(tt.s, tt.width); got != tt.want {
+			t.Errorf("Truncate(%q, %d) = %q, want %q", tt.s, tt.width, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,141 @@
+/*
+Copyright 2016 Google Inc. All Rights Reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package subcommands
+
+// A Registrar registers the commands of a group added by
+// RegisterGroupLazy.
+type Registrar interface {
+	// Register registers cmd in the group.
+	Register(cmd Command)
+}
+
+// A groupRegistrar is a Registrar registering commands in a group of a
+// Commander.
+type groupRegistrar struct {
+	cdr   *Commander
+	group string
+}
+
+func (r groupRegistrar) Register(cmd Command) { r.cdr.Register(cmd, r.group) }
+
+// RegisterGroupLazy adds a group whose commands are registered by load
+// only once they are needed, so that a program with many costly
+// commands starts quickly:
+//
+//	cdr.RegisterGroupLazy("cloud", func(r subcommands.Registrar) {
+//		r.Register(cloud.NewDeployCommand())
+//		r.Register(cloud.NewLogsCommand())
+//	}, "deploy", "logs")
+//
+// The names, if given, are those of the commands load registers. The
+// group is then loaded when help for it is requested, when one of its
+// names is looked up, as when its command is executed, and by
+// VisitCommands. A group declaring no names is loaded whenever a name is
+// looked up that is neither registered nor declared, such as an unknown
+// subcommand, and a section for one of its commands in a configuration
+// file read through ConfigFlag is accepted unchecked; if every such
+// group declares its names, an unknown subcommand is reported without
+// loading any. Until it is loaded, the top-level help lists the group
+// without its commands.
+func (cdr *Commander) RegisterGroupLazy(group string, load func(r Registrar), names ...string) {
+	for _, g := range cdr.commands {
+		if g.name == group {
+			cdr.loadGroup(g)
+			load(groupRegistrar{cdr, group})
+			return
+		}
+	}
+	cdr.commands = append(cdr.commands, &CommandGroup{name: group, load: load, declared: names})
+}
+
+// mayRegister reports whether the named command is registered, or may be
+// registered by a group added by RegisterGroupLazy that is not loaded,
+// without loading any.
+func (cdr *Commander) mayRegister(name string) bool {
+	if cdr.lookup(name) != nil {
+		return true
+	}
+	for _, g := range cdr.commands {
+		if g.load != nil && g.mayDeclare(name) {
+			return true
+		}
+	}
+	return false
+}
+
+// loadGroup registers the commands of group, if they are not yet.
+func (cdr *Commander) loadGroup(group *CommandGroup) {
+	if load := group.load; load != nil {
+		group.load, group.declared = nil, nil
+		load(groupRegistrar{cdr, group.name})
+	}
+}
+
+// loadGroupsFor registers the commands of the groups added by
+// RegisterGroupLazy that are not yet and declare the named command, or,
+// if there are none, of those declaring no names. It reports whether
+// there were any.
+func (cdr *Commander) loadGroupsFor(name string) bool {
+	var undeclared []*CommandGroup
+	for _, g := range cdr.commands {
+		if g.load == nil {
+			continue
+		}
+		if len(g.declared) == 0 {
+			undeclared = append(undeclared, g)
+			continue
+		}
+		for _, declared := range g.declared {
+			if declared == name {
+				cdr.loadGroup(g)
+				return true
+			}
+		}
+	}
+	for _, g := range undeclared {
+		cdr.loadGroup(g)
+	}
+	return len(undeclared) > 0
+}
+
+// mayDeclare reports whether group, added by RegisterGroupLazy, declares
+// the named command, or declares no names.
+func (group *CommandGroup) mayDeclare(name string) bool {
+	if len(group.declared) == 0 {
+		return true
+	}
+	for _, declared := range group.declared {
+		if declared == name {
+			return true
+		}
+	}
+	return false
+}
+
+// loadGroups registers the commands of all groups added by
+// RegisterGroupLazy that are not yet, and reports whether there were
+// any.
+func (cdr *Commander) loadGroups() bool {
+	loaded := false
+	for _, g := range cdr.commands {
+		if g.load != nil {
+			cdr.loadGroup(g)
+			loaded = true
+		}
+	}
+	return loaded
+}
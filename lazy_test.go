@@ -0,0 +1,173 @@
+/*
+Copyright 2016 Google Inc. All Rights Reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package subcommands
+
+import (
+	"bytes"
+	"context"
+	"flag"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+)
+
+// lazyCommander returns a Commander with the lazy groups "cloud", which
+// declares its commands deploy and logs, and "misc", which declares
+// none and registers cleanup, and the loads of each group so far.
+func lazyCommander() (*Commander, map[string]int) {
+	loads := make(map[string]int)
+	cdr := NewCommander(flag.NewFlagSet("tool", flag.ContinueOnError), "tool")
+	cdr.Output, cdr.Error = new(bytes.Buffer), new(bytes.Buffer)
+	cdr.Register(&testCommand{name: "local"}, "")
+	cdr.RegisterGroupLazy("cloud", func(r Registrar) {
+		loads["cloud"]++
+		r.Register(&testCommand{name: "deploy"})
+		r.Register(&testCommand{name: "logs"})
+	}, "deploy", "logs")
+	cdr.RegisterGroupLazy("misc", func(r Registrar) {
+		loads["misc"]++
+		r.Register(&testCommand{name: "cleanup"})
+	})
+	return cdr, loads
+}
+
+func TestLookupLazy(t *testing.T) {
+	tests := []struct {
+		name      string
+		found     bool
+		wantLoads map[string]int
+	}{
+		{"local", true, map[string]int{}},
+		{"deploy", true, map[string]int{"cloud": 1}},
+		{"logs", true, map[string]int{"cloud": 1}},
+		{"cleanup", true, map[string]int{"misc": 1}},
+		{"nosuch", false, map[string]int{"misc": 1}},
+	}
+	for _, tt := range tests {
+		cdr, loads := lazyCommander()
+		if got := cdr.Lookup(tt.name); (got != nil) != tt.found {
+			t.Errorf("Lookup(%q) = %v, want found %v", tt.name, got, tt.found)
+		}
+		if !reflect.DeepEqual(loads, tt.wantLoads) {
+			t.Errorf("Lookup(%q) loaded %v, want %v", tt.name, loads, tt.wantLoads)
+		}
+	}
+}
+
+func TestLookupLazyAllDeclared(t *testing.T) {
+	cdr := NewCommander(flag.NewFlagSet("tool", flag.ContinueOnError), "tool")
+	loaded := false
+	cdr.RegisterGroupLazy("cloud", func(r Registrar) {
+		loaded = true
+		r.Register(&testCommand{name: "deploy"})
+	}, "deploy")
+	if cmd := cdr.Lookup("nosuch"); cmd != nil {
+		t.Errorf("Lookup(nosuch) = %v, want nil", cmd)
+	}
+	if loaded {
+		t.Error("Lookup of a name no group declares loaded a group")
+	}
+	if cmd := cdr.Lookup("deploy"); cmd == nil || !loaded {
+		t.Errorf("Lookup(deploy) = %v, loaded %v; want the command, loaded", cmd, loaded)
+	}
+}
+
+func TestRunLazy(t *testing.T) {
+	tests := []struct {
+		argv       []string
+		wantStatus ExitStatus
+		wantLoads  map[string]int
+	}{
+		{[]string{"deploy"}, ExitSuccess, map[string]int{"cloud": 1}},
+		{[]string{"cleanup"}, ExitSuccess, map[string]int{"misc": 1}},
+		{[]string{"help", "cloud"}, ExitSuccess, map[string]int{"cloud": 1, "misc": 1}}, // "cloud" is looked up as a command first
+		{[]string{"commands"}, ExitSuccess, map[string]int{"cloud": 1, "misc": 1}},
+		{[]string{"nosuch"}, ExitUsageError, map[string]int{"misc": 1}},
+	}
+	for _, tt := range tests {
+		cdr, loads := lazyCommander()
+		cdr.Register(cdr.HelpCommand(), "")
+		cdr.Register(cdr.CommandsCommand(), "")
+		if status := cdr.Run(context.Background(), tt.argv[0], tt.argv[1:]); status != tt.wantStatus {
+			t.Errorf("Run(%q) = %v, want %v", tt.argv, status, tt.wantStatus)
+		}
+		if !reflect.DeepEqual(loads, tt.wantLoads) {
+			t.Errorf("Run(%q) loaded %v, want %v", tt.argv, loads, tt.wantLoads)
+		}
+	}
+}
+
+func TestResolveLazyWithMatcher(t *testing.T) {
+	cdr, loads := lazyCommander()
+	var known []string
+	cdr.SetMatcher(func(arg string, names []string) (string, bool) {
+		known = names
+		for _, name := range names {
+			if strings.HasPrefix(name, arg) {
+				return name, true
+			}
+		}
+		return "", false
+	})
+	if cmd := cdr.resolve("dep"); cmd == nil || cmd.Name() != "deploy" {
+		t.Errorf("resolve(dep) = %v, want deploy", cmd)
+	}
+	sort.Strings(known)
+	if want := []string{"cleanup", "deploy", "local", "logs"}; !reflect.DeepEqual(known, want) {
+		t.Errorf("matcher given %q, want %q", known, want)
+	}
+	if want := map[string]int{"cloud": 1, "misc": 1}; !reflect.DeepEqual(loads, want) {
+		t.Errorf("loaded %v, want %v", loads, want)
+	}
+}
+
+func TestConfigSectionsLazy(t *testing.T) {
+	tests := []struct {
+		config     string
+		withMisc   bool // add the group declaring no names
+		wantStatus ExitStatus
+	}{
+		{`{"deploy": {}}`, true, ExitSuccess},
+		{`{"cleanup": {}}`, true, ExitSuccess},
+		{`{"nosuch": {}}`, true, ExitSuccess}, // misc may register it
+		{`{"deploy": {}}`, false, ExitSuccess},
+		{`{"nosuch": {}}`, false, ExitUsageError},
+	}
+	for _, tt := range tests {
+		path := filepath.Join(t.TempDir(), "config.json")
+		if err := os.WriteFile(path, []byte(tt.config), 0o666); err != nil {
+			t.Fatal(err)
+		}
+		cdr, loads := lazyCommander()
+		if !tt.withMisc {
+			cdr.commands = cdr.commands[:len(cdr.commands)-1]
+		}
+		cdr.ConfigFlag("config")
+		if err := cdr.topFlags.Parse([]string{"-config", path}); err != nil {
+			t.Fatal(err)
+		}
+		if status := cdr.Run(context.Background(), "local", nil); status != tt.wantStatus {
+			t.Errorf("config %s: status %v, want %v; stderr: %s", tt.config, status, tt.wantStatus, cdr.Error)
+		}
+		if len(loads) != 0 {
+			t.Errorf("config %s: loaded %v, want no groups loaded", tt.config, loads)
+		}
+	}
+}
@@ -0,0 +1,133 @@
+/*
+Copyright 2016 Google Inc. All Rights Reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package subcommands
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io/fs"
+	"path"
+	"strings"
+)
+
+// A Notice is a license or credit notice of software included in a
+// program, such as a third-party module.
+type Notice struct {
+	Name string // Name identifies the software, such as its module path.
+	Text string // Text is the notice, such as the text of the license.
+}
+
+// AddNotices adds notices for the licenses builtin to print.
+func (cdr *Commander) AddNotices(notices ...Notice) {
+	cdr.notices = append(cdr.notices, notices...)
+}
+
+// NoticesFS returns a Notice for each file in fsys, such as an embed.FS
+// of the license files of the modules a program depends on. A file
+// named like LICENSE, COPYING or NOTICE is named by the directory it is
+// in; any other file by its path:
+//
+//	//go:embed third_party
+//	var thirdParty embed.FS
+//
+//	sub, _ := fs.Sub(thirdParty, "third_party")
+//	notices, err := subcommands.NoticesFS(sub)
+//	...
+//	cdr.AddNotices(notices...)
+//
+// With the file third_party/golang.org/x/text/LICENSE, the notice is
+// named golang.org/x/text.
+func NoticesFS(fsys fs.FS) ([]Notice, error) {
+	var notices []Notice
+	err := fs.WalkDir(fsys, ".", func(p string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return err
+		}
+		text, err := fs.ReadFile(fsys, p)
+		if err != nil {
+			return err
+		}
+		name := p
+		base := strings.ToUpper(path.Base(p))
+		for _, prefix := range []string{"LICENSE", "LICENCE", "COPYING", "NOTICE"} {
+			if strings.HasPrefix(base, prefix) && path.Dir(p) != "." {
+				name = path.Dir(p)
+				break
+			}
+		}
+		notices = append(notices, Notice{Name: name, Text: string(text)})
+		return nil
+	})
+	return notices, err
+}
+
+// A licenser is a Command implementing a "licenses" command for a given
+// Commander.
+type licenser struct {
+	cdr  *Commander
+	list bool
+}
+
+func (l *licenser) Name() string     { return "licenses" }
+func (l *licenser) Synopsis() string { return "print the licenses of software in this program" }
+func (l *licenser) SetFlags(f *flag.FlagSet) {
+	f.BoolVar(&l.list, "list", false, "list the names of the software only")
+}
+func (l *licenser) Usage() string {
+	return `licenses [-list] [<name>...]:
+	Print the license notices of the software included in this
+	program, or of the named software only.
+`
+}
+
+func (l *licenser) Execute(ctx context.Context, f *flag.FlagSet, _ ...interface{}) ExitStatus {
+	cdr := l.cdr.executing(ctx)
+	notices := cdr.notices
+	if f.NArg() > 0 {
+		notices = nil
+		for _, name := range f.Args() {
+			found := false
+			for _, n := range cdr.notices {
+				if n.Name == name {
+					notices, found = append(notices, n), true
+				}
+			}
+			if !found {
+				cdr.Errorf("licenses: no notice for %s", name)
+				return ExitFailure
+			}
+		}
+	}
+	for i, n := range notices {
+		if l.list {
+			fmt.Fprintln(cdr.Output, n.Name)
+			continue
+		}
+		if i > 0 {
+			fmt.Fprintln(cdr.Output)
+		}
+		fmt.Fprintf(cdr.Output, "%s\n%s\n\n%s\n", n.Name, strings.Repeat("=", len(n.Name)), strings.TrimRight(n.Text, "\n"))
+	}
+	return ExitSuccess
+}
+
+// LicensesCommand returns a Command which implements a "licenses"
+// subcommand, which prints the notices added by AddNotices.
+func (cdr *Commander) LicensesCommand() Command {
+	return &licenser{cdr: cdr}
+}
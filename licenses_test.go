@@ -0,0 +1,95 @@
+/*
+Copyright 2016 Google Inc. All Rights Reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package subcommands
+
+import (
+	"context"
+	"reflect"
+	"testing"
+	"testing/fstest"
+)
+
+func TestNoticesFS(t *testing.T) {
+	fsys := fstest.MapFS{
+		"golang.org/x/text/LICENSE":    {Data: []byte("BSD\n")},
+		"example.com/a/COPYING.txt":    {Data: []byte("GPL\n")},
+		"example.com/b/notice":         {Data: []byte("credits\n")},
+		"example.com/c/README":         {Data: []byte("readme\n")},
+		"LICENSE":                      {Data: []byte("top\n")},
+		"example.com/d/sub/Licence.md": {Data: []byte("MIT\n")},
+	}
+	got, err := NoticesFS(fsys)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []Notice{
+		{"LICENSE", "top\n"},
+		{"example.com/a", "GPL\n"},
+		{"example.com/b", "credits\n"},
+		{"example.com/c/README", "readme\n"},
+		{"example.com/d/sub", "MIT\n"},
+		{"golang.org/x/text", "BSD\n"},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("NoticesFS got %q, want %q", got, want)
+	}
+}
+
+func TestLicenses(t *testing.T) {
+	tests := []struct {
+		args       []string
+		wantStatus ExitStatus
+		wantStdout string
+		wantStderr string
+	}{
+		{
+			args:       nil,
+			wantStatus: ExitSuccess,
+			wantStdout: "a.io/x\n======\n\nMIT\n\nb.io/y\n======\n\nBSD\nlicense\n",
+		},
+		{
+			args:       []string{"-list"},
+			wantStatus: ExitSuccess,
+			wantStdout: "a.io/x\nb.io/y\n",
+		},
+		{
+			args:       []string{"b.io/y"},
+			wantStatus: ExitSuccess,
+			wantStdout: "b.io/y\n======\n\nBSD\nlicense\n",
+		},
+		{
+			args:       []string{"b.io/y", "c.io/z"},
+			wantStatus: ExitFailure,
+			wantStderr: "tool: licenses: no notice for c.io/z\n",
+		},
+	}
+	for _, tt := range tests {
+		cdr, stdout, stderr := newTestCommander()
+		cdr.Register(cdr.LicensesCommand(), "")
+		cdr.AddNotices(Notice{"a.io/x", "MIT"})
+		cdr.AddNotices(Notice{"b.io/y", "BSD\nlicense\n\n"})
+		if status := cdr.Run(context.Background(), "licenses", tt.args); status != tt.wantStatus {
+			t.Errorf("%q: status %v, want %v", tt.args, status, tt.wantStatus)
+		}
+		if got := stdout.String(); got != tt.wantStdout {
+			t.Errorf("%q: stdout %q, want %q", tt.args, got, tt.wantStdout)
+		}
+		if got := stderr.String(); got != tt.wantStderr {
+			t.Errorf("%q: stderr %q, want %q", tt.args, got, tt.wantStderr)
+		}
+	}
+}
@@ -0,0 +1,98 @@
+/*
+Copyright 2016 Google Inc. All Rights Reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package subcommands
+
+import "context"
+
+// An Initializer is a Command with work to do before its flags are
+// defined, such as loading remote feature flags or cached credentials
+// that decide which flags it has or their defaults.
+type Initializer interface {
+	// Init is called before SetFlags when the command is executed, with
+	// the context Execute will be called with. If it returns an error,
+	// the error is reported and the command is not executed. It is not
+	// called when the command is only described, as by help.
+	Init(ctx context.Context) error
+}
+
+// initialize calls the Init method of cmd, if it is an Initializer.
+func initialize(ctx context.Context, cmd Command) error {
+	if i, ok := dealias(cmd).(Initializer); ok {
+		return i.Init(ctx)
+	}
+	return nil
+}
+
+// A CleanUpper is a Command with resources to release after it runs,
+// such as open files or buffered output.
+type CleanUpper interface {
+	// CleanUp is called after Execute returns, with the status the
+	// command returns, or ExitFailure if Execute panics, in which case
+	// the panic continues after CleanUp returns. It is not called if
+	// the command is not executed, as when its flags fail to parse.
+	CleanUp(ctx context.Context, status ExitStatus)
+}
+
+// cleanUp calls the CleanUp method of cmd, if it is a CleanUpper, with
+// *status.
+func cleanUp(ctx context.Context, cmd Command, status *ExitStatus) {
+	if c, ok := dealias(cmd).(CleanUpper); ok {
+		c.CleanUp(ctx, *status)
+	}
+}
+
+// A Cloner is a Command that can make fresh copies of itself. Commands
+// keep the values of their flags in their own fields, so that a
+// Commander executing a command more than once, as a REPL, a test or
+// the batch builtin does, would otherwise leave the values of one
+// execution in place for the next, and could not execute the command
+// twice at once. A Commander executes a fresh clone of a Cloner each
+// time, with its flags unset:
+//
+//	func (c *fetchCmd) Clone() subcommands.Command { return &fetchCmd{client: c.client} }
+type Cloner interface {
+	// Clone returns a copy of the command, ready for SetFlags.
+	Clone() Command
+}
+
+// clone returns a clone of cmd, if it is a Cloner, or of the Cloner it
+// wraps, in the same wrapping; else it returns cmd.
+func clone(cmd Command) Command {
+	switch c := cmd.(type) {
+	case *aliaser:
+		cp := *c
+		cp.Command = clone(c.Command)
+		return &cp
+	case *retrier:
+		cp := *c
+		cp.Command = clone(c.Command)
+		return &cp
+	case *wrapper:
+		cp := *c
+		cp.Command = clone(c.Command)
+		return &cp
+	case Cloner:
+		return c.Clone()
+	}
+	return cmd
+}
+
+// cloneable reports whether cmd is a Cloner, or wraps one.
+func cloneable(cmd Command) bool {
+	_, ok := dealias(cmd).(Cloner)
+	return ok
+}
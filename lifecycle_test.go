@@ -0,0 +1,203 @@
+/*
+Copyright 2016 Google Inc. All Rights Reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package subcommands
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"reflect"
+	"testing"
+)
+
+// A lifecycleCommand is a testCommand recording the calls of its
+// CleanUp and Init methods.
+type lifecycleCommand struct {
+	testCommand
+	initErr error
+	panics  bool
+	calls   []string
+}
+
+func (c *lifecycleCommand) Init(ctx context.Context) error {
+	c.calls = append(c.calls, "init")
+	return c.initErr
+}
+
+func (c *lifecycleCommand) SetFlags(f *flag.FlagSet) {
+	c.calls = append(c.calls, "flags")
+	c.testCommand.SetFlags(f)
+}
+
+func (c *lifecycleCommand) Execute(ctx context.Context, f *flag.FlagSet, _ ...interface{}) ExitStatus {
+	c.calls = append(c.calls, "execute")
+	if c.panics {
+		panic("boom")
+	}
+	return c.testCommand.Execute(ctx, f)
+}
+
+func (c *lifecycleCommand) CleanUp(ctx context.Context, status ExitStatus) {
+	c.calls = append(c.calls, "cleanup "+status.String())
+}
+
+func TestCleanUp(t *testing.T) {
+	tests := []struct {
+		name      string
+		cmd       string
+		args      []string
+		status    ExitStatus
+		panics    bool
+		wantCalls []string
+	}{
+		{name: "success", cmd: "build", wantCalls: []string{"init", "flags", "execute", "cleanup success"}},
+		{name: "failure", cmd: "build", status: ExitFailure, wantCalls: []string{"init", "flags", "execute", "cleanup failure"}},
+		{name: "alias", cmd: "b", status: ExitCode(3), wantCalls: []string{"init", "flags", "execute", "cleanup exit status 3"}},
+		{name: "panic", cmd: "build", panics: true, wantCalls: []string{"init", "flags", "execute", "cleanup failure"}},
+		{name: "flag error", cmd: "build", args: []string{"-bogus"}, wantCalls: []string{"init", "flags"}},
+	}
+	for _, tt := range tests {
+		cdr, _, _ := newTestCommander()
+		cdr.UsageErrors = UsageSilent
+		build := &lifecycleCommand{testCommand: testCommand{name: "build", status: tt.status}, panics: tt.panics}
+		cdr.Register(build, "")
+		cdr.Register(Alias("b", build), "")
+		var recovered interface{}
+		func() {
+			defer func() { recovered = recover() }()
+			cdr.Run(context.Background(), tt.cmd, tt.args)
+		}()
+		if (recovered != nil) != tt.panics {
+			t.Errorf("%s: recovered %v", tt.name, recovered)
+		}
+		if !reflect.DeepEqual(build.calls, tt.wantCalls) {
+			t.Errorf("%s: calls %q, want %q", tt.name, build.calls, tt.wantCalls)
+		}
+	}
+}
+
+func TestInit(t *testing.T) {
+	tests := []struct {
+		name       string
+		initErr    error
+		args       []string // run with cdr.Run
+		wantStatus ExitStatus
+		wantCalls  []string
+		wantStderr string
+	}{
+		{name: "success", args: []string{"build"}, wantStatus: ExitSuccess, wantCalls: []string{"init", "flags", "execute", "cleanup success"}},
+		{name: "failure", initErr: errors.New("no credentials"), args: []string{"build"}, wantStatus: ExitFailure, wantCalls: []string{"init"}, wantStderr: "tool: build: no credentials\n"},
+		{name: "help", args: []string{"help", "build"}, wantStatus: ExitSuccess, wantCalls: []string{"flags"}},
+	}
+	for _, tt := range tests {
+		cdr, _, stderr := newTestCommander()
+		cdr.Register(cdr.HelpCommand(), "")
+		build := &lifecycleCommand{testCommand: testCommand{name: "build"}, initErr: tt.initErr}
+		cdr.Register(build, "")
+		if status := cdr.Run(context.Background(), tt.args[0], tt.args[1:]); status != tt.wantStatus {
+			t.Errorf("%s: status %v, want %v", tt.name, status, tt.wantStatus)
+		}
+		if !reflect.DeepEqual(build.calls, tt.wantCalls) {
+			t.Errorf("%s: calls %q, want %q", tt.name, build.calls, tt.wantCalls)
+		}
+		if got := stderr.String(); got != tt.wantStderr {
+			t.Errorf("%s: stderr %q, want %q", tt.name, got, tt.wantStderr)
+		}
+	}
+}
+
+type initKey struct{}
+
+// A ctxInitCommand is a testCommand whose Init records a value of the
+// context it is given.
+type ctxInitCommand struct {
+	testCommand
+	got interface{}
+}
+
+func (c *ctxInitCommand) Init(ctx context.Context) error {
+	c.got = ctx.Value(initKey{})
+	if CommanderFromContext(ctx) == nil {
+		return errors.New("no Commander in the context")
+	}
+	return nil
+}
+
+func TestInitContext(t *testing.T) {
+	cdr, _, stderr := newTestCommander()
+	cmd := &ctxInitCommand{testCommand: testCommand{name: "build"}}
+	cdr.Register(cmd, "")
+	ctx := context.WithValue(context.Background(), initKey{}, "v")
+	if status := cdr.Run(ctx, "build", nil); status != ExitSuccess {
+		t.Errorf("status %v, stderr %q", status, stderr)
+	}
+	if cmd.got != "v" {
+		t.Errorf("Init got context value %v, want v", cmd.got)
+	}
+}
+
+// A fetchCommand keeps the values of its flags in its fields, and
+// clones itself for each execution.
+type fetchCommand struct {
+	testCommand
+	tags   stringList
+	clones *int
+	seen   *[]string // the -tag values of each execution
+}
+
+func (c *fetchCommand) SetFlags(f *flag.FlagSet) { f.Var(&c.tags, "tag", "") }
+
+func (c *fetchCommand) Execute(ctx context.Context, f *flag.FlagSet, _ ...interface{}) ExitStatus {
+	*c.seen = append(*c.seen, c.tags.String())
+	return ExitSuccess
+}
+
+func (c *fetchCommand) Clone() Command {
+	*c.clones++
+	return &fetchCommand{testCommand: c.testCommand, clones: c.clones, seen: c.seen}
+}
+
+func TestClone(t *testing.T) {
+	tests := []struct {
+		name string
+		wrap func(Command) Command
+	}{
+		{"plain", func(c Command) Command { return c }},
+		{"alias", func(c Command) Command { return Alias("fetch", c) }},
+		{"wrap", func(c Command) Command { return Wrap(c) }},
+		{"retry", func(c Command) Command { return WithRetry(c, RetryPolicy{}) }},
+	}
+	for _, tt := range tests {
+		cdr, _, _ := newTestCommander()
+		var clones int
+		var seen []string
+		orig := &fetchCommand{testCommand: testCommand{name: "fetch"}, clones: &clones, seen: &seen}
+		cdr.Register(tt.wrap(orig), "")
+		cdr.Run(context.Background(), "fetch", []string{"-tag=a", "-tag=b"})
+		cdr.Run(context.Background(), "fetch", []string{"-tag=c"})
+		cdr.Run(context.Background(), "fetch", nil)
+		if want := []string{"a,b", "c", ""}; !reflect.DeepEqual(seen, want) {
+			t.Errorf("%s: executions saw -tag %q, want %q", tt.name, seen, want)
+		}
+		if clones != 3 {
+			t.Errorf("%s: cloned %d times, want 3", tt.name, clones)
+		}
+		if len(orig.tags) != 0 {
+			t.Errorf("%s: the registered command was set to %q", tt.name, orig.tags)
+		}
+	}
+}
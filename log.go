@@ -0,0 +1,127 @@
+/*
+Copyright 2016 Google Inc. All Rights Reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package subcommands
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// A Level is the severity of a message a Commander prints about its
+// work, as opposed to the output of a command.
+type Level int
+
+const (
+	LevelInfo    Level = iota // The message reports progress, such as a file written.
+	LevelWarning              // The message needs no action now, as for a deprecated flag.
+	LevelError                // The message reports a failure.
+)
+
+func (l Level) String() string {
+	switch l {
+	case LevelInfo:
+		return "info"
+	case LevelWarning:
+		return "warning"
+	case LevelError:
+		return "error"
+	}
+	return "unknown"
+}
+
+// SetLogHandler makes the Commander pass the messages printed by Logf,
+// Infof, Warnf and Errorf, without prefix or trailing newline, to
+// handler instead of printing them, to route them to a structured
+// logger. A nil handler restores printing.
+//
+//	cdr.SetLogHandler(func(level subcommands.Level, msg string) {
+//		slog.Log(ctx, levels[level], msg)
+//	})
+func (cdr *Commander) SetLogHandler(handler func(level Level, msg string)) {
+	cdr.logHandler = handler
+}
+
+// Logf prints a message at the given level, prefixed with the display
+// name of the Commander, and "warning: " for a warning, and ended with
+// a newline if it has none. Warnings are printed to the Warnings of the
+// Commander, or its Error if Warnings is nil; other messages to its
+// Error. Setting the environment variable derived as by BindEnv from
+// the name NO_WARNINGS, as in MYTOOL_NO_WARNINGS=1, silences warnings,
+// as for CI logs; in silent mode, set by SetSilent, only errors are
+// printed. The builtins report their errors with it.
+func (cdr *Commander) Logf(level Level, format string, args ...interface{}) {
+	if level < LevelError && cdr.silent || level == LevelWarning && cdr.warningsSilenced() {
+		return
+	}
+	msg := strings.TrimSuffix(fmt.Sprintf(format, args...), "\n")
+	if cdr.logHandler != nil {
+		cdr.logHandler(level, msg)
+		return
+	}
+	w, prefix := cdr.Error, ""
+	if level == LevelWarning {
+		if cdr.Warnings != nil {
+			w = cdr.Warnings
+		}
+		prefix = "warning: "
+	}
+	fmt.Fprintf(w, "%s: %s%s\n", cdr.DisplayName(), prefix, msg)
+}
+
+// Infof is like Logf at LevelInfo.
+func (cdr *Commander) Infof(format string, args ...interface{}) {
+	cdr.Logf(LevelInfo, format, args...)
+}
+
+// Warnf is like Logf at LevelWarning. It prints a warning that needs no
+// action now, such as that a command or flag is deprecated or that a
+// command is being retried.
+func (cdr *Commander) Warnf(format string, args ...interface{}) {
+	cdr.Logf(LevelWarning, format, args...)
+}
+
+// Errorf is like Logf at LevelError.
+func (cdr *Commander) Errorf(format string, args ...interface{}) {
+	cdr.Logf(LevelError, format, args...)
+}
+
+// Logf prints a message at the given level by the Logf method of the
+// Commander executing the command that was passed ctx, or of the
+// DefaultCommander if there is none, so that commands report as the
+// builtins do:
+//
+//	subcommands.Logf(ctx, subcommands.LevelWarning, "-legacy is deprecated; use -mode=legacy")
+func Logf(ctx context.Context, level Level, format string, args ...interface{}) {
+	cdr := CommanderFromContext(ctx)
+	if cdr == nil {
+		cdr = DefaultCommander
+	}
+	cdr.Logf(level, format, args...)
+}
+
+// warningsSilenced reports whether the environment variable read by
+// Logf, named by cdr.EnvName, is set to a true value.
+func (cdr *Commander) warningsSilenced() bool {
+	value, ok := cdr.LookupEnv(cdr.EnvName(nil, "no_warnings"))
+	if !ok {
+		return false
+	}
+	silenced, err := strconv.ParseBool(value)
+	return silenced || (err != nil && value != "")
+}
@@ -0,0 +1,190 @@
+/*
+Copyright 2016 Google Inc. All Rights Reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package subcommands
+
+import (
+	"bytes"
+	"context"
+	"flag"
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestLogf(t *testing.T) {
+	tests := []struct {
+		name         string
+		level        Level
+		msg          string
+		silent       bool
+		noWarnings   string
+		withWarnings bool
+		wantError    string
+		wantWarnings string
+	}{
+		{name: "error", level: LevelError, msg: "failed", wantError: "tool: failed\n"},
+		{name: "info", level: LevelInfo, msg: "wrote x\n", wantError: "tool: wrote x\n"},
+		{name: "warning", level: LevelWarning, msg: "deprecated", wantError: "tool: warning: deprecated\n"},
+		{name: "warning to Warnings", level: LevelWarning, msg: "deprecated", withWarnings: true, wantWarnings: "tool: warning: deprecated\n"},
+		{name: "silenced warning", level: LevelWarning, msg: "deprecated", noWarnings: "1"},
+		{name: "warning not silenced", level: LevelWarning, msg: "deprecated", noWarnings: "false", wantError: "tool: warning: deprecated\n"},
+		{name: "error with no warnings", level: LevelError, msg: "failed", noWarnings: "1", wantError: "tool: failed\n"},
+		{name: "silent info", level: LevelInfo, msg: "wrote x", silent: true},
+		{name: "silent warning", level: LevelWarning, msg: "deprecated", silent: true},
+		{name: "silent error", level: LevelError, msg: "failed", silent: true, wantError: "tool: failed\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var stderr, warnings bytes.Buffer
+			cdr := NewCommander(flag.NewFlagSet("tool", flag.ContinueOnError), "tool")
+			cdr.Error = &stderr
+			if tt.withWarnings {
+				cdr.Warnings = &warnings
+			}
+			cdr.SetSilent(tt.silent)
+			if tt.noWarnings != "" {
+				t.Setenv("TOOL_NO_WARNINGS", tt.noWarnings)
+			}
+			cdr.Logf(tt.level, "%s", tt.msg)
+			if got := stderr.String(); got != tt.wantError {
+				t.Errorf("Error got %q, want %q", got, tt.wantError)
+			}
+			if got := warnings.String(); got != tt.wantWarnings {
+				t.Errorf("Warnings got %q, want %q", got, tt.wantWarnings)
+			}
+		})
+	}
+}
+
+func TestLogHandler(t *testing.T) {
+	var stderr bytes.Buffer
+	cdr := NewCommander(flag.NewFlagSet("tool", flag.ContinueOnError), "tool")
+	cdr.Error = &stderr
+	cdr.Register(cdr.HelpCommand(), "")
+	cdr.Register(cdr.FlagsCommand(), "")
+	cdr.Register(cdr.SearchCommand(), "")
+	var got []string
+	cdr.SetLogHandler(func(level Level, msg string) {
+		got = append(got, fmt.Sprintf("%v: %s", level, msg))
+	})
+
+	ctx := context.Background()
+	cdr.Warnf("old %s", "flag")
+	cdr.Run(ctx, "help", []string{"nosuch"})
+	cdr.Run(ctx, "flags", []string{"nosuch"})
+	cdr.Run(ctx, "search", []string{"nosuch"})
+	Logf(withInvocation(ctx, cdr, cdr.HelpCommand(), ""), LevelInfo, "done")
+
+	want := []string{
+		"warning: old flag",
+		"error: help: subcommand nosuch not understood",
+		"error: flags: subcommand nosuch not understood",
+		`error: search: no subcommands match "nosuch"`,
+		"info: done",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("handler got %q, want %q", got, want)
+	}
+	if bytes.Contains(stderr.Bytes(), []byte("nosuch not understood")) {
+		t.Errorf("messages for the handler were also printed: %q", stderr.String())
+	}
+}
+
+func TestWarnings(t *testing.T) {
+	tests := []struct {
+		name         string
+		env          map[string]string
+		dotEnv       string // the content of a file given to LoadDotEnv
+		envName      func(Command, string) string
+		withWarnings bool
+		wantError    string
+		wantWarnings string
+	}{
+		{
+			name:      "to Error",
+			wantError: "tool: warning: -old is deprecated\ntool: warning: fetch: attempt 1 of 2 failed; retrying in 1ms\n",
+		},
+		{
+			name:         "to Warnings",
+			withWarnings: true,
+			wantWarnings: "tool: warning: -old is deprecated\ntool: warning: fetch: attempt 1 of 2 failed; retrying in 1ms\n",
+		},
+		{
+			name:         "silenced",
+			env:          map[string]string{"TOOL_NO_WARNINGS": "true"},
+			withWarnings: true,
+		},
+		{
+			name:   "silenced by dotenv file",
+			dotEnv: "TOOL_NO_WARNINGS=1\n",
+		},
+		{
+			name:    "silenced with custom names",
+			env:     map[string]string{"X_NO_WARNINGS": "yes"},
+			envName: func(_ Command, name string) string { return "X_" + strings.ToUpper(name) },
+		},
+		{
+			name:      "default name with custom names",
+			env:       map[string]string{"TOOL_NO_WARNINGS": "1"},
+			envName:   func(_ Command, name string) string { return "X_" + strings.ToUpper(name) },
+			wantError: "tool: warning: -old is deprecated\ntool: warning: fetch: attempt 1 of 2 failed; retrying in 1ms\n",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for k, v := range tt.env {
+				t.Setenv(k, v)
+			}
+			var stderr, warnings bytes.Buffer
+			cdr := NewCommander(flag.NewFlagSet("tool", flag.ContinueOnError), "tool")
+			cdr.Error = &stderr
+			if tt.withWarnings {
+				cdr.Warnings = &warnings
+			}
+			if tt.envName != nil {
+				cdr.EnvName = tt.envName
+			}
+			if tt.dotEnv != "" {
+				if err := cdr.LoadDotEnv(writeFile(t, ".env", tt.dotEnv)); err != nil {
+					t.Fatal(err)
+				}
+			}
+			attempt := 0
+			cmd := &testCommand{name: "fetch", execute: func(ctx context.Context, f *flag.FlagSet) ExitStatus {
+				attempt++
+				if attempt == 1 {
+					Logf(ctx, LevelWarning, "-old is deprecated")
+					return ExitFailure
+				}
+				return ExitSuccess
+			}}
+			cdr.Register(WithRetry(cmd, RetryPolicy{Attempts: 2, Backoff: time.Millisecond}), "")
+
+			if status := cdr.Run(context.Background(), "fetch", nil); status != ExitSuccess {
+				t.Errorf("status %v, want %v", status, ExitSuccess)
+			}
+			if got := stderr.String(); got != tt.wantError {
+				t.Errorf("Error got %q, want %q", got, tt.wantError)
+			}
+			if got := warnings.String(); got != tt.wantWarnings {
+				t.Errorf("Warnings got %q, want %q", got, tt.wantWarnings)
+			}
+		})
+	}
+}
@@ -0,0 +1,210 @@
+/*
+Copyright 2016 Google Inc. All Rights Reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package subcommands
+
+import (
+	"context"
+	"flag"
+	"io"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+)
+
+// Main parses the command line into flag.CommandLine, executes the
+// DefaultCommander with a context that is canceled when the program
+// receives SIGINT or SIGTERM, and exits with the resulting status. If
+// the subcommand does not return within the GracePeriod of the
+// Commander after the signal, or another signal is received, Main exits
+// with ExitInterrupted without waiting further. It replaces the usual
+// end of main:
+//
+//	flag.Parse()
+//	ctx := context.Background()
+//	os.Exit(int(subcommands.Execute(ctx)))
+func Main() {
+	MainWithCommander(DefaultCommander)
+}
+
+// MainWithCommander is like Main, but parses the command line into the
+// top-level flags of cdr, unless they have already been parsed, and
+// executes cdr.
+func MainWithCommander(cdr *Commander) {
+	if !cdr.topFlags.Parsed() {
+		if err := cdr.topFlags.Parse(os.Args[1:]); err == flag.ErrHelp {
+			os.Exit(int(ExitSuccess))
+		} else if err != nil {
+			os.Exit(int(ExitUsageError))
+		}
+	}
+	os.Exit(int(cdr.executeInterruptibly(context.Background())))
+}
+
+// executeInterruptibly executes cdr with a context that is canceled when
+// the program receives SIGINT or SIGTERM, with a cause naming the
+// signal. If the subcommand has not
+// returned after cdr.GracePeriod, or when a second signal is received,
+// it gives up waiting and returns ExitInterrupted.
+func (cdr *Commander) executeInterruptibly(ctx context.Context) ExitStatus {
+	ctx, cancel := context.WithCancelCause(ctx)
+	defer cancel(nil)
+	sigs := make(chan os.Signal, 2)
+	signal.Notify(sigs, os.Interrupt, syscall.SIGTERM)
+	defer signal.Stop(sigs)
+
+	done := make(chan ExitStatus, 1)
+	go func() { done <- cdr.Execute(ctx) }()
+	select {
+	case status := <-done:
+		return status
+	case sig := <-sigs:
+		cancel(signalError{sig})
+	}
+
+	var grace <-chan time.Time
+	if cdr.GracePeriod > 0 {
+		timer := time.NewTimer(cdr.GracePeriod)
+		defer timer.Stop()
+		grace = timer.C
+	}
+	select {
+	case status := <-done:
+		return status
+	case <-sigs:
+		cdr.Errorf("interrupted again; exiting")
+	case <-grace:
+		cdr.Errorf("interrupted, and did not stop within %v; exiting", cdr.GracePeriod)
+	}
+	return ExitInterrupted
+}
+
+// A signalError is the cause of the cancellation of the context of a
+// command by a signal.
+type signalError struct {
+	sig os.Signal
+}
+
+func (e signalError) Error() string {
+	name := e.sig.String()
+	switch e.sig {
+	case syscall.SIGINT:
+		name = "SIGINT"
+	case syscall.SIGTERM:
+		name = "SIGTERM"
+	}
+	return "canceled by " + name
+}
+
+// An Option configures the Commander built by Run.
+type Option func(*runConfig)
+
+// runConfig holds the configuration set by Options.
+type runConfig struct {
+	name     string
+	args     []string
+	setFlags []func(*flag.FlagSet)
+	setup    []func(*Commander)
+}
+
+// WithName sets the name of the command (default: the base name of
+// os.Args[0]).
+func WithName(name string) Option {
+	return func(c *runConfig) { c.name = name }
+}
+
+// WithArgs sets the command line arguments to parse, not including the
+// command name (default: os.Args[1:]).
+func WithArgs(args ...string) Option {
+	return func(c *runConfig) { c.args = args }
+}
+
+// WithTopFlags adds the flags defined by setFlags to the top-level flags.
+func WithTopFlags(setFlags func(*flag.FlagSet)) Option {
+	return func(c *runConfig) { c.setFlags = append(c.setFlags, setFlags) }
+}
+
+// WithCommands registers the commands in the specified group.
+func WithCommands(group string, cmds ...Command) Option {
+	return func(c *runConfig) {
+		c.setup = append(c.setup, func(cdr *Commander) {
+			for _, cmd := range cmds {
+				cdr.Register(cmd, group)
+			}
+		})
+	}
+}
+
+// WithBuiltins registers the help, flags and commands builtins in the
+// unnamed group.
+func WithBuiltins() Option {
+	return func(c *runConfig) {
+		c.setup = append(c.setup, func(cdr *Commander) {
+			cdr.Register(cdr.HelpCommand(), "")
+			cdr.Register(cdr.FlagsCommand(), "")
+			cdr.Register(cdr.CommandsCommand(), "")
+		})
+	}
+}
+
+// WithOutput sets the Output and Error of the Commander.
+func WithOutput(stdout, stderr io.Writer) Option {
+	return func(c *runConfig) {
+		c.setup = append(c.setup, func(cdr *Commander) {
+			cdr.Output, cdr.Error = stdout, stderr
+		})
+	}
+}
+
+// WithSetup calls setup with the Commander before the command line is
+// parsed, to configure it in ways no other Option provides.
+func WithSetup(setup func(*Commander)) Option {
+	return func(c *runConfig) { c.setup = append(c.setup, setup) }
+}
+
+// Run builds a Commander with its own top-level flags, configured by
+// opts, parses the command line and executes the Commander with ctx.
+// It suits small tools, and tests that need a throwaway command line
+// interface:
+//
+//	status := subcommands.Run(ctx,
+//		subcommands.WithArgs("print", "-capitalize", "hello"),
+//		subcommands.WithCommands("", &printCmd{}),
+//		subcommands.WithBuiltins())
+func Run(ctx context.Context, opts ...Option) ExitStatus {
+	c := &runConfig{name: programName(os.Args[0]), args: os.Args[1:]}
+	for _, opt := range opts {
+		opt(c)
+	}
+
+	topFlags := flag.NewFlagSet(c.name, flag.ContinueOnError)
+	for _, setFlags := range c.setFlags {
+		setFlags(topFlags)
+	}
+	cdr := NewCommander(topFlags, c.name)
+	for _, setup := range c.setup {
+		setup(cdr)
+	}
+	topFlags.SetOutput(cdr.Error)
+
+	if err := topFlags.Parse(c.args); err == flag.ErrHelp {
+		return ExitSuccess
+	} else if err != nil {
+		return ExitUsageError
+	}
+	return cdr.Execute(ctx)
+}
@@ -0,0 +1,194 @@
+/*
+Copyright 2016 Google Inc. All Rights Reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package subcommands
+
+import (
+	"bytes"
+	"context"
+	"flag"
+	"os"
+	"reflect"
+	"runtime"
+	"strings"
+	"syscall"
+	"testing"
+	"time"
+)
+
+func TestExecuteInterruptibly(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("the process cannot send itself signals on Windows")
+	}
+	tests := []struct {
+		name       string
+		grace      time.Duration
+		sig        syscall.Signal // sent to the process by the command (default: SIGINT)
+		signals    int            // how many times
+		stop       bool           // whether the command returns once canceled
+		wantStatus ExitStatus
+		wantCause  string
+		wantStderr string
+	}{
+		{name: "not interrupted", wantStatus: ExitSuccess},
+		{name: "stops", signals: 1, stop: true, wantStatus: ExitInterrupted, wantCause: "canceled by SIGINT", wantStderr: "tool: wait: canceled by SIGINT\n"},
+		{name: "grace period", grace: 10 * time.Millisecond, signals: 1, wantStatus: ExitInterrupted, wantCause: "canceled by SIGINT", wantStderr: "tool: interrupted, and did not stop within 10ms; exiting\n"},
+		{name: "terminated", sig: syscall.SIGTERM, signals: 1, stop: true, wantStatus: ExitInterrupted, wantCause: "canceled by SIGTERM", wantStderr: "tool: wait: canceled by SIGTERM\n"},
+		{name: "interrupted again", signals: 2, wantStatus: ExitInterrupted, wantCause: "canceled by SIGINT", wantStderr: "tool: interrupted again; exiting\n"},
+	}
+	for _, tt := range tests {
+		tt := tt // the command may still be running after the subtest
+		t.Run(tt.name, func(t *testing.T) {
+			cdr, _, stderr := newTestCommander()
+			cdr.GracePeriod = tt.grace
+			sig := tt.sig
+			if sig == 0 {
+				sig = syscall.SIGINT
+			}
+			release := make(chan struct{})
+			defer close(release)
+			canceled := make(chan error, 1)
+			self, err := os.FindProcess(os.Getpid())
+			if err != nil {
+				t.Fatal(err)
+			}
+			cdr.Register(&testCommand{name: "wait", execute: func(ctx context.Context, f *flag.FlagSet) ExitStatus {
+				for i := 0; i < tt.signals; i++ {
+					self.Signal(sig)
+					if i == 0 {
+						<-ctx.Done()
+						canceled <- context.Cause(ctx)
+					}
+				}
+				if tt.signals == 0 {
+					return ExitSuccess
+				}
+				if tt.stop {
+					return ExitFailure
+				}
+				<-release
+				return ExitFailure
+			}}, "")
+			if err := cdr.topFlags.Parse([]string{"wait"}); err != nil {
+				t.Fatal(err)
+			}
+			if status := cdr.executeInterruptibly(context.Background()); status != tt.wantStatus {
+				t.Errorf("status %v, want %v", status, tt.wantStatus)
+			}
+			if tt.wantCause != "" {
+				if cause := <-canceled; cause == nil || cause.Error() != tt.wantCause {
+					t.Errorf("cause %v, want %q", cause, tt.wantCause)
+				}
+			}
+			if got := stderr.String(); got != tt.wantStderr {
+				t.Errorf("stderr %q, want %q", got, tt.wantStderr)
+			}
+		})
+	}
+}
+
+func TestSignalError(t *testing.T) {
+	tests := []struct {
+		sig  os.Signal
+		want string
+	}{
+		{os.Interrupt, "canceled by SIGINT"},
+		{syscall.SIGTERM, "canceled by SIGTERM"},
+		{syscall.SIGHUP, "canceled by hangup"},
+	}
+	for _, tt := range tests {
+		if got := (signalError{tt.sig}).Error(); got != tt.want {
+			t.Errorf("signalError{%v}.Error() = %q, want %q", tt.sig, got, tt.want)
+		}
+	}
+}
+
+func TestRun(t *testing.T) {
+	tests := []struct {
+		name       string
+		opts       []Option
+		wantStatus ExitStatus
+		wantArgs   []string
+		wantStdout string
+		wantStderr string
+	}{
+		{
+			name:       "command",
+			opts:       []Option{WithArgs("print", "a", "b")},
+			wantStatus: ExitSuccess,
+			wantArgs:   []string{"a", "b"},
+		},
+		{
+			name: "top flags",
+			opts: []Option{
+				WithTopFlags(func(f *flag.FlagSet) { f.Bool("v", false, "verbose") }),
+				WithArgs("-v", "print", "a"),
+			},
+			wantStatus: ExitSuccess,
+			wantArgs:   []string{"a"},
+		},
+		{
+			name:       "unknown top flag",
+			opts:       []Option{WithArgs("-v", "print")},
+			wantStatus: ExitUsageError,
+			wantStderr: "flag provided but not defined: -v\n",
+		},
+		{
+			name:       "builtins",
+			opts:       []Option{WithBuiltins(), WithArgs("commands")},
+			wantStatus: ExitSuccess,
+			wantStdout: "print\nhelp\nflags\ncommands\n",
+		},
+		{
+			name:       "no builtins",
+			opts:       []Option{WithArgs("commands")},
+			wantStatus: ExitUsageError,
+			wantStderr: "Usage: mytool <flags> <subcommand> <subcommand args>\n",
+		},
+		{
+			name: "setup",
+			opts: []Option{
+				WithSetup(func(cdr *Commander) { cdr.Register(cdr.HelpCommand(), "") }),
+				WithArgs("help", "print"),
+			},
+			wantStatus: ExitSuccess,
+			wantStdout: "print:\n\tA command for tests.\n",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var stdout, stderr bytes.Buffer
+			print := &testCommand{name: "print"}
+			opts := append([]Option{
+				WithName("mytool"),
+				WithOutput(&stdout, &stderr),
+				WithCommands("", print),
+			}, tt.opts...)
+			if status := Run(context.Background(), opts...); status != tt.wantStatus {
+				t.Errorf("status %v, want %v", status, tt.wantStatus)
+			}
+			if tt.wantArgs != nil && !reflect.DeepEqual(print.args, tt.wantArgs) {
+				t.Errorf("print got args %q, want %q", print.args, tt.wantArgs)
+			}
+			if got := stdout.String(); got != tt.wantStdout {
+				t.Errorf("stdout %q, want %q", got, tt.wantStdout)
+			}
+			if got := stderr.String(); !strings.HasPrefix(got, tt.wantStderr) || tt.wantStderr == "" && got != "" {
+				t.Errorf("stderr %q, want it to start with %q", got, tt.wantStderr)
+			}
+		})
+	}
+}
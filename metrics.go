@@ -0,0 +1,45 @@
+/*
+Copyright 2016 Google Inc. All Rights Reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package subcommands
+
+import "time"
+
+// Metrics records how often subcommands are executed, how they end and
+// how long they run, for tools deployed to many machines that report
+// their usage and failure rates. The package
+// github.com/google/subcommands/metrics/prometheus implements it for
+// Prometheus.
+type Metrics interface {
+	// IncInvocation counts an execution of the named command, including
+	// one rejected before it ran, that ended with status.
+	IncInvocation(cmd string, status ExitStatus)
+	// ObserveDuration records how long an execution of the named command
+	// ran, from the call of its middleware to its return.
+	ObserveDuration(cmd string, d time.Duration)
+}
+
+// SetMetrics sets the Metrics the Commander reports executions of
+// subcommands to, by Execute or Run, or none if m is nil.
+func (cdr *Commander) SetMetrics(m Metrics) {
+	cdr.metrics = m
+}
+
+// countInvocation reports the execution of cmd ending with *status to
+// the Metrics set by SetMetrics. It must be deferred.
+func (cdr *Commander) countInvocation(cmd Command, status *ExitStatus) {
+	cdr.metrics.IncInvocation(cmd.Name(), *status)
+}
@@ -0,0 +1,84 @@
+/*
+Copyright 2016 Google Inc. All Rights Reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package prometheus reports the executions of subcommands to
+// Prometheus, as a subcommands.Metrics:
+//
+//	m, err := prometheus.New(nil, "mytool")
+//	if err != nil {
+//		log.Fatal(err)
+//	}
+//	subcommands.DefaultCommander.SetMetrics(m)
+//
+// It records the counter <namespace>_command_invocations_total, by
+// command and status, and the histogram
+// <namespace>_command_duration_seconds, by command.
+package prometheus
+
+import (
+	"time"
+
+	"github.com/google/subcommands"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// Metrics is a subcommands.Metrics recording to Prometheus collectors.
+type Metrics struct {
+	invocations *prometheus.CounterVec
+	duration    *prometheus.HistogramVec
+}
+
+var _ subcommands.Metrics = (*Metrics)(nil)
+
+// New returns Metrics whose collectors have names in namespace, which
+// may be empty, and are registered with reg, or with
+// prometheus.DefaultRegisterer if reg is nil.
+func New(reg prometheus.Registerer, namespace string) (*Metrics, error) {
+	if reg == nil {
+		reg = prometheus.DefaultRegisterer
+	}
+	m := &Metrics{
+		invocations: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "command_invocations_total",
+			Help:      "Executions of subcommands, by command and exit status.",
+		}, []string{"command", "status"}),
+		duration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Name:      "command_duration_seconds",
+			Help:      "How long subcommands ran, by command.",
+			Buckets:   prometheus.DefBuckets,
+		}, []string{"command"}),
+	}
+	for _, c := range []prometheus.Collector{m.invocations, m.duration} {
+		if err := reg.Register(c); err != nil {
+			return nil, err
+		}
+	}
+	return m, nil
+}
+
+// IncInvocation counts an execution of the named command ending with
+// status, labeled by the name of the status, such as "usage error".
+func (m *Metrics) IncInvocation(cmd string, status subcommands.ExitStatus) {
+	m.invocations.WithLabelValues(cmd, status.String()).Inc()
+}
+
+// ObserveDuration records that an execution of the named command ran
+// for d.
+func (m *Metrics) ObserveDuration(cmd string, d time.Duration) {
+	m.duration.WithLabelValues(cmd).Observe(d.Seconds())
+}
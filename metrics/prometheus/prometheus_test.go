@@ -0,0 +1,109 @@
+/*
+Copyright 2016 Google Inc. All Rights Reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package prometheus
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/google/subcommands"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// statusCommand is a subcommands.Command returning the status named by
+// its argument.
+type statusCommand struct{}
+
+func (statusCommand) Name() string           { return "get" }
+func (statusCommand) Synopsis() string       { return "" }
+func (statusCommand) Usage() string          { return "" }
+func (statusCommand) SetFlags(*flag.FlagSet) {}
+func (statusCommand) Execute(_ context.Context, f *flag.FlagSet, _ ...interface{}) subcommands.ExitStatus {
+	status, _ := subcommands.ParseExitStatus(f.Arg(0))
+	return status
+}
+
+func TestMetrics(t *testing.T) {
+	tests := []struct {
+		namespace string
+		args      [][]string // of each run of the command
+		want      []string   // the series gathered, with their counts
+	}{
+		{
+			namespace: "tool",
+			args:      [][]string{{"success"}, {"failure"}, {"success"}, {"-x"}},
+			want: []string{
+				`tool_command_duration_seconds{command="get"} 3`,
+				`tool_command_invocations_total{command="get",status="failure"} 1`,
+				`tool_command_invocations_total{command="get",status="success"} 2`,
+				`tool_command_invocations_total{command="get",status="usage error"} 1`,
+			},
+		},
+		{
+			namespace: "",
+			args:      [][]string{{"success"}},
+			want: []string{
+				`command_duration_seconds{command="get"} 1`,
+				`command_invocations_total{command="get",status="success"} 1`,
+			},
+		},
+	}
+	for _, tt := range tests {
+		reg := prometheus.NewPedanticRegistry()
+		m, err := New(reg, tt.namespace)
+		if err != nil {
+			t.Fatal(err)
+		}
+		cdr := subcommands.NewCommander(flag.NewFlagSet("tool", flag.ContinueOnError), "tool")
+		cdr.Output, cdr.Error = io.Discard, io.Discard
+		cdr.Register(statusCommand{}, "")
+		cdr.SetMetrics(m)
+		for _, args := range tt.args {
+			cdr.Run(context.Background(), "get", args)
+		}
+
+		families, err := reg.Gather()
+		if err != nil {
+			t.Fatal(err)
+		}
+		var got []string
+		for _, family := range families {
+			for _, metric := range family.GetMetric() {
+				var labels []string
+				for _, l := range metric.GetLabel() {
+					labels = append(labels, fmt.Sprintf("%s=%q", l.GetName(), l.GetValue()))
+				}
+				count := metric.GetCounter().GetValue()
+				if h := metric.GetHistogram(); h != nil {
+					count = float64(h.GetSampleCount())
+				}
+				got = append(got, fmt.Sprintf("%s{%s} %v", family.GetName(), strings.Join(labels, ","), count))
+			}
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%q: gathered\n%s\nwant\n%s", tt.namespace, strings.Join(got, "\n"), strings.Join(tt.want, "\n"))
+		}
+		if _, err := New(reg, tt.namespace); err == nil {
+			t.Errorf("%q: registering twice succeeded", tt.namespace)
+		}
+	}
+}
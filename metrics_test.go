@@ -0,0 +1,99 @@
+/*
+Copyright 2016 Google Inc. All Rights Reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package subcommands
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"reflect"
+	"testing"
+	"time"
+)
+
+// testMetrics is a Metrics recording what it is told.
+type testMetrics struct {
+	invocations []string
+	durations   []string
+}
+
+func (m *testMetrics) IncInvocation(cmd string, status ExitStatus) {
+	m.invocations = append(m.invocations, fmt.Sprintf("%s %v", cmd, status))
+}
+
+func (m *testMetrics) ObserveDuration(cmd string, d time.Duration) {
+	if d < 0 {
+		cmd += " (negative)"
+	}
+	m.durations = append(m.durations, cmd)
+}
+
+func TestSetMetrics(t *testing.T) {
+	tests := []struct {
+		args            []string
+		wantStatus      ExitStatus
+		wantInvocations []string
+		wantDurations   []string
+	}{
+		{[]string{"get"}, ExitSuccess, []string{"get success"}, []string{"get"}},
+		{[]string{"get", "fail"}, ExitFailure, []string{"get failure"}, []string{"get"}},
+		{[]string{"get", "-x"}, ExitUsageError, []string{"get usage error"}, nil},
+		{[]string{"get", "panic"}, ExitFailure, []string{"get failure"}, []string{"get"}},
+		{[]string{"g"}, ExitSuccess, []string{"g success"}, []string{"g"}},
+		{[]string{"nosuch"}, ExitUsageError, nil, nil},
+	}
+	for _, tt := range tests {
+		cdr, _, _ := newTestCommander()
+		get := &testCommand{name: "get", execute: func(ctx context.Context, f *flag.FlagSet) ExitStatus {
+			switch f.Arg(0) {
+			case "fail":
+				return ExitFailure
+			case "panic":
+				panic("oops")
+			}
+			return ExitSuccess
+		}}
+		cdr.Register(get, "")
+		cdr.Register(Alias("g", get), "")
+		cdr.SetCrashHandler(func(CrashInfo) {})
+		m := &testMetrics{}
+		cdr.SetMetrics(m)
+		if status := execute(t, cdr, tt.args...); status != tt.wantStatus {
+			t.Errorf("%q: status %v, want %v", tt.args, status, tt.wantStatus)
+		}
+		if !reflect.DeepEqual(m.invocations, tt.wantInvocations) || !reflect.DeepEqual(m.durations, tt.wantDurations) {
+			t.Errorf("%q: invocations %q, durations %q; want %q, %q", tt.args, m.invocations, m.durations, tt.wantInvocations, tt.wantDurations)
+		}
+	}
+}
+
+func TestSetMetricsPanic(t *testing.T) {
+	// Without a crash handler, the panic goes on, but is counted first.
+	cdr, _, _ := newTestCommander()
+	cdr.Register(&testCommand{name: "boom", execute: func(context.Context, *flag.FlagSet) ExitStatus {
+		panic("oops")
+	}}, "")
+	m := &testMetrics{}
+	cdr.SetMetrics(m)
+	func() {
+		defer func() { recover() }()
+		cdr.Run(context.Background(), "boom", nil)
+	}()
+	if want := []string{"boom failure"}; !reflect.DeepEqual(m.invocations, want) {
+		t.Errorf("invocations %q, want %q", m.invocations, want)
+	}
+}
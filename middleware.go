@@ -0,0 +1,80 @@
+/*
+Copyright 2016 Google Inc. All Rights Reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package subcommands
+
+import (
+	"context"
+	"flag"
+)
+
+// An Executor executes cmd with its parsed flags f, normally by calling
+// cmd.Execute(ctx, f, args...).
+type Executor func(ctx context.Context, cmd Command, f *flag.FlagSet, args ...interface{}) ExitStatus
+
+// A Middleware wraps an Executor with behavior common to many commands,
+// such as logging or access control. It may call next, or return an
+// ExitStatus without executing the command.
+type Middleware func(next Executor) Executor
+
+// Use adds middleware that every subcommand is executed through, after
+// its flags are parsed. The first middleware added is the outermost.
+func (cdr *Commander) Use(mw ...Middleware) {
+	cdr.middleware = append(cdr.middleware, mw...)
+}
+
+// chain returns the Executor for cmd, wrapped in the middleware of cdr
+// and, unless cmd is a mount, the persistent hooks of cdr and the
+// Commanders it is mounted in.
+func (cdr *Commander) chain(cmd Command) Executor {
+	exec := Executor(func(ctx context.Context, cmd Command, f *flag.FlagSet, args ...interface{}) ExitStatus {
+		return cmd.Execute(ctx, f, args...)
+	})
+	if mounted(cmd) == nil {
+		exec = cdr.persistentHooks(exec)
+	}
+	for i := len(cdr.middleware) - 1; i >= 0; i-- {
+		exec = cdr.middleware[i](exec)
+	}
+	return exec
+}
+
+// A wrapper is a Command executed through middleware of its own.
+type wrapper struct {
+	Command
+	middleware []Middleware
+}
+
+// Wrap returns a Command that executes cmd through mw, in addition to
+// the middleware added to the Commander by Use, for behavior only some
+// commands need, such as caching or asking for confirmation. The first
+// middleware given is the outermost, and runs inside that of the
+// Commander.
+//
+//	subcommands.Register(subcommands.Wrap(&deleteCmd{}, confirm), "")
+func Wrap(cmd Command, mw ...Middleware) Command {
+	return &wrapper{cmd, mw}
+}
+
+func (w *wrapper) Execute(ctx context.Context, f *flag.FlagSet, args ...interface{}) ExitStatus {
+	exec := Executor(func(ctx context.Context, _ Command, f *flag.FlagSet, args ...interface{}) ExitStatus {
+		return w.Command.Execute(ctx, f, args...)
+	})
+	for i := len(w.middleware) - 1; i >= 0; i-- {
+		exec = w.middleware[i](exec)
+	}
+	return exec(ctx, w.Command, f, args...)
+}
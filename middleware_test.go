@@ -0,0 +1,99 @@
+/*
+Copyright 2016 Google Inc. All Rights Reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package subcommands
+
+import (
+	"context"
+	"flag"
+	"reflect"
+	"testing"
+)
+
+func TestMiddleware(t *testing.T) {
+	tests := []struct {
+		name       string
+		cmd        string
+		stop       string // the middleware that returns without calling next
+		wantStatus ExitStatus
+		wantCalls  []string
+	}{
+		{
+			name:       "use",
+			cmd:        "plain",
+			wantStatus: ExitSuccess,
+			wantCalls:  []string{"a in plain", "b in plain", "hook", "execute plain", "b out success", "a out success"},
+		},
+		{
+			name:       "wrap",
+			cmd:        "wrapped",
+			wantStatus: ExitSuccess,
+			wantCalls:  []string{"a in wrapped", "b in wrapped", "hook", "c in wrapped", "d in wrapped", "execute wrapped", "d out success", "c out success", "b out success", "a out success"},
+		},
+		{
+			name:       "stop",
+			cmd:        "wrapped",
+			stop:       "b",
+			wantStatus: ExitCode(77),
+			wantCalls:  []string{"a in wrapped", "b in wrapped", "a out exit status 77"},
+		},
+		{
+			name:       "stop wrapped",
+			cmd:        "wrapped",
+			stop:       "c",
+			wantStatus: ExitCode(77),
+			wantCalls:  []string{"a in wrapped", "b in wrapped", "hook", "c in wrapped", "b out exit status 77", "a out exit status 77"},
+		},
+	}
+	for _, tt := range tests {
+		var calls []string
+		mw := func(name string) Middleware {
+			return func(next Executor) Executor {
+				return func(ctx context.Context, cmd Command, f *flag.FlagSet, args ...interface{}) ExitStatus {
+					calls = append(calls, name+" in "+cmd.Name())
+					if name == tt.stop {
+						return ExitCode(77)
+					}
+					status := next(ctx, cmd, f, args...)
+					calls = append(calls, name+" out "+status.String())
+					return status
+				}
+			}
+		}
+		cmd := func(name string) *testCommand {
+			return &testCommand{name: name, execute: func(context.Context, *flag.FlagSet) ExitStatus {
+				calls = append(calls, "execute "+name)
+				return ExitSuccess
+			}}
+		}
+		cdr, _, _ := newTestCommander()
+		cdr.Register(cmd("plain"), "")
+		cdr.Register(Wrap(cmd("wrapped"), mw("c"), mw("d")), "")
+		cdr.Use(mw("a"))
+		cdr.Use(mw("b"))
+		cdr.PersistentPreRun(func(ctx context.Context, cmd Command) (context.Context, error) {
+			calls = append(calls, "hook")
+			return ctx, nil
+		})
+
+		if status := execute(t, cdr, tt.cmd); status != tt.wantStatus {
+			t.Errorf("%s: status %v, want %v", tt.name, status, tt.wantStatus)
+		}
+		if !reflect.DeepEqual(calls, tt.wantCalls) {
+			t.Errorf("%s: calls\n%q\nwant\n%q", tt.name, calls, tt.wantCalls)
+		}
+	}
+}
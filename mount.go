@@ -0,0 +1,77 @@
+/*
+Copyright 2016 Google Inc. All Rights Reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package subcommands
+
+import (
+	"bytes"
+	"context"
+	"flag"
+	"fmt"
+)
+
+// A mount is a Command that runs the subcommands of another Commander.
+type mount struct {
+	name string
+	cdr  *Commander
+}
+
+func (m *mount) Name() string     { return m.name }
+func (m *mount) Synopsis() string { return fmt.Sprintf("%s subcommands", m.name) }
+func (m *mount) Usage() string {
+	var buf bytes.Buffer
+	m.cdr.Explain(&buf)
+	return buf.String()
+}
+
+// SetFlags adds the top-level flags of the mounted Commander to f, so
+// that they may be given between the mount name and the subcommand.
+func (m *mount) SetFlags(f *flag.FlagSet) {
+	m.cdr.VisitAll(func(fl *flag.Flag) {
+		f.Var(fl.Value, fl.Name, fl.Usage)
+	})
+}
+
+func (m *mount) Execute(ctx context.Context, f *flag.FlagSet, args ...interface{}) ExitStatus {
+	// The flags have already been set through the shared values;
+	// parsing again leaves the subcommand line as the remaining args.
+	if err := m.cdr.topFlags.Parse(f.Args()); err != nil {
+		return ExitUsageError
+	}
+	return m.cdr.Execute(ctx, args...)
+}
+
+// Mount registers other as a subcommand of cdr named name, in the
+// specified group, so that "<cdr> <name> <subcommand>" executes the
+// subcommand of other. This lets a library export a fully configured
+// Commander for applications to graft into their own. Help output
+// follows the mount: "help <name> <subcommand>" explains other's
+// subcommand, and other is shown under the name "<cdr> <name>" unless
+// it has a display name of its own.
+func (cdr *Commander) Mount(name string, other *Commander, group string) {
+	other.parent = cdr
+	other.mountName = name
+	cdr.Register(&mount{name: name, cdr: other}, group)
+}
+
+// mounted returns the Commander mounted as cmd, or nil if cmd is not a
+// mount.
+func mounted(cmd Command) *Commander {
+	if m, ok := dealias(cmd).(*mount); ok {
+		return m.cdr
+	}
+	return nil
+}
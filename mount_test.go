@@ -0,0 +1,96 @@
+/*
+Copyright 2016 Google Inc. All Rights Reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package subcommands
+
+import (
+	"bytes"
+	"flag"
+	"strings"
+	"testing"
+)
+
+func TestMount(t *testing.T) {
+	tests := []struct {
+		args       []string
+		wantStatus ExitStatus
+		wantRun    bool
+		wantArgs   []string
+		wantDryRun bool
+		wantStdout string
+		wantStderr string
+	}{
+		{[]string{"db", "migrate", "up"}, ExitSuccess, true, []string{"up"}, false, "", ""},
+		{[]string{"db", "-dry-run", "migrate"}, ExitSuccess, true, []string{}, true, "", ""},
+		{[]string{"db", "nosuch"}, ExitUsageError, false, nil, false, "", "tool db: unknown subcommand \"nosuch\"\n"},
+		{[]string{"db"}, ExitUsageError, false, nil, false, "", "tool db: no subcommand given\n"},
+		{[]string{"help", "db", "migrate"}, ExitSuccess, false, nil, false, "migrate:\n\tA command for tests.\n", ""},
+		{[]string{"help", "db"}, ExitSuccess, false, nil, false, "Usage: tool db <flags> <subcommand> <subcommand args>\n", ""},
+	}
+	for _, tt := range tests {
+		var stdout, stderr bytes.Buffer
+		db := NewCommander(flag.NewFlagSet("db", flag.ContinueOnError), "db")
+		dryRun := db.topFlags.Bool("dry-run", false, "print the statements only")
+		db.UsageErrors = UsageLine
+		migrate := &testCommand{name: "migrate"}
+		db.Register(migrate, "")
+
+		cdr := NewCommander(flag.NewFlagSet("tool", flag.ContinueOnError), "tool")
+		cdr.Register(cdr.HelpCommand(), "")
+		cdr.Mount("db", db, "")
+		for _, c := range []*Commander{cdr, db} {
+			c.Output, c.Error = &stdout, &stderr
+		}
+
+		if status := execute(t, cdr, tt.args...); status != tt.wantStatus {
+			t.Errorf("%q: status %v, want %v", tt.args, status, tt.wantStatus)
+		}
+		if ran := migrate.runs > 0; ran != tt.wantRun {
+			t.Errorf("%q: migrate ran %v, want %v", tt.args, ran, tt.wantRun)
+		}
+		if tt.wantRun && strings.Join(migrate.args, " ") != strings.Join(tt.wantArgs, " ") {
+			t.Errorf("%q: migrate args %q, want %q", tt.args, migrate.args, tt.wantArgs)
+		}
+		if *dryRun != tt.wantDryRun {
+			t.Errorf("%q: -dry-run %v, want %v", tt.args, *dryRun, tt.wantDryRun)
+		}
+		if !strings.HasPrefix(stdout.String(), tt.wantStdout) {
+			t.Errorf("%q: stdout %q, want it to begin %q", tt.args, stdout.String(), tt.wantStdout)
+		}
+		if got := stderr.String(); got != tt.wantStderr {
+			t.Errorf("%q: stderr %q, want %q", tt.args, got, tt.wantStderr)
+		}
+	}
+}
+
+func TestMountReset(t *testing.T) {
+	db := NewCommander(flag.NewFlagSet("db", flag.ContinueOnError), "db")
+	dryRun := db.topFlags.Bool("dry-run", false, "")
+	db.Register(&testCommand{name: "migrate"}, "")
+	cdr := NewCommander(flag.NewFlagSet("tool", flag.ContinueOnError), "tool")
+	cdr.Mount("db", db, "")
+
+	execute(t, cdr, "db", "-dry-run", "migrate")
+	if !*dryRun {
+		t.Fatal("-dry-run not set")
+	}
+	if err := cdr.Reset(); err != nil {
+		t.Fatal(err)
+	}
+	if *dryRun || db.topFlags.Parsed() {
+		t.Errorf("after Reset: -dry-run %v, parsed %v; want false, false", *dryRun, db.topFlags.Parsed())
+	}
+}
@@ -0,0 +1,266 @@
+/*
+Copyright 2016 Google Inc. All Rights Reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package output gives subcommands a consistent way to emit results as
+// JSON, YAML or a table, selected by an -output flag on the Commander.
+//
+// A program calls Register before parsing its top-level flags, and each
+// command calls Emit with the value it produced:
+//
+//	output.Register(subcommands.DefaultCommander)
+//	...
+//	func (c *listCmd) Execute(ctx context.Context, f *flag.FlagSet, _ ...interface{}) subcommands.ExitStatus {
+//		if err := output.Emit(ctx, c.list()); err != nil {
+//			...
+//		}
+//		return subcommands.ExitSuccess
+//	}
+package output
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/google/subcommands"
+	"github.com/google/subcommands/table"
+)
+
+// A Format is a way of rendering values.
+type Format string
+
+const (
+	JSON  Format = "json"  // Indented JSON.
+	YAML  Format = "yaml"  // Block-style YAML.
+	Table Format = "table" // Aligned columns, one row per element.
+)
+
+// Formats lists the supported formats.
+var Formats = []Format{JSON, YAML, Table}
+
+// String implements flag.Value.
+func (f *Format) String() string { return string(*f) }
+
+// Choices implements subcommands.Chooser, so that the formats are listed
+// in help and offered by shell completion.
+func (f *Format) Choices() []string {
+	choices := make([]string, len(Formats))
+	for i, format := range Formats {
+		choices[i] = string(format)
+	}
+	return choices
+}
+
+// Set implements flag.Value, accepting only the supported formats.
+func (f *Format) Set(s string) error {
+	for _, format := range Formats {
+		if Format(s) == format {
+			*f = format
+			return nil
+		}
+	}
+	return fmt.Errorf("unknown format %q (want json, yaml or table)", s)
+}
+
+// Reset sets f to Table, the default of the -output flag, for
+// subcommands.Commander.Reset.
+func (f *Format) Reset() { *f = Table }
+
+// Register defines an -output flag on the top-level flags of cdr, which
+// selects the format Emit uses for the commands of cdr. It must be
+// called before the top-level flags are parsed.
+func Register(cdr *subcommands.Commander) {
+	format := Table
+	cdr.TopFlags().Var(&format, "output", "output `format`")
+}
+
+// commander returns the Commander executing the command ctx was passed
+// to, or else the DefaultCommander.
+func commander(ctx context.Context) *subcommands.Commander {
+	if cdr := subcommands.CommanderFromContext(ctx); cdr != nil {
+		return cdr
+	}
+	return subcommands.DefaultCommander
+}
+
+// flagFormat returns the format selected by the -output flag defined by
+// Register on cdr, and whether the flag was set, from the command line,
+// the environment or a config file. Without the flag, it returns Table.
+func flagFormat(cdr *subcommands.Commander) (format Format, set bool) {
+	fl := cdr.TopFlags().Lookup("output")
+	if fl == nil {
+		return Table, false
+	}
+	p, ok := fl.Value.(*Format)
+	if !ok {
+		return Table, false
+	}
+	return *p, subcommands.Provenance(cdr.TopFlags(), "output") != subcommands.SourceDefault
+}
+
+type contextKey int
+
+const (
+	formatKey contextKey = iota
+	writerKey
+)
+
+// WithFormat returns a copy of ctx in which Emit uses format instead of
+// the one selected by the -output flag.
+func WithFormat(ctx context.Context, format Format) context.Context {
+	return context.WithValue(ctx, formatKey, format)
+}
+
+// WithWriter returns a copy of ctx in which Emit writes to w.
+func WithWriter(ctx context.Context, w io.Writer) context.Context {
+	return context.WithValue(ctx, writerKey, w)
+}
+
+// FormatFrom returns the format Emit uses with ctx: the one set by
+// WithFormat, or else the one selected by the -output flag of the
+// Commander executing the command ctx was passed to, or else of the
+// DefaultCommander, or else Table.
+func FormatFrom(ctx context.Context) Format {
+	if format, ok := ctx.Value(formatKey).(Format); ok {
+		return format
+	}
+	format, _ := flagFormat(commander(ctx))
+	return format
+}
+
+// Writer returns the writer Emit uses with ctx: the one set by
+// WithWriter, or else the Output of the Commander executing the command
+// ctx was passed to, or else of the DefaultCommander.
+func Writer(ctx context.Context) io.Writer {
+	if w, ok := ctx.Value(writerKey).(io.Writer); ok {
+		return w
+	}
+	return commander(ctx).Output
+}
+
+// Human reports whether output for ctx is read by a person: whether
+// Writer(ctx) is a terminal and no format has been explicitly chosen by
+// WithFormat or the -output flag. Commands may then emit colored or
+// decorated text rather than plain, parseable text.
+func Human(ctx context.Context) bool {
+	if _, ok := ctx.Value(formatKey).(Format); ok {
+		return false
+	}
+	if _, set := flagFormat(commander(ctx)); set {
+		return false
+	}
+	return subcommands.IsTerminal(Writer(ctx))
+}
+
+// Machine reports whether output for ctx is read by another program; it
+// is the opposite of Human.
+func Machine(ctx context.Context) bool {
+	return !Human(ctx)
+}
+
+// Emit renders v in the format selected for ctx. v may be anything
+// encoding/json can marshal; as a table, a slice becomes one row per
+// element, and any other value a single row.
+func Emit(ctx context.Context, v interface{}) error {
+	w := Writer(ctx)
+	switch format := FormatFrom(ctx); format {
+	case JSON:
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(v)
+	case YAML:
+		data, err := decode(v)
+		if err != nil {
+			return err
+		}
+		var buf bytes.Buffer
+		writeYAML(&buf, data, 0)
+		_, err = w.Write(buf.Bytes())
+		return err
+	case Table:
+		data, err := decode(v)
+		if err != nil {
+			return err
+		}
+		return writeTable(w, data)
+	default:
+		return fmt.Errorf("output: unknown format %q", format)
+	}
+}
+
+// writeTable writes data as a table with a header row naming the keys.
+func writeTable(w io.Writer, data interface{}) error {
+	rows, ok := data.([]interface{})
+	if !ok {
+		rows = []interface{}{data}
+	}
+
+	var columns []string
+	seen := make(map[string]bool)
+	for _, row := range rows {
+		if obj, ok := row.(object); ok {
+			for _, m := range obj {
+				if !seen[m.key] {
+					seen[m.key] = true
+					columns = append(columns, m.key)
+				}
+			}
+		}
+	}
+
+	if len(columns) == 0 {
+		t := table.New()
+		for _, row := range rows {
+			t.Append(cell(row))
+		}
+		return t.Render(w)
+	}
+	header := make([]string, len(columns))
+	for i, column := range columns {
+		header[i] = strings.ToUpper(column)
+	}
+	t := table.New(header...)
+	for _, row := range rows {
+		obj, _ := row.(object)
+		cells := make([]string, len(columns))
+		for i, column := range columns {
+			if value, ok := obj.get(column); ok {
+				cells[i] = cell(value)
+			}
+		}
+		t.Append(cells...)
+	}
+	return t.Render(w)
+}
+
+// cell renders value as the text of a table cell.
+func cell(value interface{}) string {
+	switch value := value.(type) {
+	case nil:
+		return ""
+	case string:
+		return value
+	case json.Number:
+		return value.String()
+	case bool:
+		return fmt.Sprint(value)
+	}
+	data, _ := json.Marshal(encodable(value))
+	return string(data)
+}
@@ -0,0 +1,166 @@
+/*
+Copyright 2016 Google Inc. All Rights Reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package output
+
+import (
+	"bytes"
+	"context"
+	"flag"
+	"testing"
+
+	"github.com/google/subcommands"
+)
+
+type item struct {
+	Name  string   `json:"name"`
+	Count int      `json:"count"`
+	Tags  []string `json:"tags"`
+}
+
+func TestEmit(t *testing.T) {
+	items := []item{{Name: "a", Count: 1, Tags: []string{"x"}}, {Name: "b c", Count: 2, Tags: []string{}}}
+	tests := []struct {
+		format Format
+		v      interface{}
+		want   string
+	}{
+		{JSON, items[0], "{\n  \"name\": \"a\",\n  \"count\": 1,\n  \"tags\": [\n    \"x\"\n  ]\n}\n"},
+		{YAML, items, "- name: a\n  count: 1\n  tags:\n    - x\n- name: b c\n  count: 2\n  tags: []\n"},
+		{YAML, item{Name: "true"}, "name: \"true\"\ncount: 0\ntags: null\n"},
+		{YAML, map[string]interface{}{"attrs": map[string]string{}, "tags": []string{}}, "attrs: {}\ntags: []\n"},
+		{Table, items, "NAME  COUNT  TAGS\na     1      [\"x\"]\nb c   2      []\n"},
+		{Table, []string{"x", "y"}, "x\ny\n"},
+	}
+	for _, tt := range tests {
+		var buf bytes.Buffer
+		ctx := WithWriter(WithFormat(context.Background(), tt.format), &buf)
+		if err := Emit(ctx, tt.v); err != nil {
+			t.Errorf("Emit(%s, %+v): %v", tt.format, tt.v, err)
+			continue
+		}
+		if got := buf.String(); got != tt.want {
+			t.Errorf("Emit(%s, %+v) wrote\n%q\nwant\n%q", tt.format, tt.v, got, tt.want)
+		}
+	}
+}
+
+func TestFormatSet(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    Format
+		wantErr bool
+	}{
+		{"json", JSON, false},
+		{"yaml", YAML, false},
+		{"table", Table, false},
+		{"xml", Table, true},
+		{"", Table, true},
+	}
+	for _, tt := range tests {
+		f := Table
+		err := f.Set(tt.in)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("Set(%q): error %v, want error %v", tt.in, err, tt.wantErr)
+		}
+		if f != tt.want {
+			t.Errorf("Set(%q): format %q, want %q", tt.in, f, tt.want)
+		}
+	}
+}
+
+// formatCmd is a Command recording the format Emit would use, and
+// whether it was chosen explicitly.
+type formatCmd struct {
+	format Format
+	set    bool
+}
+
+func (*formatCmd) Name() string           { return "show" }
+func (*formatCmd) Synopsis() string       { return "" }
+func (*formatCmd) Usage() string          { return "" }
+func (*formatCmd) SetFlags(*flag.FlagSet) {}
+func (c *formatCmd) Execute(ctx context.Context, _ *flag.FlagSet, _ ...interface{}) subcommands.ExitStatus {
+	c.format = FormatFrom(ctx)
+	_, c.set = flagFormat(subcommands.CommanderFromContext(ctx))
+	return subcommands.ExitSuccess
+}
+
+func TestRegisterPerCommander(t *testing.T) {
+	tests := []struct {
+		args       []string
+		wantFormat Format
+		wantSet    bool
+	}{
+		{nil, Table, false},
+		{[]string{"-output=json"}, JSON, true},
+		{[]string{"-output=table"}, Table, true},
+	}
+	// Each Commander keeps its own -output flag, so they are all set up
+	// before any runs.
+	cdrs := make([]*subcommands.Commander, len(tests))
+	cmds := make([]*formatCmd, len(tests))
+	for i, tt := range tests {
+		cdrs[i] = subcommands.NewCommander(flag.NewFlagSet("tool", flag.ContinueOnError), "tool")
+		Register(cdrs[i])
+		cmds[i] = &formatCmd{}
+		cdrs[i].Register(cmds[i], "")
+		if err := cdrs[i].TopFlags().Parse(append(tt.args, "show")); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for i, tt := range tests {
+		if status := cdrs[i].Execute(context.Background()); status != subcommands.ExitSuccess {
+			t.Fatalf("%q: status %v", tt.args, status)
+		}
+		if cmds[i].format != tt.wantFormat || cmds[i].set != tt.wantSet {
+			t.Errorf("%q: format %q, set %v; want %q, %v", tt.args, cmds[i].format, cmds[i].set, tt.wantFormat, tt.wantSet)
+		}
+	}
+
+	// After Reset, the format is no longer explicitly chosen.
+	cdr, cmd := cdrs[1], cmds[1]
+	if err := cdr.Reset(); err != nil {
+		t.Fatal(err)
+	}
+	if err := cdr.TopFlags().Parse([]string{"show"}); err != nil {
+		t.Fatal(err)
+	}
+	cdr.Execute(context.Background())
+	if cmd.format != Table || cmd.set {
+		t.Errorf("after Reset: format %q, set %v; want %q, false", cmd.format, cmd.set, Table)
+	}
+}
+
+func TestMachine(t *testing.T) {
+	// Without a terminal, output is always for another program; only the
+	// writer Emit uses matters, not the process's own standard output.
+	tests := []struct {
+		name string
+		ctx  func(context.Context) context.Context
+	}{
+		{"default", func(ctx context.Context) context.Context { return ctx }},
+		{"format", func(ctx context.Context) context.Context { return WithFormat(ctx, Table) }},
+		{"writer", func(ctx context.Context) context.Context { return WithWriter(ctx, new(bytes.Buffer)) }},
+	}
+	for _, tt := range tests {
+		ctx := WithWriter(context.Background(), new(bytes.Buffer))
+		ctx = tt.ctx(ctx)
+		if Human(ctx) || !Machine(ctx) {
+			t.Errorf("%s: Human %v, Machine %v; want false, true", tt.name, Human(ctx), Machine(ctx))
+		}
+	}
+}
@@ -0,0 +1,206 @@
+/*
+Copyright 2016 Google Inc. All Rights Reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package output
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"regexp"
+	"strings"
+)
+
+// A member is a member of a JSON object.
+type member struct {
+	key   string
+	value interface{}
+}
+
+// An object is a JSON object whose members keep their order.
+type object []member
+
+// get returns the value of the member with the given key.
+func (o object) get(key string) (interface{}, bool) {
+	for _, m := range o {
+		if m.key == key {
+			return m.value, true
+		}
+	}
+	return nil, false
+}
+
+// decode converts v to its JSON data model, keeping the order of object
+// members: objects become objects, arrays []interface{}, and numbers
+// json.Numbers.
+func decode(v interface{}) (interface{}, error) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	return decodeValue(dec)
+}
+
+func decodeValue(dec *json.Decoder) (interface{}, error) {
+	tok, err := dec.Token()
+	if err != nil {
+		return nil, err
+	}
+	switch tok {
+	case json.Delim('{'):
+		obj := object{}
+		for dec.More() {
+			key, err := dec.Token()
+			if err != nil {
+				return nil, err
+			}
+			value, err := decodeValue(dec)
+			if err != nil {
+				return nil, err
+			}
+			obj = append(obj, member{key.(string), value})
+		}
+		_, err := dec.Token()
+		return obj, err
+	case json.Delim('['):
+		arr := []interface{}{}
+		for dec.More() {
+			value, err := decodeValue(dec)
+			if err != nil {
+				return nil, err
+			}
+			arr = append(arr, value)
+		}
+		_, err := dec.Token()
+		return arr, err
+	}
+	return tok, nil
+}
+
+// encodable converts decoded data back into values encoding/json can
+// marshal in the same order.
+func encodable(data interface{}) interface{} {
+	switch data := data.(type) {
+	case object:
+		var buf bytes.Buffer
+		buf.WriteByte('{')
+		for i, m := range data {
+			if i > 0 {
+				buf.WriteByte(',')
+			}
+			key, _ := json.Marshal(m.key)
+			value, _ := json.Marshal(encodable(m.value))
+			buf.Write(key)
+			buf.WriteByte(':')
+			buf.Write(value)
+		}
+		buf.WriteByte('}')
+		return json.RawMessage(buf.Bytes())
+	case []interface{}:
+		arr := make([]interface{}, len(data))
+		for i, value := range data {
+			arr[i] = encodable(value)
+		}
+		return arr
+	}
+	return data
+}
+
+// plain matches strings that YAML reads as strings without quoting.
+var plain = regexp.MustCompile(`^[A-Za-z_/][A-Za-z0-9_./ -]*[A-Za-z0-9_./]$|^[A-Za-z_/]$`)
+
+// yamlString renders s as a YAML scalar.
+func yamlString(s string) string {
+	switch strings.ToLower(s) {
+	case "true", "false", "yes", "no", "on", "off", "null", "~", "y", "n":
+		return fmt.Sprintf("%q", s)
+	}
+	if plain.MatchString(s) {
+		return s
+	}
+	// JSON string syntax is valid YAML double-quoted string syntax.
+	data, _ := json.Marshal(s)
+	return string(data)
+}
+
+// yamlScalar renders a decoded scalar, or an empty collection, as YAML.
+func yamlScalar(value interface{}) string {
+	switch value := value.(type) {
+	case nil:
+		return "null"
+	case string:
+		return yamlString(value)
+	case object:
+		return "{}"
+	case []interface{}:
+		return "[]"
+	}
+	return fmt.Sprint(value)
+}
+
+// writeYAML writes data as a block-style YAML document, indented by
+// indent levels.
+func writeYAML(w io.Writer, data interface{}, indent int) {
+	pad := strings.Repeat("  ", indent)
+	switch data := data.(type) {
+	case object:
+		if len(data) == 0 {
+			fmt.Fprintf(w, "%s{}\n", pad)
+			return
+		}
+		for _, m := range data {
+			if isCollection(m.value) {
+				fmt.Fprintf(w, "%s%s:\n", pad, yamlString(m.key))
+				writeYAML(w, m.value, indent+1)
+			} else {
+				fmt.Fprintf(w, "%s%s: %s\n", pad, yamlString(m.key), yamlScalar(m.value))
+			}
+		}
+	case []interface{}:
+		if len(data) == 0 {
+			fmt.Fprintf(w, "%s[]\n", pad)
+			return
+		}
+		for _, value := range data {
+			if !isCollection(value) {
+				fmt.Fprintf(w, "%s- %s\n", pad, yamlScalar(value))
+				continue
+			}
+			// Render the collection one level in, then replace its
+			// leading indentation with the sequence marker.
+			var buf bytes.Buffer
+			writeYAML(&buf, value, indent+1)
+			item := strings.TrimPrefix(buf.String(), pad+"  ")
+			fmt.Fprintf(w, "%s- %s", pad, item)
+		}
+	default:
+		fmt.Fprintf(w, "%s%s\n", pad, yamlScalar(data))
+	}
+}
+
+// isCollection reports whether value is a non-empty object or array.
+func isCollection(value interface{}) bool {
+	switch value := value.(type) {
+	case object:
+		return len(value) > 0
+	case []interface{}:
+		return len(value) > 0
+	}
+	return false
+}
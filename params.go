@@ -0,0 +1,85 @@
+/*
+Copyright 2016 Google Inc. All Rights Reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package subcommands
+
+// Params holds values passed by name through Execute to a command, in
+// place of the values passed by position in args, which each command
+// must know the order and types of. A program passes a *Params to
+// Execute:
+//
+//	p := subcommands.NewParams()
+//	subcommands.SetParam(p, "db", db)
+//	os.Exit(int(subcommands.Execute(ctx, p)))
+//
+// and commands find it with ParamsFrom:
+//
+//	func (c *listCmd) Execute(ctx context.Context, f *flag.FlagSet, args ...interface{}) subcommands.ExitStatus {
+//		db, ok := subcommands.GetParam[*sql.DB](subcommands.ParamsFrom(args...), "db")
+//		...
+//	}
+//
+// Params is not safe for concurrent use while it is modified.
+type Params struct {
+	values map[string]interface{}
+	args   []interface{}
+}
+
+// NewParams returns an empty Params.
+func NewParams() *Params {
+	return &Params{values: make(map[string]interface{})}
+}
+
+// SetParam sets the value of key in p.
+func SetParam[T any](p *Params, key string, value T) {
+	p.values[key] = value
+}
+
+// GetParam returns the value of key in p, and whether there is one of
+// type T. It returns the zero T if p is nil.
+func GetParam[T any](p *Params, key string) (T, bool) {
+	var zero T
+	if p == nil {
+		return zero, false
+	}
+	v, ok := p.values[key].(T)
+	if !ok {
+		return zero, false
+	}
+	return v, true
+}
+
+// ParamsFrom returns the *Params among args, the values passed to
+// Execute, for commands that take Params. If there is none, as when a
+// program passes its values by position, ParamsFrom returns an empty
+// Params holding args, so that commands can move to Params before the
+// programs calling them do.
+func ParamsFrom(args ...interface{}) *Params {
+	for _, arg := range args {
+		if p, ok := arg.(*Params); ok {
+			return p
+		}
+	}
+	p := NewParams()
+	p.args = args
+	return p
+}
+
+// Args returns the values passed to Execute by position, if p was made
+// from them by ParamsFrom.
+func (p *Params) Args() []interface{} {
+	return p.args
+}
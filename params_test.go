@@ -0,0 +1,78 @@
+/*
+Copyright 2016 Google Inc. All Rights Reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package subcommands
+
+import (
+	"context"
+	"reflect"
+	"testing"
+)
+
+func TestParams(t *testing.T) {
+	p := NewParams()
+	SetParam(p, "name", "db1")
+	SetParam(p, "port", 5432)
+	tests := []struct {
+		key    string
+		get    func(*Params, string) (interface{}, bool)
+		want   interface{}
+		wantOK bool
+	}{
+		{"name", func(p *Params, k string) (interface{}, bool) { return GetParam[string](p, k) }, "db1", true},
+		{"port", func(p *Params, k string) (interface{}, bool) { return GetParam[int](p, k) }, 5432, true},
+		{"port", func(p *Params, k string) (interface{}, bool) { return GetParam[string](p, k) }, "", false},
+		{"nosuch", func(p *Params, k string) (interface{}, bool) { return GetParam[int](p, k) }, 0, false},
+	}
+	for _, tt := range tests {
+		got, ok := tt.get(p, tt.key)
+		if got != tt.want || ok != tt.wantOK {
+			t.Errorf("GetParam(%q) = %v, %v; want %v, %v", tt.key, got, ok, tt.want, tt.wantOK)
+		}
+	}
+	if got, ok := GetParam[int](nil, "port"); got != 0 || ok {
+		t.Errorf("GetParam(nil) = %v, %v; want 0, false", got, ok)
+	}
+}
+
+func TestParamsFrom(t *testing.T) {
+	p := NewParams()
+	SetParam(p, "db", "db1")
+	tests := []struct {
+		name     string
+		args     []interface{}
+		wantDB   string
+		wantArgs []interface{}
+	}{
+		{"params", []interface{}{p}, "db1", nil},
+		{"params among others", []interface{}{1, p}, "db1", nil},
+		{"by position", []interface{}{"db1", 2}, "", []interface{}{"db1", 2}},
+		{"none", nil, "", nil},
+	}
+	for _, tt := range tests {
+		cdr, _, _ := newTestCommander()
+		show := &extrasCommand{testCommand: testCommand{name: "show"}}
+		cdr.Register(show, "")
+		cdr.Run(context.Background(), "show", nil, tt.args...)
+		got := ParamsFrom(show.extras...)
+		if db, _ := GetParam[string](got, "db"); db != tt.wantDB {
+			t.Errorf("%s: db %q, want %q", tt.name, db, tt.wantDB)
+		}
+		if !reflect.DeepEqual(got.Args(), tt.wantArgs) {
+			t.Errorf("%s: Args() = %v, want %v", tt.name, got.Args(), tt.wantArgs)
+		}
+	}
+}
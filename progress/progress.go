@@ -0,0 +1,158 @@
+/*
+Copyright 2016 Google Inc. All Rights Reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package progress shows the activity of long-running subcommands on a
+// status line. On a terminal the line is redrawn in place with a
+// spinner; otherwise each new status is printed on a line of its own,
+// and nothing is printed at all in quiet mode.
+//
+//	s := progress.ForCommander(cdr)
+//	s.Start("fetching %d objects", n)
+//	for i := range objects {
+//		s.Update("fetching object %d of %d", i+1, n)
+//		...
+//	}
+//	s.Stop("fetched %d objects", n)
+package progress
+
+import (
+	"fmt"
+	"io"
+	"sync"
+	"time"
+
+	"github.com/google/subcommands"
+)
+
+// spinner holds the frames of the spinner shown on terminals.
+var spinner = []string{"|", "/", "-", "\\"}
+
+// A Status is a status line written to a terminal or log.
+type Status struct {
+	Quiet    bool          // Quiet suppresses all output.
+	Interval time.Duration // Interval is the time between spinner frames (default: 100ms).
+
+	w    io.Writer
+	tty  bool
+	mu   sync.Mutex
+	msg  string
+	tick int
+	stop chan struct{}
+	done chan struct{}
+}
+
+// New returns a Status that writes to w, drawing a spinner if w is a
+// terminal.
+func New(w io.Writer) *Status {
+	return &Status{
+		Interval: 100 * time.Millisecond,
+		w:        w,
+		tty:      subcommands.IsTerminal(w),
+	}
+}
+
+// ForCommander returns a Status that writes to cdr.Error, in quiet mode
+// if cdr is silent.
+func ForCommander(cdr *subcommands.Commander) *Status {
+	s := New(cdr.Error)
+	s.Quiet = cdr.Silent()
+	return s
+}
+
+// Start shows the status formatted according to format and args and,
+// on a terminal, starts the spinner.
+func (s *Status) Start(format string, args ...interface{}) {
+	s.Update(format, args...)
+	if s.Quiet || !s.tty {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.stop != nil {
+		return
+	}
+	s.stop, s.done = make(chan struct{}), make(chan struct{})
+	go s.spin(s.stop, s.done)
+}
+
+// spin redraws the status line every s.Interval until stop is closed.
+func (s *Status) spin(stop, done chan struct{}) {
+	defer close(done)
+	ticker := time.NewTicker(s.Interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+			s.mu.Lock()
+			s.tick++
+			s.draw()
+			s.mu.Unlock()
+		}
+	}
+}
+
+// Update replaces the status with one formatted according to format
+// and args.
+func (s *Status) Update(format string, args ...interface{}) {
+	if s.Quiet {
+		return
+	}
+	msg := fmt.Sprintf(format, args...)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if msg == s.msg {
+		return
+	}
+	s.msg = msg
+	if s.tty {
+		s.draw()
+	} else {
+		fmt.Fprintln(s.w, msg)
+	}
+}
+
+// draw redraws the status line on a terminal. s.mu must be held.
+func (s *Status) draw() {
+	fmt.Fprintf(s.w, "\r\033[K%s %s", spinner[s.tick%len(spinner)], s.msg)
+}
+
+// Stop stops the spinner, clears the status line and, if format is not
+// empty, prints a final message formatted according to format and args.
+func (s *Status) Stop(format string, args ...interface{}) {
+	if s.Quiet {
+		return
+	}
+	s.mu.Lock()
+	stop, done := s.stop, s.done
+	s.stop, s.done = nil, nil
+	s.mu.Unlock()
+	if stop != nil {
+		close(stop)
+		<-done
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.tty && s.msg != "" {
+		fmt.Fprint(s.w, "\r\033[K")
+	}
+	s.msg = ""
+	if format != "" {
+		fmt.Fprintf(s.w, format+"\n", args...)
+	}
+}
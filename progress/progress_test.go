@@ -0,0 +1,86 @@
+/*
+Copyright 2016 Google Inc. All Rights Reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package progress
+
+import (
+	"bytes"
+	"flag"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/subcommands"
+)
+
+func TestStatus(t *testing.T) {
+	tests := []struct {
+		name  string
+		quiet bool
+		want  string
+	}{
+		{"log", false, "fetching 2 objects\nfetching object 1 of 2\nfetching object 2 of 2\nfetched 2 objects\n"},
+		{"quiet", true, ""},
+	}
+	for _, tt := range tests {
+		var buf bytes.Buffer
+		s := New(&buf)
+		s.Quiet = tt.quiet
+		s.Start("fetching %d objects", 2)
+		for i := 1; i <= 2; i++ {
+			s.Update("fetching object %d of %d", i, 2)
+			s.Update("fetching object %d of %d", i, 2) // unchanged, not repeated
+		}
+		s.Stop("fetched %d objects", 2)
+		if got := buf.String(); got != tt.want {
+			t.Errorf("%s: wrote %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestStatusTerminal(t *testing.T) {
+	var buf bytes.Buffer
+	s := New(&buf)
+	s.tty = true
+	s.Interval = time.Millisecond
+	s.Start("working")
+	time.Sleep(10 * time.Millisecond)
+	s.Stop("")
+	s.Stop("done") // stopping again only prints the message
+
+	got := buf.String()
+	if !strings.HasPrefix(got, "\r\033[K| working\r\033[K/ working") {
+		t.Errorf("wrote %q, want the spinner to turn", got)
+	}
+	if !strings.HasSuffix(got, " working\r\033[Kdone\n") {
+		t.Errorf("wrote %q, want the line cleared before the final message", got)
+	}
+}
+
+func TestForCommander(t *testing.T) {
+	for _, silent := range []bool{false, true} {
+		var buf bytes.Buffer
+		cdr := subcommands.NewCommander(flag.NewFlagSet("tool", flag.ContinueOnError), "tool")
+		cdr.Error = &buf
+		cdr.SetSilent(silent)
+		s := ForCommander(cdr)
+		s.Start("working")
+		s.Stop("")
+		if got := buf.Len() == 0; got != silent {
+			t.Errorf("silent %v: wrote %q", silent, buf.String())
+		}
+	}
+}
@@ -0,0 +1,173 @@
+/*
+Copyright 2016 Google Inc. All Rights Reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package subcommands
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"sync"
+)
+
+// A Source is where the value of a flag came from.
+type Source int
+
+const (
+	SourceDefault     Source = iota // The flag was not set.
+	SourceCommandLine               // The flag was set on the command line.
+	SourceEnv                       // The flag was set from an environment variable bound by BindEnv.
+	SourceConfig                    // The flag was set from the file named by the flag defined by ConfigFlag.
+)
+
+func (s Source) String() string {
+	switch s {
+	case SourceDefault:
+		return "default"
+	case SourceCommandLine:
+		return "command line"
+	case SourceEnv:
+		return "environment"
+	case SourceConfig:
+		return "config file"
+	}
+	return "unknown"
+}
+
+// provenance records the sources of the flags set by Commanders.
+var provenance = struct {
+	sync.Mutex
+	m map[*flag.FlagSet]map[string]Source
+}{m: make(map[*flag.FlagSet]map[string]Source)}
+
+// Provenance returns where the value of the named flag in f came from.
+// It reports on the top-level flags of a Commander, and on the flags a
+// Commander passes to a command's Execute method while it runs.
+func Provenance(f *flag.FlagSet, name string) Source {
+	provenance.Lock()
+	defer provenance.Unlock()
+	return provenance.m[f][name]
+}
+
+// record records that the named flag in f was set from src.
+func record(f *flag.FlagSet, name string, src Source) {
+	provenance.Lock()
+	defer provenance.Unlock()
+	if provenance.m[f] == nil {
+		provenance.m[f] = make(map[string]Source)
+	}
+	provenance.m[f][name] = src
+}
+
+// forget discards the sources recorded for the flags in f, which of them
+// are secret, how their values are completed and validated, and how
+// their defaults are computed and shown.
+func forget(f *flag.FlagSet) {
+	provenance.Lock()
+	defer provenance.Unlock()
+	delete(provenance.m, f)
+	secrets.Lock()
+	defer secrets.Unlock()
+	delete(secrets.m, f)
+	valueCompletions.Lock()
+	defer valueCompletions.Unlock()
+	delete(valueCompletions.m, f)
+	validators.Lock()
+	defer validators.Unlock()
+	delete(validators.m, f)
+	lazyDefaults.Lock()
+	defer lazyDefaults.Unlock()
+	delete(lazyDefaults.m, f)
+	defaultTexts.Lock()
+	defer defaultTexts.Unlock()
+	delete(defaultTexts.m, f)
+}
+
+// A recordingValue is a flag.Value that notes when it is set.
+type recordingValue struct {
+	flag.Value
+	set    bool
+	secret *[]string // if not nil, values rejected by Set are added to it
+}
+
+func (v *recordingValue) Set(s string) error {
+	v.set = true
+	err := v.Value.Set(s)
+	if err != nil && v.secret != nil {
+		*v.secret = append(*v.secret, s)
+	}
+	return err
+}
+
+func (v *recordingValue) IsBoolFlag() bool {
+	b, ok := v.Value.(interface{ IsBoolFlag() bool })
+	return ok && b.IsBoolFlag()
+}
+
+// parse parses args into f with fn, or f.Parse if fn is nil, recording
+// the flags it sets as set from the command line. Invalid values of
+// secret flags are redacted from the error and the messages printed.
+func parse(f *flag.FlagSet, args []string, fn func(*flag.FlagSet, []string) error) error {
+	if fn == nil {
+		fn = (*flag.FlagSet).Parse
+	}
+	var rejected []string
+	values := make(map[*flag.Flag]*recordingValue)
+	f.VisitAll(func(fl *flag.Flag) {
+		v := &recordingValue{Value: fl.Value}
+		if IsSecret(f, fl.Name) {
+			v.secret = &rejected
+		}
+		values[fl] = v
+	})
+	wrap := func(recording bool) {
+		for fl, v := range values {
+			if recording {
+				fl.Value = v
+			} else {
+				fl.Value = v.Value
+			}
+		}
+	}
+	// The usage printed on errors shows the flags' own values.
+	usage := f.Usage
+	f.Usage = func() {
+		wrap(false)
+		defer wrap(true)
+		if usage != nil {
+			usage()
+			return
+		}
+		fmt.Fprintf(f.Output(), "Usage of %s:\n", f.Name())
+		f.PrintDefaults()
+	}
+	out := f.Output()
+	f.SetOutput(&redactingWriter{out, &rejected})
+	wrap(true)
+	err := fn(f, args)
+	wrap(false)
+	f.SetOutput(out)
+	f.Usage = usage
+	for fl, v := range values {
+		if v.set {
+			record(f, fl.Name, SourceCommandLine)
+		}
+	}
+	if err != nil && len(rejected) > 0 {
+		err = errors.New(redactValues(err.Error(), rejected))
+	}
+	return err
+}
@@ -0,0 +1,158 @@
+/*
+Copyright 2016 Google Inc. All Rights Reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package subcommands
+
+import (
+	"context"
+	"flag"
+	"io"
+	"strings"
+	"testing"
+)
+
+func TestProvenance(t *testing.T) {
+	config := `{"c": "config", "both": "config", "print": {"pc": "config", "pboth": "config"}}`
+	tests := []struct {
+		args []string
+		env  map[string]string
+		want map[string]Source // of the top-level flags, and the flags of print starting with p
+	}{
+		{
+			args: []string{"print"},
+			want: map[string]Source{"c": SourceConfig, "e": SourceDefault, "both": SourceConfig, "pc": SourceConfig, "pe": SourceDefault, "pboth": SourceConfig},
+		},
+		{
+			args: []string{"print"},
+			env:  map[string]string{"TOOL_E": "env", "TOOL_BOTH": "env", "TOOL_PRINT_PE": "env", "TOOL_PRINT_PBOTH": "env"},
+			want: map[string]Source{"c": SourceConfig, "e": SourceEnv, "both": SourceEnv, "pc": SourceConfig, "pe": SourceEnv, "pboth": SourceEnv},
+		},
+		{
+			args: []string{"-both=x", "-e=", "print", "-pboth=x", "-pe", "x"},
+			env:  map[string]string{"TOOL_E": "env", "TOOL_BOTH": "env", "TOOL_PRINT_PE": "env", "TOOL_PRINT_PBOTH": "env"},
+			want: map[string]Source{"c": SourceConfig, "e": SourceCommandLine, "both": SourceCommandLine, "pc": SourceConfig, "pe": SourceCommandLine, "pboth": SourceCommandLine},
+		},
+	}
+	for _, tt := range tests {
+		for k, v := range tt.env {
+			t.Setenv(k, v)
+		}
+		path := writeFile(t, "config.json", config)
+		cdr := NewCommander(flag.NewFlagSet("tool", flag.ContinueOnError), "tool")
+		cdr.Output, cdr.Error = io.Discard, io.Discard
+		cdr.ConfigFlag("config")
+		cdr.BindEnv()
+		for _, name := range []string{"c", "e", "both"} {
+			cdr.topFlags.String(name, "", "")
+		}
+		got := make(map[string]Source)
+		var flags *flag.FlagSet
+		cdr.Register(&testCommand{
+			name: "print",
+			flags: func(f *flag.FlagSet) {
+				for _, name := range []string{"pc", "pe", "pboth"} {
+					f.String(name, "", "")
+				}
+			},
+			execute: func(_ context.Context, f *flag.FlagSet) ExitStatus {
+				flags = f
+				for _, name := range []string{"pc", "pe", "pboth"} {
+					got[name] = Provenance(f, name)
+				}
+				return ExitSuccess
+			},
+		}, "")
+
+		if status := execute(t, cdr, append([]string{"-config", path}, tt.args...)...); status != ExitSuccess {
+			t.Fatalf("%q: status %v", tt.args, status)
+		}
+		for _, name := range []string{"c", "e", "both"} {
+			got[name] = Provenance(cdr.topFlags, name)
+		}
+		for name, want := range tt.want {
+			if got[name] != want {
+				t.Errorf("%q, env %v: -%s from %v, want %v", tt.args, tt.env, name, got[name], want)
+			}
+		}
+		if src := Provenance(cdr.topFlags, "config"); src != SourceCommandLine {
+			t.Errorf("-config from %v, want %v", src, SourceCommandLine)
+		}
+		if src := Provenance(flags, "pc"); src != SourceDefault {
+			t.Errorf("after Execute, -pc of print from %v, want it forgotten", src)
+		}
+	}
+}
+
+func TestProvenanceFlagParser(t *testing.T) {
+	cdr := NewCommander(flag.NewFlagSet("tool", flag.ContinueOnError), "tool")
+	cdr.SetFlagParser(func(f *flag.FlagSet, args []string) error {
+		if err := f.Set("n", "3"); err != nil {
+			return err
+		}
+		return f.Parse(args)
+	})
+	var got map[string]Source
+	cdr.Register(&testCommand{
+		name: "print",
+		flags: func(f *flag.FlagSet) {
+			f.Int("n", 0, "")
+			f.Int("m", 0, "")
+			f.Int("k", 0, "")
+		},
+		execute: func(_ context.Context, f *flag.FlagSet) ExitStatus {
+			got = map[string]Source{"n": Provenance(f, "n"), "m": Provenance(f, "m"), "k": Provenance(f, "k")}
+			return ExitSuccess
+		},
+	}, "")
+	cdr.Run(context.Background(), "print", []string{"-m", "1"})
+	want := map[string]Source{"n": SourceCommandLine, "m": SourceCommandLine, "k": SourceDefault}
+	for name := range want {
+		if got[name] != want[name] {
+			t.Errorf("-%s from %v, want %v", name, got[name], want[name])
+		}
+	}
+}
+
+func TestParseUsage(t *testing.T) {
+	// A flag set with the default Usage prints its defaults on errors,
+	// which makes zero values of the flag.Value types parse wraps them in.
+	var out strings.Builder
+	f := flag.NewFlagSet("print", flag.ContinueOnError)
+	f.SetOutput(&out)
+	f.Int("n", 0, "count")
+	f.String("s", "x", "text")
+	if err := parse(f, []string{"-x"}, nil); err == nil {
+		t.Fatal("parse of an undefined flag succeeded")
+	}
+	want := "flag provided but not defined: -x\nUsage of print:\n  -n int\n    \tcount\n  -s string\n    \ttext (default \"x\")\n"
+	if got := out.String(); got != want {
+		t.Errorf("printed\n%q\nwant\n%q", got, want)
+	}
+}
+
+func TestSourceString(t *testing.T) {
+	for src, want := range map[Source]string{
+		SourceDefault:     "default",
+		SourceCommandLine: "command line",
+		SourceEnv:         "environment",
+		SourceConfig:      "config file",
+		Source(99):        "unknown",
+	} {
+		if got := src.String(); got != want {
+			t.Errorf("Source(%d).String() = %q, want %q", int(src), got, want)
+		}
+	}
+}
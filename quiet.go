@@ -0,0 +1,51 @@
+/*
+Copyright 2016 Google Inc. All Rights Reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package subcommands
+
+// SetSilent sets whether the Commander keeps to reporting errors, for
+// programs run from cron jobs and scripts: in silent mode a usage error
+// is reported by its message alone, without usage or a pointer to help,
+// whatever the UsageErrors, and Logf drops messages below LevelError.
+// Help that is asked for is still printed, and the output of commands is
+// untouched; the progress package shows no status lines.
+func (cdr *Commander) SetSilent(silent bool) {
+	cdr.silent = silent
+}
+
+// Silent reports whether the Commander is in silent mode, as set by
+// SetSilent or the flag defined by QuietFlag, so that commands can leave
+// out chatter of their own.
+func (cdr *Commander) Silent() bool {
+	return cdr.silent
+}
+
+// QuietFlag defines a top-level boolean flag with the given name,
+// conventionally "quiet", that puts the Commander in silent mode, as
+// SetSilent does. It must be called before the top-level flags are
+// parsed.
+func (cdr *Commander) QuietFlag(name string) {
+	cdr.topFlags.BoolVar(&cdr.silent, name, false, "report only errors, without usage, hints or warnings")
+}
+
+// usageErrors returns how usage errors are reported: as selected by
+// cdr.UsageErrors, or by their message alone in silent mode.
+func (cdr *Commander) usageErrors() UsageErrorMode {
+	if cdr.silent && cdr.UsageErrors != UsageSilent {
+		return UsageLine
+	}
+	return cdr.UsageErrors
+}
@@ -0,0 +1,104 @@
+/*
+Copyright 2016 Google Inc. All Rights Reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package subcommands
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestQuietFlag(t *testing.T) {
+	tests := []struct {
+		args       []string
+		mode       UsageErrorMode
+		wantStatus ExitStatus
+		wantSilent bool   // as seen by the command, if it runs
+		wantStdout string // a prefix of stdout
+		wantStderr string
+	}{
+		{
+			args:       []string{"chat"},
+			wantStatus: ExitSuccess,
+			wantStdout: "output\n",
+			wantStderr: "tool: working\ntool: warning: slow\ntool: failed once\n",
+		},
+		{
+			args:       []string{"-quiet", "chat"},
+			wantStatus: ExitSuccess,
+			wantSilent: true,
+			wantStdout: "output\n",
+			wantStderr: "tool: failed once\n",
+		},
+		{
+			args:       []string{"-quiet=false", "chat"},
+			wantStatus: ExitSuccess,
+			wantStdout: "output\n",
+			wantStderr: "tool: working\ntool: warning: slow\ntool: failed once\n",
+		},
+		{
+			args:       []string{"-quiet", "chat", "-x"},
+			wantStatus: ExitUsageError,
+			wantStderr: "tool: chat: flag provided but not defined: -x\n",
+		},
+		{
+			args:       []string{"-quiet", "nosuch"},
+			wantStatus: ExitUsageError,
+			wantStderr: "tool: unknown subcommand \"nosuch\"\n",
+		},
+		{
+			args:       []string{"-quiet", "chat", "-x"},
+			mode:       UsageSilent,
+			wantStatus: ExitUsageError,
+		},
+		{
+			args:       []string{"-quiet", "help", "chat"},
+			wantStatus: ExitSuccess,
+			wantStdout: "chat:\n",
+		},
+	}
+	for _, tt := range tests {
+		cdr, stdout, stderr := newTestCommander()
+		cdr.UsageErrors = tt.mode
+		cdr.QuietFlag("quiet")
+		cdr.Register(cdr.HelpCommand(), "")
+		silent := false
+		cdr.Register(&testCommand{name: "chat", execute: func(ctx context.Context, f *flag.FlagSet) ExitStatus {
+			cdr := CommanderFromContext(ctx)
+			silent = cdr.Silent()
+			cdr.Infof("working")
+			cdr.Warnf("slow")
+			cdr.Errorf("failed once")
+			fmt.Fprintln(cdr.Output, "output")
+			return ExitSuccess
+		}}, "")
+		if status := execute(t, cdr, tt.args...); status != tt.wantStatus {
+			t.Errorf("%q: status %v, want %v", tt.args, status, tt.wantStatus)
+		}
+		if silent != tt.wantSilent {
+			t.Errorf("%q: Silent() = %v, want %v", tt.args, silent, tt.wantSilent)
+		}
+		if got := stdout.String(); !strings.HasPrefix(got, tt.wantStdout) || tt.wantStdout == "" && got != "" {
+			t.Errorf("%q: stdout %q, want it to start with %q", tt.args, got, tt.wantStdout)
+		}
+		if got := stderr.String(); got != tt.wantStderr {
+			t.Errorf("%q: stderr %q, want %q", tt.args, got, tt.wantStderr)
+		}
+	}
+}
@@ -0,0 +1,71 @@
+/*
+Copyright 2016 Google Inc. All Rights Reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package subcommands
+
+import "context"
+
+// A Reason is why Execute did not succeed.
+type Reason int
+
+const (
+	NoReason       Reason = iota // Execute succeeded.
+	NoCommand                    // No subcommand was given.
+	UnknownCommand               // The subcommand given is not registered.
+	FlagError                    // The flags of the subcommand could not be set.
+	CommandError                 // The subcommand failed, or ran past its deadline.
+	ArgError                     // The positional arguments of the subcommand are not those it declares.
+)
+
+func (r Reason) String() string {
+	switch r {
+	case NoReason:
+		return "none"
+	case NoCommand:
+		return "no command"
+	case UnknownCommand:
+		return "unknown command"
+	case FlagError:
+		return "flag error"
+	case CommandError:
+		return "command error"
+	case ArgError:
+		return "argument error"
+	}
+	return "unknown"
+}
+
+// A Result describes the outcome of Execute.
+type Result struct {
+	Command string     // Command is the name of the subcommand executed, or "" if none was.
+	Group   string     // Group is the group of the subcommand.
+	Status  ExitStatus // Status is the status returned by Execute.
+	Reason  Reason     // Reason is why Execute did not succeed.
+	Err     error      // Err is the error behind Reason, as returned by LastError, if any.
+}
+
+// ExecuteResult is like Execute, but returns a Result, so that a wrapper
+// can act on the outcome, such as retrying after a CommandError but not
+// after a FlagError, without parsing what was printed.
+func (cdr *Commander) ExecuteResult(ctx context.Context, args ...interface{}) Result {
+	status := cdr.Execute(ctx, args...)
+	r := cdr.last
+	r.Status = status
+	if status != ExitSuccess && r.Reason == NoReason {
+		r.Reason = CommandError
+	}
+	return r
+}
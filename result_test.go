@@ -0,0 +1,78 @@
+/*
+Copyright 2016 Google Inc. All Rights Reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package subcommands
+
+import (
+	"context"
+	"flag"
+	"testing"
+)
+
+func TestExecuteResult(t *testing.T) {
+	tests := []struct {
+		args    []string
+		want    Result
+		wantErr bool
+	}{
+		{[]string{"get", "x"}, Result{Command: "get", Group: "remote", Status: ExitSuccess, Reason: NoReason}, false},
+		{[]string{"fetch", "x"}, Result{Command: "fetch", Group: "remote", Status: ExitSuccess, Reason: NoReason}, false},
+		{nil, Result{Status: ExitUsageError, Reason: NoCommand}, true},
+		{[]string{"nosuch"}, Result{Status: ExitUsageError, Reason: UnknownCommand}, true},
+		{[]string{"get", "-n=x", "x"}, Result{Command: "get", Group: "remote", Status: ExitUsageError, Reason: FlagError}, true},
+		{[]string{"get"}, Result{Command: "get", Group: "remote", Status: ExitUsageError, Reason: ArgError}, true},
+		{[]string{"fail"}, Result{Command: "fail", Status: ExitCode(3), Reason: CommandError}, false},
+	}
+	for _, tt := range tests {
+		cdr, _, _ := newTestCommander()
+		cdr.UsageErrors = UsageSilent
+		get := &argsCommand{testCommand{name: "get", flags: func(f *flag.FlagSet) { f.Int("n", 0, "") }}, "<name>"}
+		cdr.Register(get, "remote")
+		cdr.Register(Alias("fetch", get), "remote")
+		cdr.Register(&testCommand{name: "fail", status: ExitCode(3)}, "")
+		if err := cdr.topFlags.Parse(tt.args); err != nil {
+			t.Fatal(err)
+		}
+		r := cdr.ExecuteResult(context.Background())
+		if (r.Err != nil) != tt.wantErr {
+			t.Errorf("%q: Err %v, want error %v", tt.args, r.Err, tt.wantErr)
+		}
+		r.Err = nil
+		if r != tt.want {
+			t.Errorf("%q: got %+v, want %+v", tt.args, r, tt.want)
+		}
+	}
+}
+
+func TestReasonString(t *testing.T) {
+	tests := []struct {
+		r    Reason
+		want string
+	}{
+		{NoReason, "none"},
+		{NoCommand, "no command"},
+		{UnknownCommand, "unknown command"},
+		{FlagError, "flag error"},
+		{CommandError, "command error"},
+		{ArgError, "argument error"},
+		{Reason(99), "unknown"},
+	}
+	for _, tt := range tests {
+		if got := tt.r.String(); got != tt.want {
+			t.Errorf("Reason(%d).String() = %q, want %q", int(tt.r), got, tt.want)
+		}
+	}
+}
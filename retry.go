@@ -0,0 +1,82 @@
+/*
+Copyright 2016 Google Inc. All Rights Reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package subcommands
+
+import (
+	"context"
+	"flag"
+	"time"
+)
+
+// A RetryPolicy controls how WithRetry re-executes a command.
+type RetryPolicy struct {
+	Attempts   int                   // Attempts is the most times the command is executed (default: 3).
+	Backoff    time.Duration         // Backoff is the delay before the second attempt, doubled for each later one (default: 1s).
+	MaxBackoff time.Duration         // MaxBackoff limits the delay between attempts (default: 0, unlimited).
+	Retryable  func(ExitStatus) bool // Retryable reports whether a failed attempt may be retried (default: if its status is ExitFailure).
+}
+
+// A retrier is a Command re-executing another by a RetryPolicy.
+type retrier struct {
+	Command
+	policy RetryPolicy
+}
+
+// WithRetry returns a Command that executes cmd until it succeeds, for
+// commands that fail on transient errors such as network timeouts. An
+// attempt whose status policy.Retryable accepts is retried after a
+// backoff, unless it was the last attempt or the context is done. Each
+// retry is reported as a warning by Warnf.
+//
+//	subcommands.Register(subcommands.WithRetry(&fetchCmd{}, subcommands.RetryPolicy{Attempts: 5}), "")
+func WithRetry(cmd Command, policy RetryPolicy) Command {
+	if policy.Attempts <= 0 {
+		policy.Attempts = 3
+	}
+	if policy.Backoff <= 0 {
+		policy.Backoff = time.Second
+	}
+	if policy.Retryable == nil {
+		policy.Retryable = func(status ExitStatus) bool { return status == ExitFailure }
+	}
+	return &retrier{cmd, policy}
+}
+
+func (r *retrier) Execute(ctx context.Context, f *flag.FlagSet, args ...interface{}) ExitStatus {
+	cdr := CommanderFromContext(ctx)
+	if cdr == nil {
+		cdr = DefaultCommander
+	}
+	backoff := r.policy.Backoff
+	for attempt := 1; ; attempt++ {
+		status := r.Command.Execute(ctx, f, args...)
+		if status == ExitSuccess || attempt == r.policy.Attempts || !r.policy.Retryable(status) {
+			return status
+		}
+		cdr.Warnf("%s: attempt %d of %d failed; retrying in %v", r.Name(), attempt, r.policy.Attempts, backoff)
+		timer := time.NewTimer(backoff)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return status
+		case <-timer.C:
+		}
+		if backoff *= 2; r.policy.MaxBackoff > 0 && backoff > r.policy.MaxBackoff {
+			backoff = r.policy.MaxBackoff
+		}
+	}
+}
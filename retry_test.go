@@ -0,0 +1,111 @@
+/*
+Copyright 2016 Google Inc. All Rights Reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package subcommands
+
+import (
+	"context"
+	"flag"
+	"testing"
+	"time"
+)
+
+func TestWithRetry(t *testing.T) {
+	tests := []struct {
+		name       string
+		policy     RetryPolicy
+		statuses   []ExitStatus // returned by successive attempts
+		wantStatus ExitStatus
+		wantRuns   int
+		wantStderr string
+	}{
+		{
+			name:       "first attempt",
+			policy:     RetryPolicy{Backoff: time.Millisecond},
+			statuses:   []ExitStatus{ExitSuccess},
+			wantStatus: ExitSuccess,
+			wantRuns:   1,
+		},
+		{
+			name:       "third attempt",
+			policy:     RetryPolicy{Backoff: time.Millisecond},
+			statuses:   []ExitStatus{ExitFailure, ExitFailure, ExitSuccess},
+			wantStatus: ExitSuccess,
+			wantRuns:   3,
+			wantStderr: "tool: warning: flaky: attempt 1 of 3 failed; retrying in 1ms\ntool: warning: flaky: attempt 2 of 3 failed; retrying in 2ms\n",
+		},
+		{
+			name:       "out of attempts",
+			policy:     RetryPolicy{Attempts: 2, Backoff: time.Millisecond},
+			statuses:   []ExitStatus{ExitFailure, ExitFailure, ExitSuccess},
+			wantStatus: ExitFailure,
+			wantRuns:   2,
+			wantStderr: "tool: warning: flaky: attempt 1 of 2 failed; retrying in 1ms\n",
+		},
+		{
+			name:       "max backoff",
+			policy:     RetryPolicy{Attempts: 4, Backoff: 2 * time.Millisecond, MaxBackoff: 3 * time.Millisecond},
+			statuses:   []ExitStatus{ExitFailure, ExitFailure, ExitFailure, ExitFailure},
+			wantStatus: ExitFailure,
+			wantRuns:   4,
+			wantStderr: "tool: warning: flaky: attempt 1 of 4 failed; retrying in 2ms\ntool: warning: flaky: attempt 2 of 4 failed; retrying in 3ms\ntool: warning: flaky: attempt 3 of 4 failed; retrying in 3ms\n",
+		},
+		{
+			name:       "not retryable",
+			policy:     RetryPolicy{Backoff: time.Millisecond},
+			statuses:   []ExitStatus{ExitTimeout, ExitSuccess},
+			wantStatus: ExitTimeout,
+			wantRuns:   1,
+		},
+		{
+			name:       "retryable",
+			policy:     RetryPolicy{Backoff: time.Millisecond, Retryable: func(s ExitStatus) bool { return s == ExitTimeout }},
+			statuses:   []ExitStatus{ExitTimeout, ExitSuccess},
+			wantStatus: ExitSuccess,
+			wantRuns:   2,
+			wantStderr: "tool: warning: flaky: attempt 1 of 3 failed; retrying in 1ms\n",
+		},
+	}
+	for _, tt := range tests {
+		cdr, _, stderr := newTestCommander()
+		flaky := &testCommand{name: "flaky"}
+		flaky.execute = func(context.Context, *flag.FlagSet) ExitStatus { return tt.statuses[flaky.runs-1] }
+		cdr.Register(WithRetry(flaky, tt.policy), "")
+		if status := execute(t, cdr, "flaky"); status != tt.wantStatus {
+			t.Errorf("%s: status %v, want %v", tt.name, status, tt.wantStatus)
+		}
+		if flaky.runs != tt.wantRuns {
+			t.Errorf("%s: ran %d times, want %d", tt.name, flaky.runs, tt.wantRuns)
+		}
+		if got := stderr.String(); got != tt.wantStderr {
+			t.Errorf("%s: stderr %q, want %q", tt.name, got, tt.wantStderr)
+		}
+	}
+}
+
+func TestWithRetryCanceled(t *testing.T) {
+	cdr, _, _ := newTestCommander()
+	ctx, cancel := context.WithCancel(context.Background())
+	flaky := &testCommand{name: "flaky", execute: func(context.Context, *flag.FlagSet) ExitStatus {
+		cancel()
+		return ExitFailure
+	}}
+	cdr.Register(WithRetry(flaky, RetryPolicy{Backoff: time.Hour}), "")
+	cdr.Run(ctx, "flaky", nil)
+	if flaky.runs != 1 {
+		t.Errorf("ran %d times after the context was canceled, want 1", flaky.runs)
+	}
+}
@@ -0,0 +1,114 @@
+/*
+Copyright 2016 Google Inc. All Rights Reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package subcommands
+
+import (
+	"encoding/json"
+	"flag"
+	"io"
+	"strconv"
+)
+
+// A jsonSchema is the subset of JSON Schema used to describe a
+// configuration file.
+type jsonSchema struct {
+	Schema               string                 `json:"$schema,omitempty"`
+	Title                string                 `json:"title,omitempty"`
+	Description          string                 `json:"description,omitempty"`
+	Type                 string                 `json:"type,omitempty"`
+	Default              interface{}            `json:"default,omitempty"`
+	Properties           map[string]*jsonSchema `json:"properties,omitempty"`
+	AdditionalProperties *bool                  `json:"additionalProperties,omitempty"`
+}
+
+// ConfigSchema writes to w a JSON Schema describing the configuration
+// file read through ConfigFlag: the keys for the top-level flags and
+// for the flags of each subcommand, with their types and defaults. It
+// lets editors validate the file. Aliases have no keys of their own;
+// they read those of the command they alias.
+func (cdr *Commander) ConfigSchema(w io.Writer) error {
+	no := false
+	root := &jsonSchema{
+		Schema:               "http://json-schema.org/draft-07/schema#",
+		Title:                cdr.DisplayName() + " configuration",
+		Type:                 "object",
+		Properties:           make(map[string]*jsonSchema),
+		AdditionalProperties: &no,
+	}
+	cdr.VisitAll(func(fl *flag.Flag) {
+		if fl.Name != cdr.configFlag {
+			root.Properties[fl.Name] = flagSchema(cdr.topFlags, fl)
+		}
+	})
+	cdr.VisitCommands(func(_ *CommandGroup, cmd Command) {
+		if _, ok := cmd.(*aliaser); ok {
+			return
+		}
+		f := flag.NewFlagSet(cmd.Name(), flag.ContinueOnError)
+		cmd.SetFlags(f)
+		defer forget(f)
+		markSecretFlags(cmd, f)
+		section := &jsonSchema{
+			Description:          cdr.synopsis(cmd),
+			Type:                 "object",
+			Properties:           make(map[string]*jsonSchema),
+			AdditionalProperties: &no,
+		}
+		f.VisitAll(func(fl *flag.Flag) {
+			section.Properties[fl.Name] = flagSchema(f, fl)
+		})
+		root.Properties[cmd.Name()] = section
+	})
+
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(root)
+}
+
+// flagSchema describes the values of fl, a flag in f, in a
+// configuration file. A text set by SetDefaultText stands for the
+// default, and the default of a secret flag is otherwise left out.
+func flagSchema(f *flag.FlagSet, fl *flag.Flag) *jsonSchema {
+	s := &jsonSchema{Description: fl.Usage, Type: "string"}
+	if fl.DefValue != "" {
+		s.Default = fl.DefValue
+	}
+	if getter, ok := fl.Value.(flag.Getter); ok {
+		switch getter.Get().(type) {
+		case bool:
+			s.Type = "boolean"
+			s.Default, _ = strconv.ParseBool(fl.DefValue)
+		case int, int64, uint, uint64:
+			s.Type = "integer"
+			if n, err := strconv.ParseInt(fl.DefValue, 0, 64); err == nil {
+				s.Default = n
+			}
+		case float64:
+			s.Type = "number"
+			if x, err := strconv.ParseFloat(fl.DefValue, 64); err == nil {
+				s.Default = x
+			}
+		}
+	}
+	if IsSecret(f, fl.Name) {
+		s.Default = nil
+	}
+	if text, ok := defaultText(f, fl.Name); ok {
+		s.Default = text
+	}
+	return s
+}
@@ -0,0 +1,96 @@
+/*
+Copyright 2016 Google Inc. All Rights Reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package subcommands
+
+import (
+	"bytes"
+	"flag"
+	"testing"
+	"time"
+)
+
+func TestConfigSchema(t *testing.T) {
+	cdr := NewCommander(flag.NewFlagSet("tool", flag.ContinueOnError), "tool")
+	cdr.ConfigFlag("config")
+	cdr.topFlags.Bool("verbose", false, "log more")
+	cdr.topFlags.String("token", "s3cret", "API token")
+	MarkSecret(cdr.topFlags, "token")
+	print := &testCommand{
+		name:     "print",
+		synopsis: "print args",
+		flags: func(f *flag.FlagSet) {
+			f.Int("n", 2, "count")
+			f.Float64("scale", 1.5, "scale")
+			f.Duration("wait", time.Second, "wait")
+			f.String("name", "", "name")
+		},
+	}
+	cdr.Register(print, "")
+	cdr.Register(Alias("p", print), "")
+
+	var buf bytes.Buffer
+	if err := cdr.ConfigSchema(&buf); err != nil {
+		t.Fatal(err)
+	}
+	want := `{
+  "$schema": "http://json-schema.org/draft-07/schema#",
+  "title": "tool configuration",
+  "type": "object",
+  "properties": {
+    "print": {
+      "description": "print args",
+      "type": "object",
+      "properties": {
+        "n": {
+          "description": "count",
+          "type": "integer",
+          "default": 2
+        },
+        "name": {
+          "description": "name",
+          "type": "string"
+        },
+        "scale": {
+          "description": "scale",
+          "type": "number",
+          "default": 1.5
+        },
+        "wait": {
+          "description": "wait",
+          "type": "string",
+          "default": "1s"
+        }
+      },
+      "additionalProperties": false
+    },
+    "token": {
+      "description": "API token",
+      "type": "string"
+    },
+    "verbose": {
+      "description": "log more",
+      "type": "boolean",
+      "default": false
+    }
+  },
+  "additionalProperties": false
+}
+`
+	if got := buf.String(); got != want {
+		t.Errorf("ConfigSchema wrote\n%s\nwant\n%s", got, want)
+	}
+}
@@ -0,0 +1,126 @@
+/*
+Copyright 2016 Google Inc. All Rights Reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package subcommands
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/google/subcommands/internal/textwidth"
+)
+
+// A searcher is a Command implementing a "search" command for a given
+// Commander.
+type searcher Commander
+
+func (s *searcher) Name() string           { return "search" }
+func (s *searcher) Synopsis() string       { return "search subcommands and their flags" }
+func (s *searcher) SetFlags(*flag.FlagSet) {}
+func (s *searcher) Usage() string {
+	return `search <keyword>...:
+	List the subcommands whose name, synopsis, usage or flags mention
+	the keywords, ignoring case, best matches first, with the lines
+	that mention them.
+`
+}
+
+// A searchHit is a subcommand found by a searcher.
+type searchHit struct {
+	cmd      Command
+	score    int      // score ranks hits, higher first.
+	snippets []string // snippets are the lines of usage and flags that match.
+}
+
+// Weights of matches, by where in a command they are found.
+const (
+	nameWeight     = 8
+	synopsisWeight = 4
+	usageWeight    = 2
+	flagWeight     = 1
+)
+
+func (s *searcher) Execute(ctx context.Context, f *flag.FlagSet, _ ...interface{}) ExitStatus {
+	cdr := (*Commander)(s).executing(ctx)
+	if f.NArg() == 0 {
+		f.Usage()
+		return ExitUsageError
+	}
+	keyword := strings.ToLower(strings.Join(f.Args(), " "))
+	matches := func(text string) bool { return strings.Contains(strings.ToLower(text), keyword) }
+
+	var hits []searchHit
+	cdr.VisitCommands(func(_ *CommandGroup, cmd Command) {
+		if _, ok := cmd.(*aliaser); ok || hidden(cmd) {
+			return
+		}
+		hit := searchHit{cmd: cmd}
+		if matches(cmd.Name()) {
+			hit.score += nameWeight
+		}
+		if matches(cdr.synopsis(cmd)) {
+			hit.score += synopsisWeight
+		}
+		for _, line := range strings.Split(usage(cmd), "\n") {
+			if line = strings.TrimSpace(line); line != "" && matches(line) {
+				hit.score += usageWeight
+				hit.snippets = append(hit.snippets, line)
+			}
+		}
+		fs := flag.NewFlagSet(cmd.Name(), flag.ContinueOnError)
+		cmd.SetFlags(fs)
+		defer forget(fs)
+		fs.VisitAll(func(fl *flag.Flag) {
+			if matches(fl.Name) || matches(fl.Usage) {
+				hit.score += flagWeight
+				arg, usage := flag.UnquoteUsage(fl)
+				hit.snippets = append(hit.snippets, strings.TrimSpace("-"+fl.Name+" "+arg)+": "+usage)
+			}
+		})
+		if hit.score > 0 {
+			hits = append(hits, hit)
+		}
+	})
+	if len(hits) == 0 {
+		cdr.Errorf("search: no subcommands match %q", keyword)
+		return ExitFailure
+	}
+	sort.SliceStable(hits, func(i, j int) bool {
+		if hits[i].score != hits[j].score {
+			return hits[i].score > hits[j].score
+		}
+		return hits[i].cmd.Name() < hits[j].cmd.Name()
+	})
+
+	st := cdr.Style
+	for _, hit := range hits {
+		fmt.Fprintf(cdr.Output, "%s%s%s%s\n", st.Indent, textwidth.Pad(hit.cmd.Name(), st.NameWidth), strings.Repeat(" ", st.Gutter), cdr.synopsis(hit.cmd))
+		for _, snippet := range hit.snippets {
+			fmt.Fprintf(cdr.Output, "%s%s%s\n", st.Indent, st.Indent, snippet)
+		}
+	}
+	return ExitSuccess
+}
+
+// SearchCommand returns a Command which implements a "search"
+// subcommand, which lists the subcommands whose name, synopsis, usage or
+// flags mention its arguments.
+func (cdr *Commander) SearchCommand() Command {
+	return (*searcher)(cdr)
+}
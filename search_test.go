@@ -0,0 +1,86 @@
+/*
+Copyright 2016 Google Inc. All Rights Reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package subcommands
+
+import (
+	"context"
+	"flag"
+	"strings"
+	"testing"
+)
+
+func TestSearch(t *testing.T) {
+	tests := []struct {
+		args       []string
+		wantStatus ExitStatus
+		wantStdout string
+		wantStderr string
+	}{
+		{
+			args:       []string{"DEPLOY"},
+			wantStatus: ExitSuccess,
+			wantStdout: "" +
+				"\tdeploy           deploy a release\n" +
+				"\t\tdeploy:\n" +
+				"\t\t-region string: region to deploy to\n" +
+				"\trollback         undo a deploy\n",
+		},
+		{
+			args:       []string{"region", "to"},
+			wantStatus: ExitSuccess,
+			wantStdout: "\tdeploy           deploy a release\n\t\t-region string: region to deploy to\n",
+		},
+		{
+			args:       []string{"tests"},
+			wantStatus: ExitSuccess,
+			wantStdout: "" +
+				"\tdeploy           deploy a release\n\t\tA command for tests.\n" +
+				"\tprint            print args\n\t\tA command for tests.\n" +
+				"\trollback         undo a deploy\n\t\tA command for tests.\n",
+		},
+		{
+			args:       []string{"complete"},
+			wantStatus: ExitFailure,
+			wantStderr: "tool: search: no subcommands match \"complete\"\n",
+		},
+		{
+			args:       nil,
+			wantStatus: ExitUsageError,
+			wantStderr: "search <keyword>...:\n",
+		},
+	}
+	for _, tt := range tests {
+		cdr, stdout, stderr := newTestCommander()
+		cdr.Register(cdr.SearchCommand(), "")
+		cdr.Register(cdr.CompleteCommand(), "")
+		deploy := &testCommand{name: "deploy", synopsis: "deploy a release", flags: func(f *flag.FlagSet) {
+			f.String("region", "", "region to deploy to")
+		}}
+		cdr.Register(deploy, "")
+		cdr.Register(Alias("d", deploy), "")
+		cdr.Register(&testCommand{name: "rollback", synopsis: "undo a deploy"}, "")
+		if status := cdr.Run(context.Background(), "search", tt.args); status != tt.wantStatus {
+			t.Errorf("%q: status %v, want %v", tt.args, status, tt.wantStatus)
+		}
+		if got := stdout.String(); got != tt.wantStdout {
+			t.Errorf("%q: stdout\n%q\nwant\n%q", tt.args, got, tt.wantStdout)
+		}
+		if got := stderr.String(); !strings.HasPrefix(got, tt.wantStderr) {
+			t.Errorf("%q: stderr %q, want it to start with %q", tt.args, got, tt.wantStderr)
+		}
+	}
+}
@@ -0,0 +1,116 @@
+/*
+Copyright 2016 Google Inc. All Rights Reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package subcommands
+
+import (
+	"flag"
+	"io"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// Redacted replaces the values of secret flags wherever they would be
+// shown.
+const Redacted = "<redacted>"
+
+// A SecretFlagger is a Command with flags whose values must not be
+// recorded or shown, such as passwords and tokens. See MarkSecret.
+type SecretFlagger interface {
+	// SecretFlags returns the names of the secret flags of the command.
+	SecretFlags() []string
+}
+
+// secrets records the flags marked secret by MarkSecret.
+var secrets = struct {
+	sync.Mutex
+	m map[*flag.FlagSet]map[string]bool
+}{m: make(map[*flag.FlagSet]map[string]bool)}
+
+// MarkSecret marks the named flag in f as secret, such as a password or
+// token. Its value is replaced by Redacted in audit records, in the
+// output of the config builtin, and in the error messages of the
+// Commander that would otherwise show it. Commands call it from
+// SetFlags:
+//
+//	func (c *loginCmd) SetFlags(f *flag.FlagSet) {
+//		f.StringVar(&c.token, "token", "", "API token")
+//		subcommands.MarkSecret(f, "token")
+//	}
+//
+// The flags named by a command implementing SecretFlagger are marked
+// secret by the Commander.
+func MarkSecret(f *flag.FlagSet, name string) {
+	secrets.Lock()
+	defer secrets.Unlock()
+	if secrets.m[f] == nil {
+		secrets.m[f] = make(map[string]bool)
+	}
+	secrets.m[f][name] = true
+}
+
+// IsSecret reports whether the named flag in f has been marked secret.
+func IsSecret(f *flag.FlagSet, name string) bool {
+	secrets.Lock()
+	defer secrets.Unlock()
+	return secrets.m[f][name]
+}
+
+// markSecretFlags marks the flags named by cmd, if it is a
+// SecretFlagger, as secret in f.
+func markSecretFlags(cmd Command, f *flag.FlagSet) {
+	if sf, ok := dealias(cmd).(SecretFlagger); ok {
+		for _, name := range sf.SecretFlags() {
+			MarkSecret(f, name)
+		}
+	}
+}
+
+// shown returns value, the value of the named flag in f, as it may be
+// shown: Redacted if the flag is secret.
+func shown(f *flag.FlagSet, name, value string) string {
+	if IsSecret(f, name) {
+		return Redacted
+	}
+	return value
+}
+
+// redactValues returns s with the quoted forms of values replaced by
+// that of Redacted.
+func redactValues(s string, values []string) string {
+	for _, v := range values {
+		s = strings.ReplaceAll(s, strconv.Quote(v), strconv.Quote(Redacted))
+	}
+	return s
+}
+
+// A redactingWriter writes to w with the quoted forms of the values
+// rejected for secret flags redacted.
+type redactingWriter struct {
+	w        io.Writer
+	rejected *[]string
+}
+
+func (r *redactingWriter) Write(b []byte) (int, error) {
+	if len(*r.rejected) == 0 {
+		return r.w.Write(b)
+	}
+	if _, err := io.WriteString(r.w, redactValues(string(b), *r.rejected)); err != nil {
+		return 0, err
+	}
+	return len(b), nil
+}
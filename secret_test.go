@@ -0,0 +1,109 @@
+/*
+Copyright 2016 Google Inc. All Rights Reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package subcommands
+
+import (
+	"errors"
+	"flag"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestMarkSecret(t *testing.T) {
+	f := flag.NewFlagSet("login", flag.ContinueOnError)
+	f.String("token", "", "")
+	if IsSecret(f, "token") {
+		t.Error("IsSecret before MarkSecret = true")
+	}
+	MarkSecret(f, "token")
+	if !IsSecret(f, "token") {
+		t.Error("IsSecret after MarkSecret = false")
+	}
+	if IsSecret(flag.NewFlagSet("login", flag.ContinueOnError), "token") {
+		t.Error("IsSecret in another flag set = true")
+	}
+}
+
+func TestSecretRedaction(t *testing.T) {
+	// Each case makes the value 12ab of a flag appear in an error or in
+	// help; it must be shown only for the -count flag, not the secret
+	// -pin flag.
+	tests := []struct {
+		name   string
+		flag   string
+		config string            // contents of the -config file
+		env    map[string]string // with BindEnv
+		check  bool              // reject the flag with ValidateFlag
+		args   []string
+	}{
+		{name: "command line", flag: "pin", args: []string{"login", "-pin=12ab"}},
+		{name: "command line", flag: "count", args: []string{"login", "-count=12ab"}},
+		{name: "environment", flag: "pin", env: map[string]string{"TOOL_LOGIN_PIN": "12ab"}, args: []string{"login"}},
+		{name: "environment", flag: "count", env: map[string]string{"TOOL_LOGIN_COUNT": "12ab"}, args: []string{"login"}},
+		{name: "config", flag: "pin", config: `{"login": {"pin": "12ab"}}`, args: []string{"login"}},
+		{name: "config", flag: "count", config: `{"login": {"count": "12ab"}}`, args: []string{"login"}},
+		{name: "validation", flag: "pin", check: true, args: []string{"login", "-pin=12ab"}},
+		{name: "validation", flag: "count", check: true, args: []string{"login", "-count=12ab"}},
+		{name: "help", flag: "pin", args: []string{"help", "login"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name+" "+tt.flag, func(t *testing.T) {
+			for k, v := range tt.env {
+				t.Setenv(k, v)
+			}
+			cdr, stdout, stderr := newTestCommander()
+			cdr.Register(cdr.HelpCommand(), "")
+			if tt.env != nil {
+				cdr.BindEnv()
+			}
+			args := tt.args
+			if tt.config != "" {
+				path := filepath.Join(t.TempDir(), "config.json")
+				if err := os.WriteFile(path, []byte(tt.config), 0o666); err != nil {
+					t.Fatal(err)
+				}
+				cdr.ConfigFlag("config")
+				args = append([]string{"-config", path}, args...)
+			}
+			cdr.Register(&testCommand{name: "login", flags: func(f *flag.FlagSet) {
+				switch {
+				case tt.name == "help":
+					f.String("pin", "12ab", "")
+				case tt.check:
+					f.String("pin", "", "")
+					f.String("count", "", "")
+					ValidateFlag(f, tt.flag, func(string) error { return errors.New("too short") })
+				default:
+					f.Int("pin", 0, "")
+					f.Int("count", 0, "")
+				}
+				MarkSecret(f, "pin")
+			}}, "")
+
+			execute(t, cdr, args...)
+			out := stdout.String() + stderr.String()
+			if shown := strings.Contains(out, "12ab"); shown != (tt.flag == "count") {
+				t.Errorf("value shown %v in %q", shown, out)
+			}
+			if tt.flag == "pin" && !strings.Contains(out, Redacted) {
+				t.Errorf("%s missing from %q", Redacted, out)
+			}
+		})
+	}
+}
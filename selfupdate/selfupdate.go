@@ -0,0 +1,148 @@
+/*
+Copyright 2016 Google Inc. All Rights Reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package selfupdate provides a "self-update" subcommand, which updates
+// a program to its latest release. The program supplies how releases
+// are found and installed; the command provides the flags to choose a
+// channel, to only check and to skip confirmation, and reports progress
+// with the progress package.
+//
+//	subcommands.Register(selfupdate.Command(checkRelease, installRelease), "")
+package selfupdate
+
+import (
+	"bufio"
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/google/subcommands"
+	"github.com/google/subcommands/progress"
+)
+
+// A Release is a version of a program that it can be updated to.
+type Release struct {
+	Version string // Version names the release, such as "v1.4.0".
+	Notes   string // Notes summarize the changes in the release, if any.
+}
+
+// A Checker looks up the latest release of a program on the named
+// channel, such as "stable" or "beta". It returns nil if the running
+// program is the latest.
+type Checker func(ctx context.Context, channel string) (*Release, error)
+
+// An Applier replaces the running program with r, reporting its
+// progress, such as bytes downloaded, by calling progress with the work
+// done and the total, or a total of -1 if that is unknown.
+type Applier func(ctx context.Context, r *Release, progress func(done, total int64)) error
+
+// An updater is a Command implementing a "self-update" command.
+type updater struct {
+	check   Checker
+	apply   Applier
+	channel string
+	dryRun  bool
+	yes     bool
+}
+
+func (u *updater) Name() string     { return "self-update" }
+func (u *updater) Synopsis() string { return "update this program to its latest release" }
+func (u *updater) SetFlags(f *flag.FlagSet) {
+	f.StringVar(&u.channel, "channel", "stable", "update from the release `channel`")
+	f.BoolVar(&u.dryRun, "check", false, "only report whether there is a newer release")
+	f.BoolVar(&u.yes, "yes", false, "update without asking for confirmation")
+}
+func (u *updater) Usage() string {
+	return `self-update [-channel name] [-check] [-yes]:
+	Look up the latest release of this program on the channel and, if
+	it is newer, replace this program with it after asking for
+	confirmation. With -check, only report whether there is a newer
+	release; the exit status is 0 either way.
+`
+}
+
+func (u *updater) Execute(ctx context.Context, f *flag.FlagSet, _ ...interface{}) subcommands.ExitStatus {
+	cdr := subcommands.CommanderFromContext(ctx)
+	if cdr == nil {
+		cdr = subcommands.DefaultCommander
+	}
+	if f.NArg() != 0 {
+		f.Usage()
+		return subcommands.ExitUsageError
+	}
+
+	r, err := u.check(ctx, u.channel)
+	if err != nil {
+		cdr.Errorf("self-update: checking for a release: %v", err)
+		return subcommands.ExitFailure
+	}
+	if r == nil {
+		fmt.Fprintf(cdr.Output, "Already up to date on the %s channel.\n", u.channel)
+		return subcommands.ExitSuccess
+	}
+	fmt.Fprintf(cdr.Output, "Release %s is available on the %s channel.\n", r.Version, u.channel)
+	if r.Notes != "" {
+		fmt.Fprintf(cdr.Output, "\n%s\n\n", strings.TrimRight(r.Notes, "\n"))
+	}
+	if u.dryRun {
+		return subcommands.ExitSuccess
+	}
+	if !u.yes {
+		if !subcommands.IsTerminal(os.Stdin) {
+			cdr.Errorf("self-update: not updating without confirmation; use -yes")
+			return subcommands.ExitFailure
+		}
+		fmt.Fprintf(cdr.Output, "Update to %s? [y/N] ", r.Version)
+		answer, _ := bufio.NewReader(os.Stdin).ReadString('\n')
+		if a := strings.ToLower(strings.TrimSpace(answer)); a != "y" && a != "yes" {
+			fmt.Fprintln(cdr.Output, "Not updated.")
+			return subcommands.ExitFailure
+		}
+	}
+
+	// The amount done is shown only on a terminal, where the status line
+	// is redrawn in place, rather than as a line in a log for each step.
+	status := progress.ForCommander(cdr)
+	status.Start("updating to %s", r.Version)
+	interactive := subcommands.IsTerminal(cdr.Error)
+	err = u.apply(ctx, r, func(done, total int64) {
+		switch {
+		case !interactive:
+		case total > 0:
+			status.Update("updating to %s: %3d%%", r.Version, done*100/total)
+		default:
+			status.Update("updating to %s: %d bytes", r.Version, done)
+		}
+	})
+	status.Stop("")
+	if err != nil {
+		cdr.Errorf("self-update: updating to %s: %v", r.Version, err)
+		return subcommands.ExitFailure
+	}
+	fmt.Fprintf(cdr.Output, "Updated to %s.\n", r.Version)
+	return subcommands.ExitSuccess
+}
+
+// Command returns a Command which implements a "self-update"
+// subcommand, which updates the program to its latest release. The
+// program supplies how releases are found, by check, and installed, by
+// apply; the command provides the flags to choose a channel, to only
+// check and to skip confirmation, and reports progress.
+func Command(check Checker, apply Applier) subcommands.Command {
+	return &updater{check: check, apply: apply}
+}
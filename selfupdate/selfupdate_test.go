@@ -0,0 +1,145 @@
+/*
+Copyright 2016 Google Inc. All Rights Reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package selfupdate
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"flag"
+	"os"
+	"testing"
+
+	"github.com/google/subcommands"
+)
+
+func TestCommand(t *testing.T) {
+	v2 := &Release{Version: "v2", Notes: "Faster.\n"}
+	tests := []struct {
+		name       string
+		args       []string
+		channel    string // given by -channel, if not stable
+		release    *Release
+		checkErr   error
+		applyErr   error
+		silent     bool
+		wantStatus subcommands.ExitStatus
+		wantApply  bool
+		wantStdout string
+		wantStderr string
+	}{
+		{
+			name:       "up to date",
+			wantStatus: subcommands.ExitSuccess,
+			wantStdout: "Already up to date on the stable channel.\n",
+		},
+		{
+			name:       "check fails",
+			checkErr:   errors.New("offline"),
+			wantStatus: subcommands.ExitFailure,
+			wantStderr: "tool: self-update: checking for a release: offline\n",
+		},
+		{
+			name:       "check only",
+			args:       []string{"-check", "-channel=beta"},
+			channel:    "beta",
+			release:    v2,
+			wantStatus: subcommands.ExitSuccess,
+			wantStdout: "Release v2 is available on the beta channel.\n\nFaster.\n\n",
+		},
+		{
+			name:       "update",
+			args:       []string{"-yes"},
+			release:    v2,
+			wantStatus: subcommands.ExitSuccess,
+			wantApply:  true,
+			wantStdout: "Release v2 is available on the stable channel.\n\nFaster.\n\nUpdated to v2.\n",
+			wantStderr: "updating to v2\n",
+		},
+		{
+			name:       "update silently",
+			args:       []string{"-yes"},
+			release:    v2,
+			silent:     true,
+			wantStatus: subcommands.ExitSuccess,
+			wantApply:  true,
+			wantStdout: "Release v2 is available on the stable channel.\n\nFaster.\n\nUpdated to v2.\n",
+		},
+		{
+			name:       "update fails",
+			args:       []string{"-yes"},
+			release:    v2,
+			applyErr:   errors.New("disk full"),
+			wantStatus: subcommands.ExitFailure,
+			wantApply:  true,
+			wantStdout: "Release v2 is available on the stable channel.\n\nFaster.\n\n",
+			wantStderr: "updating to v2\ntool: self-update: updating to v2: disk full\n",
+		},
+		{
+			name:       "no confirmation",
+			release:    v2,
+			wantStatus: subcommands.ExitFailure,
+			wantStdout: "Release v2 is available on the stable channel.\n\nFaster.\n\n",
+			wantStderr: "tool: self-update: not updating without confirmation; use -yes\n",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.name == "no confirmation" && subcommands.IsTerminal(os.Stdin) {
+				t.Skip("standard input is a terminal")
+			}
+			var stdout, stderr bytes.Buffer
+			cdr := subcommands.NewCommander(flag.NewFlagSet("tool", flag.ContinueOnError), "tool")
+			cdr.Output, cdr.Error = &stdout, &stderr
+			cdr.SetSilent(tt.silent)
+			var channel string
+			applied := false
+			check := func(ctx context.Context, ch string) (*Release, error) {
+				channel = ch
+				return tt.release, tt.checkErr
+			}
+			apply := func(ctx context.Context, r *Release, progress func(done, total int64)) error {
+				applied = true
+				progress(1, 2)
+				progress(2, 2)
+				return tt.applyErr
+			}
+			cdr.Register(Command(check, apply), "")
+
+			status := cdr.Run(context.Background(), "self-update", tt.args)
+			if status != tt.wantStatus {
+				t.Errorf("status %v, want %v", status, tt.wantStatus)
+			}
+			wantChannel := "stable"
+			if tt.channel != "" {
+				wantChannel = tt.channel
+			}
+			if channel != wantChannel {
+				t.Errorf("checked channel %q, want %q", channel, wantChannel)
+			}
+			if applied != tt.wantApply {
+				t.Errorf("applied %v, want %v", applied, tt.wantApply)
+			}
+			if got := stdout.String(); got != tt.wantStdout {
+				t.Errorf("stdout %q, want %q", got, tt.wantStdout)
+			}
+			if got := stderr.String(); got != tt.wantStderr {
+				t.Errorf("stderr %q, want %q", got, tt.wantStderr)
+			}
+		})
+	}
+}
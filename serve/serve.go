@@ -0,0 +1,198 @@
+/*
+Copyright 2016 Google Inc. All Rights Reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package serve exposes the subcommands of a Commander on a unix
+// socket, so that a long-running program can offer its command line as
+// an admin interface. A thin client sends a command line with Call; the
+// server runs it and streams its output and exit status back.
+//
+//	// In the daemon:
+//	go serve.ListenAndServe(ctx, "/run/tool.sock", cdr)
+//
+//	// In the client:
+//	status, err := serve.Call(ctx, "/run/tool.sock", os.Args[1:], os.Stdout, os.Stderr)
+//
+// Commands run one at a time, since they keep their flags in their own
+// fields. They should write to the Output and Error of the Commander
+// returned by subcommands.CommanderFromContext for their output to
+// reach the client, as the builtins, such as help, do.
+package serve
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"sync"
+
+	"github.com/google/subcommands"
+)
+
+// A message is sent, one per line of JSON, in either direction. The
+// client sends Args; the server replies with any number of Stdout and
+// Stderr messages followed by one Status message.
+type message struct {
+	Args   []string                `json:"args,omitempty"`
+	Stdout []byte                  `json:"stdout,omitempty"`
+	Stderr []byte                  `json:"stderr,omitempty"`
+	Status *subcommands.ExitStatus `json:"status,omitempty"`
+}
+
+// ListenAndServe listens on the unix socket at path and serves cdr on it
+// until ctx is done. It removes the socket when it returns.
+func ListenAndServe(ctx context.Context, path string, cdr *subcommands.Commander) error {
+	l, err := net.Listen("unix", path)
+	if err != nil {
+		return err
+	}
+	defer os.Remove(path)
+	return Serve(ctx, l, cdr)
+}
+
+// Serve accepts connections on l and runs the command line sent on each
+// with cdr, until ctx is done. It closes l when it returns, and returns
+// nil if it stopped because ctx was done.
+func Serve(ctx context.Context, l net.Listener, cdr *subcommands.Commander) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	go func() {
+		<-ctx.Done()
+		l.Close()
+	}()
+
+	var (
+		mu sync.Mutex // serializes command execution
+		wg sync.WaitGroup
+	)
+	defer wg.Wait()
+	for {
+		conn, err := l.Accept()
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil
+			}
+			return err
+		}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			defer conn.Close()
+			serveConn(ctx, conn, cdr, &mu)
+		}()
+	}
+}
+
+// serveConn runs the command line sent on conn with cdr, holding mu.
+func serveConn(ctx context.Context, conn net.Conn, cdr *subcommands.Commander, mu *sync.Mutex) {
+	r := bufio.NewReader(conn)
+	var req message
+	if err := json.NewDecoder(r).Decode(&req); err != nil {
+		return
+	}
+
+	// The command is canceled if the client goes away.
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	go func() {
+		io.Copy(io.Discard, r)
+		cancel()
+	}()
+
+	enc := &encoder{enc: json.NewEncoder(conn)}
+	stdout := &stream{enc, func(b []byte) message { return message{Stdout: b} }}
+	stderr := &stream{enc, func(b []byte) message { return message{Stderr: b} }}
+
+	status := subcommands.ExitUsageError
+	if len(req.Args) == 0 {
+		fmt.Fprintf(stderr, "%s: no subcommand given\n", cdr.DisplayName())
+	} else {
+		mu.Lock()
+		c := *cdr
+		c.Output, c.Error = stdout, stderr
+		status = c.Run(ctx, req.Args[0], req.Args[1:])
+		mu.Unlock()
+	}
+	enc.encode(message{Status: &status})
+}
+
+// An encoder writes messages to a connection from several writers.
+type encoder struct {
+	mu  sync.Mutex
+	enc *json.Encoder
+}
+
+func (e *encoder) encode(m message) error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return e.enc.Encode(m)
+}
+
+// A stream is an io.Writer sending what is written to it in messages.
+type stream struct {
+	enc  *encoder
+	wrap func([]byte) message
+}
+
+func (s *stream) Write(b []byte) (int, error) {
+	if err := s.enc.encode(s.wrap(append([]byte(nil), b...))); err != nil {
+		return 0, err
+	}
+	return len(b), nil
+}
+
+// Call runs the command line argv, a subcommand name followed by its
+// arguments, with the Commander served on the unix socket at path. It
+// copies the output of the command to stdout and stderr and returns its
+// exit status.
+func Call(ctx context.Context, path string, argv []string, stdout, stderr io.Writer) (subcommands.ExitStatus, error) {
+	var d net.Dialer
+	conn, err := d.DialContext(ctx, "unix", path)
+	if err != nil {
+		return subcommands.ExitFailure, err
+	}
+	defer conn.Close()
+	stop := context.AfterFunc(ctx, func() { conn.Close() })
+	defer stop()
+
+	if err := json.NewEncoder(conn).Encode(message{Args: argv}); err != nil {
+		return subcommands.ExitFailure, err
+	}
+	dec := json.NewDecoder(conn)
+	for {
+		var m message
+		if err := dec.Decode(&m); err != nil {
+			if ctx.Err() != nil {
+				return subcommands.ExitFailure, ctx.Err()
+			}
+			if err == io.EOF {
+				err = errors.New("connection closed before exit status")
+			}
+			return subcommands.ExitFailure, err
+		}
+		switch {
+		case m.Status != nil:
+			return *m.Status, nil
+		case m.Stdout != nil:
+			stdout.Write(m.Stdout)
+		case m.Stderr != nil:
+			stderr.Write(m.Stderr)
+		}
+	}
+}
@@ -0,0 +1,101 @@
+/*
+Copyright 2016 Google Inc. All Rights Reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package serve
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"flag"
+	"net"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/google/subcommands"
+)
+
+// request sends argv to serveConn running cdr, and returns what the
+// command wrote to the client and its exit status.
+func request(t *testing.T, cdr *subcommands.Commander, argv ...string) (stdout, stderr string, status subcommands.ExitStatus) {
+	t.Helper()
+	client, server := net.Pipe()
+	defer client.Close()
+	var mu sync.Mutex
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		defer server.Close()
+		serveConn(context.Background(), server, cdr, &mu)
+	}()
+	if err := json.NewEncoder(client).Encode(message{Args: argv}); err != nil {
+		t.Fatal(err)
+	}
+	var out, errOut bytes.Buffer
+	dec := json.NewDecoder(client)
+	for {
+		var m message
+		if err := dec.Decode(&m); err != nil {
+			t.Fatalf("reading reply to %q: %v", argv, err)
+		}
+		out.Write(m.Stdout)
+		errOut.Write(m.Stderr)
+		if m.Status != nil {
+			status = *m.Status
+			break
+		}
+	}
+	client.Close()
+	<-done
+	return out.String(), errOut.String(), status
+}
+
+func TestServeConnBuiltins(t *testing.T) {
+	tests := []struct {
+		argv       []string
+		wantStatus subcommands.ExitStatus
+		wantStdout string
+		wantStderr string
+	}{
+		{[]string{"help"}, subcommands.ExitSuccess, "Subcommands:", ""},
+		{[]string{"help", "commands"}, subcommands.ExitSuccess, "Print a list of all commands.", ""},
+		{[]string{"help", "nosuch"}, subcommands.ExitUsageError, "", "tool: help: subcommand nosuch not understood\n"},
+		{[]string{"commands"}, subcommands.ExitSuccess, "help\n", ""},
+		{[]string{"nosuch"}, subcommands.ExitUsageError, "", `unknown subcommand "nosuch"`},
+	}
+	for _, tt := range tests {
+		var daemonOut, daemonErr bytes.Buffer
+		cdr := subcommands.NewCommander(flag.NewFlagSet("tool", flag.ContinueOnError), "tool")
+		cdr.Output, cdr.Error = &daemonOut, &daemonErr
+		cdr.Register(cdr.HelpCommand(), "")
+		cdr.Register(cdr.CommandsCommand(), "")
+
+		stdout, stderr, status := request(t, cdr, tt.argv...)
+		if status != tt.wantStatus {
+			t.Errorf("%q: status %v, want %v", tt.argv, status, tt.wantStatus)
+		}
+		if !strings.Contains(stdout, tt.wantStdout) {
+			t.Errorf("%q: stdout %q, want it to contain %q", tt.argv, stdout, tt.wantStdout)
+		}
+		if !strings.Contains(stderr, tt.wantStderr) {
+			t.Errorf("%q: stderr %q, want it to contain %q", tt.argv, stderr, tt.wantStderr)
+		}
+		if daemonOut.Len() != 0 || daemonErr.Len() != 0 {
+			t.Errorf("%q: daemon wrote %q and %q, want nothing", tt.argv, daemonOut.String(), daemonErr.String())
+		}
+	}
+}
@@ -19,14 +19,22 @@ limitations under the License.
 package subcommands
 
 import (
+	"bytes"
 	"context"
+	"errors"
 	"flag"
 	"fmt"
 	"io"
 	"os"
-	"path"
+	"path/filepath"
 	"sort"
+	"strconv"
 	"strings"
+	"sync"
+	"text/template"
+	"time"
+
+	"github.com/google/subcommands/internal/textwidth"
 )
 
 // A Command represents a single command.
@@ -48,25 +56,139 @@ type Command interface {
 	Execute(ctx context.Context, f *flag.FlagSet, args ...interface{}) ExitStatus
 }
 
+// A KeyFlagger is a Command that nominates a few of its flags as the
+// most important ones. Their names are shown after the command's
+// synopsis when its group is explained.
+type KeyFlagger interface {
+	// KeyFlags returns the names of the command's most important flags.
+	KeyFlags() []string
+}
+
+// A ContextSynopsizer is a Command whose synopsis depends on runtime
+// state, such as enabled features or the current profile. Help output
+// uses SynopsisContext in place of Synopsis, with the context the
+// Commander is executing with.
+type ContextSynopsizer interface {
+	// SynopsisContext returns a short string (less than one line)
+	// describing the command.
+	SynopsisContext(ctx context.Context) string
+}
+
 // A Commander represents a set of commands.
 type Commander struct {
 	commands  []*CommandGroup
-	topFlags  *flag.FlagSet // top-level flags
-	important []string      // important top-level flags
-	name      string        // normally path.Base(os.Args[0])
+	topFlags  *flag.FlagSet   // top-level flags
+	important []string        // important top-level flags
+	name      string          // normally programName(os.Args[0])
+	display   string          // name shown in help output, if not name
+	helpFlag  bool            // set by the flags defined by HandleHelpFlags
+	ctx       context.Context // context of the running Execute, if any
+	parent    *Commander      // commander this one is mounted in, if any
+	mountName string          // name this commander is mounted under
+
+	groupOrder     []string            // groups to list first, in order
+	groupImportant map[string][]string // important top-level flags by group
+	importantText  map[string]string   // help text overrides for important flags
+	keepOrder      map[string]bool     // groups listed in registration order
+	keepAllOrder   bool                // list all groups in registration order
+	disabled       map[string]bool     // builtins disabled by DisableBuiltins
+	allowed        map[string]bool     // commands allowed by AllowCommands, or nil for all
+	denied         map[string]bool     // commands denied by DenyCommands
+
+	matcher     func(arg string, known []string) (string, bool) // set by SetMatcher
+	userAliases map[string][]string                             // loaded by LoadAliases
+	configFlag  string                                          // name of the flag defined by ConfigFlag
+	configPath  string                                          // value of the flag defined by ConfigFlag
+	dotEnv      map[string]string                               // variables read by LoadDotEnv
+	dotEnvFiles []string                                        // files named to LoadDotEnv
+	envBound    bool                                            // set by BindEnv
+	precedence  []Source                                        // set by SetPrecedence
+	flagParser  func(*flag.FlagSet, []string) error             // set by SetFlagParser
+	middleware  []Middleware                                    // added by Use
+	timeout     time.Duration                                   // value of the flag defined by TimeoutFlag
+	timeoutFlag string                                          // name of the flag defined by TimeoutFlag
+	timeouts    map[Command]time.Duration                       // set by RegisterWithTimeout
+	last        Result                                          // outcome of the last Execute or Run
+	notices     []Notice                                        // added by AddNotices
+	checks      []Check                                         // added by AddChecks
+	subscribers []func(Event)                                   // added by Subscribe
+	metrics     Metrics                                         // set by SetMetrics
+	silent      bool                                            // set by SetSilent or the flag defined by QuietFlag
+	helpHeader  *template.Template                              // set by SetHelpHeader
+	helpFooter  *template.Template                              // set by SetHelpFooter
+	banner      *template.Template                              // set by SetBanner
+	sharedFlags bool                                            // set by withWriters
+
+	flagErrorFunc func(Command, error) (string, ExitStatus) // set by SetFlagErrorFunc
+	crashHandler  func(CrashInfo)                           // set by SetCrashHandler
+	logHandler    func(Level, string)                       // set by SetLogHandler
+
+	preRun  []func(context.Context, Command) (context.Context, error) // added by PersistentPreRun
+	postRun []func(context.Context, Command, ExitStatus)              // added by PersistentPostRun
 
 	Explain        func(io.Writer)                // A function to print a top level usage explanation. Can be overridden.
 	ExplainGroup   func(io.Writer, *CommandGroup) // A function to print a command group's usage explanation. Can be overridden.
 	ExplainCommand func(io.Writer, Command)       // A function to print a command usage explanation. Can be overridden.
 
+	SynopsisFallback func(Command) string         // A function to derive a synopsis for commands whose Synopsis is empty (default: the first line of Usage). Can be overridden or set to nil.
+	EnvName          func(Command, string) string // A function to derive the environment variable bound by BindEnv to a flag of a command (nil for top-level flags). Can be overridden.
+
 	Output io.Writer // Output specifies where the commander should write its output (default: os.Stdout).
 	Error  io.Writer // Error specifies where the commander should write its error (default: os.Stderr).
+
+	// Warnings specifies where Logf writes deprecation and advisory
+	// warnings (default: nil, the Error of the commander).
+	Warnings io.Writer
+
+	UsageErrors UsageErrorMode // UsageErrors controls what is printed on a usage error (default: UsageFull).
+
+	// FlagErrorHandling is what Execute does after reporting that the
+	// flags of a subcommand failed to parse, as for a flag.FlagSet:
+	// return ExitUsageError (flag.ContinueOnError, the default), exit
+	// the program (flag.ExitOnError) or panic (flag.PanicOnError). A
+	// command implementing FlagErrorHandler overrides it. Whether the
+	// flag defaults are printed is controlled by UsageErrors.
+	FlagErrorHandling flag.ErrorHandling
+
+	// ChainSeparator, if not empty, separates subcommands that Execute
+	// runs in turn from a single command line, as in "tool fmt + vet +
+	// test" with a separator of "+". Execute stops at, and returns the
+	// status of, the first subcommand that does not succeed. The command
+	// line is split before flags are parsed, so an argument equal to the
+	// separator always separates. It cannot be "--", which ends the
+	// flags of a subcommand, and Execute panics if it is; use a separator
+	// such as "+" or ";" (quoted for the shell).
+	ChainSeparator string
+
+	// GracePeriod is how long Main waits for a subcommand to return
+	// after the context is canceled by SIGINT or SIGTERM, before giving
+	// up and exiting with ExitInterrupted (default: 0, until a second
+	// signal).
+	GracePeriod time.Duration
+
+	Style Style // Style controls the layout of group listings in help output.
+
+	SynopsisWidth    int    // SynopsisWidth limits the width of synopses in group listings (default: 0, unlimited).
+	SynopsisWrap     bool   // SynopsisWrap wraps synopses wider than SynopsisWidth onto indented lines instead of truncating them.
+	SynopsisEllipsis string // SynopsisEllipsis ends truncated synopses (default: "...").
+}
+
+// A Style controls the layout of the subcommand listings in help output,
+// so that it can be matched to an existing style guide without
+// replacing ExplainGroup.
+type Style struct {
+	Indent       string // Indent precedes each subcommand in a listing (default: "\t").
+	NameWidth    int    // NameWidth is the width names are padded to (default: 15).
+	Gutter       int    // Gutter is the number of spaces between name and synopsis (default: 2).
+	GroupSpacing int    // GroupSpacing is the number of blank lines after each group (default: 1).
 }
 
 // A CommandGroup represents a set of commands about a common topic.
 type CommandGroup struct {
 	name     string
 	commands []Command
+	load     func(Registrar) // registers the commands of a group added by RegisterGroupLazy, until called
+	declared []string        // names of the commands load registers, if declared to RegisterGroupLazy
 }
 
 // Name returns the group name
@@ -75,13 +197,76 @@ func (g *CommandGroup) Name() string {
 }
 
 // An ExitStatus represents a Posix exit status that a subcommand
-// expects to be returned to the shell.
+// expects to be returned to the shell. Besides the constants below, a
+// command may return any status made by ExitCode.
 type ExitStatus int
 
 const (
 	ExitSuccess ExitStatus = iota
 	ExitFailure
 	ExitUsageError
+
+	ExitTimeout     ExitStatus = 124 // The subcommand ran past its deadline, as set by TimeoutFlag or RegisterWithTimeout.
+	ExitCanceled    ExitStatus = 125 // The subcommand failed after its context was canceled.
+	ExitInterrupted ExitStatus = 130 // The subcommand was interrupted by a signal, or Main stopped waiting for it to return.
+)
+
+// ExitCode returns the ExitStatus for the exit code n, for commands whose
+// callers script against codes other than those of the ExitStatus
+// constants, such as 3 for partial success or 64 (EX_USAGE) from
+// sysexits.h. The status is returned by Execute and passed by Main to
+// os.Exit unchanged. ExitCode panics if n is not between 0 and 255.
+func ExitCode(n int) ExitStatus {
+	if n < 0 || n > 255 {
+		panic(fmt.Sprintf("subcommands: exit code %d out of range", n))
+	}
+	return ExitStatus(n)
+}
+
+// exitStatusNames holds the names of the ExitStatus constants.
+var exitStatusNames = map[ExitStatus]string{
+	ExitSuccess:     "success",
+	ExitFailure:     "failure",
+	ExitUsageError:  "usage error",
+	ExitTimeout:     "timeout",
+	ExitCanceled:    "canceled",
+	ExitInterrupted: "interrupted",
+}
+
+// String returns the name of the status, such as "usage error", or
+// "exit status N" for a status without a name.
+func (s ExitStatus) String() string {
+	if name, ok := exitStatusNames[s]; ok {
+		return name
+	}
+	return fmt.Sprintf("exit status %d", int(s))
+}
+
+// ParseExitStatus returns the ExitStatus named by s, which may be a name
+// returned by ExitStatus.String or a number.
+func ParseExitStatus(s string) (ExitStatus, error) {
+	for status, name := range exitStatusNames {
+		if s == name {
+			return status, nil
+		}
+	}
+	n, err := strconv.Atoi(strings.TrimPrefix(s, "exit status "))
+	if err != nil || n < 0 || n > 255 {
+		return 0, fmt.Errorf("invalid exit status %q", s)
+	}
+	return ExitStatus(n), nil
+}
+
+// A UsageErrorMode controls what a Commander prints when it is given
+// no subcommand, an unknown subcommand, or flags a subcommand cannot
+// parse.
+type UsageErrorMode int
+
+const (
+	UsageFull   UsageErrorMode = iota // Print the relevant usage explanation.
+	UsageLine                         // Print a one-line error message.
+	UsageSilent                       // Print nothing; only ExitUsageError is returned.
+	UsageHint                         // Print a one-line error and a pointer to the help subcommand, if registered.
 )
 
 // NewCommander returns a new commander with the specified top-level
@@ -93,11 +278,21 @@ func NewCommander(topLevelFlags *flag.FlagSet, name string) *Commander {
 		name:     name,
 		Output:   os.Stdout,
 		Error:    os.Stderr,
+
+		Style: Style{
+			Indent:       "\t",
+			NameWidth:    15,
+			Gutter:       2,
+			GroupSpacing: 1,
+		},
+		SynopsisEllipsis: "...",
 	}
 
 	cdr.Explain = cdr.explain
-	cdr.ExplainGroup = explainGroup
-	cdr.ExplainCommand = explain
+	cdr.ExplainGroup = cdr.explainGroup
+	cdr.ExplainCommand = cdr.explainCommand
+	cdr.EnvName = cdr.envName
+	cdr.SynopsisFallback = usageSynopsis
 	topLevelFlags.Usage = func() { cdr.Explain(cdr.Error) }
 	return cdr
 }
@@ -107,11 +302,50 @@ func (cdr *Commander) Name() string {
 	return cdr.name
 }
 
+// SetDisplayName sets the name the commander uses for itself in help
+// and error output, in place of the name it was created with.
+func (cdr *Commander) SetDisplayName(name string) {
+	cdr.display = name
+}
+
+// DisplayName returns the name the commander uses for itself in help
+// and error output: the name set by SetDisplayName, or else its name,
+// or if it is mounted in another commander, the name it is mounted
+// under there.
+func (cdr *Commander) DisplayName() string {
+	if cdr.display != "" {
+		return cdr.display
+	}
+	if cdr.parent != nil {
+		return cdr.parent.DisplayName() + " " + cdr.mountName
+	}
+	return cdr.name
+}
+
 // Register adds a subcommand to the supported subcommands in the
 // specified group. (Help output is sorted and arranged by group name.)
 // The empty string is an acceptable group name; such subcommands are
-// explained first before named groups.
+// explained first before named groups. A command with the same name as
+// a builtin from HelpCommand, FlagsCommand, CommandsCommand,
+// AliasesCommand, ConfigCommand, BatchCommand, CompletionCommand,
+// CompleteCommand, SearchCommand, TreeCommand, VersionCommand,
+// LicensesCommand, EnvCommand or DoctorCommand replaces the builtin,
+// whichever is registered first.
 func (cdr *Commander) Register(cmd Command, group string) {
+	if isBuiltin(cmd) && cdr.disabled[cmd.Name()] {
+		return
+	}
+	if cdr.restricted(cmd) {
+		return
+	}
+	if old := cdr.lookup(cmd.Name()); old != nil {
+		switch {
+		case isBuiltin(cmd) && !isBuiltin(old):
+			return
+		case isBuiltin(old) && !isBuiltin(cmd):
+			cdr.unregister(old)
+		}
+	}
 	for _, g := range cdr.commands {
 		if g.name == group {
 			g.commands = append(g.commands, cmd)
@@ -124,6 +358,143 @@ func (cdr *Commander) Register(cmd Command, group string) {
 	})
 }
 
+// unregister removes cmd from the supported subcommands.
+func (cdr *Commander) unregister(cmd Command) {
+	for _, g := range cdr.commands {
+		for i, c := range g.commands {
+			if c == cmd {
+				g.commands = append(g.commands[:i:i], g.commands[i+1:]...)
+				delete(cdr.timeouts, cmd)
+				return
+			}
+		}
+	}
+}
+
+// DisableBuiltins removes the named builtins ("help", "flags",
+// "commands", "alias", "config", "batch", "completion", "__complete",
+// "search", "tree", "version", "licenses", "env" or "doctor"; all of
+// them if no names are given) from the commander, ignores any later
+// registration of them, and stops help and error output from referring
+// to them.
+func (cdr *Commander) DisableBuiltins(names ...string) {
+	if len(names) == 0 {
+		names = []string{"help", "flags", "commands", "alias", "config", "batch", "completion", "__complete", "search", "tree", "version", "licenses", "env", "doctor"}
+	}
+	if cdr.disabled == nil {
+		cdr.disabled = make(map[string]bool)
+	}
+	for _, name := range names {
+		cdr.disabled[name] = true
+		if cmd := cdr.lookup(name); cmd != nil && isBuiltin(cmd) {
+			cdr.unregister(cmd)
+		}
+	}
+}
+
+// AllowCommands restricts the commander to the named commands: all
+// others, registered before or after, are removed from it, so that
+// they can neither be executed nor seen in help output. Calling
+// AllowCommands again extends the list. The builtin commands are not
+// restricted; see DisableBuiltins. A command registered with Alias is
+// allowed if either its alias or the name of the aliased command is.
+//
+// Together with DenyCommands, it lets one binary serve several
+// privilege tiers, with the commands of each tier chosen at startup:
+//
+//	if os.Getenv("TOOL_TIER") == "readonly" {
+//		cdr.AllowCommands("get", "list", "status")
+//	}
+func (cdr *Commander) AllowCommands(names ...string) {
+	if cdr.allowed == nil {
+		cdr.allowed = make(map[string]bool)
+	}
+	for _, name := range names {
+		cdr.allowed[name] = true
+	}
+	cdr.removeRestricted()
+}
+
+// DenyCommands removes the named commands, registered before or after,
+// from the commander, so that they can neither be executed nor seen in
+// help output. It takes precedence over AllowCommands. A command
+// registered with Alias is denied if either its alias or the name of the
+// aliased command is.
+func (cdr *Commander) DenyCommands(names ...string) {
+	if cdr.denied == nil {
+		cdr.denied = make(map[string]bool)
+	}
+	for _, name := range names {
+		cdr.denied[name] = true
+	}
+	cdr.removeRestricted()
+}
+
+// restricted reports whether cmd is excluded by AllowCommands or
+// DenyCommands.
+func (cdr *Commander) restricted(cmd Command) bool {
+	if isBuiltin(cmd) {
+		return false
+	}
+	name, orig := cmd.Name(), dealias(cmd).Name()
+	if cdr.denied[name] || cdr.denied[orig] {
+		return true
+	}
+	return cdr.allowed != nil && !cdr.allowed[name] && !cdr.allowed[orig]
+}
+
+// removeRestricted unregisters the commands excluded by AllowCommands or
+// DenyCommands.
+func (cdr *Commander) removeRestricted() {
+	for _, g := range cdr.commands {
+		kept := g.commands[:0]
+		for _, cmd := range g.commands {
+			if !cdr.restricted(cmd) {
+				kept = append(kept, cmd)
+			}
+		}
+		g.commands = kept
+	}
+}
+
+// isBuiltin reports whether cmd is one of the builtin commands.
+func isBuiltin(cmd Command) bool {
+	switch cmd.(type) {
+	case *helper, *flagger, *lister, *aliasLister, *configShower, *batcher, *completer, *completionScripter, *searcher, *treePrinter, *versioner, *licenser, *envPrinter, *doctor:
+		return true
+	}
+	return false
+}
+
+// Lookup returns the registered subcommand with the given name, or nil
+// if there is none. If there is none, the groups added by
+// RegisterGroupLazy that may register it, as described there, are
+// loaded, and searched too.
+func (cdr *Commander) Lookup(name string) Command {
+	if cmd := cdr.lookup(name); cmd != nil || !cdr.loadGroupsFor(name) {
+		return cmd
+	}
+	return cdr.lookup(name)
+}
+
+// lookup returns the registered subcommand named name, without loading
+// the groups added by RegisterGroupLazy, or nil if there is none.
+func (cdr *Commander) lookup(name string) Command {
+	for _, group := range cdr.commands {
+		for _, cmd := range group.commands {
+			if cmd.Name() == name {
+				return cmd
+			}
+		}
+	}
+	return nil
+}
+
+// TopFlags returns the top-level flags of the commander.
+func (cdr *Commander) TopFlags() *flag.FlagSet {
+	return cdr.topFlags
+}
+
 // ImportantFlag marks a top-level flag as important, which means it
 // will be printed out as part of the output of an ordinary "help"
 // subcommand.  (All flags, important or not, are printed by the
@@ -132,18 +503,82 @@ func (cdr *Commander) ImportantFlag(name string) {
 	cdr.important = append(cdr.important, name)
 }
 
-// VisitGroups visits each command group in lexicographical order, calling
-// fn for each.
+// SetGroupOrder sets the order in which the named command groups are
+// listed in help output, ahead of any other groups, which follow in
+// lexicographical order. The empty string names the unnamed group,
+// which is otherwise listed first.
+func (cdr *Commander) SetGroupOrder(groups ...string) {
+	cdr.groupOrder = groups
+}
+
+// ImportantFlagf marks a top-level flag as important, like
+// ImportantFlag, and sets the line that describes it in the output of
+// an ordinary "help" subcommand, formatted according to format and
+// args. This is useful when the flag's own usage is too detailed for a
+// summary, as in:
+//
+//	cdr.ImportantFlagf("project", "-project=ID  target project")
+func (cdr *Commander) ImportantFlagf(name, format string, args ...interface{}) {
+	if cdr.importantText == nil {
+		cdr.importantText = make(map[string]string)
+	}
+	cdr.importantText[name] = fmt.Sprintf(format, args...)
+	cdr.ImportantFlag(name)
+}
+
+// ImportantFlagForGroup marks a top-level flag as important to the
+// named group, which means it will be printed out alongside that
+// group's subcommands in the output of an ordinary "help" subcommand,
+// rather than with the important flags of the whole command.
+func (cdr *Commander) ImportantFlagForGroup(group, name string) {
+	if cdr.groupImportant == nil {
+		cdr.groupImportant = make(map[string][]string)
+	}
+	cdr.groupImportant[group] = append(cdr.groupImportant[group], name)
+}
+
+// KeepRegistrationOrder lists the subcommands of the named groups in
+// help output in the order they were registered, rather than in
+// lexicographical order. With no arguments, it applies to all groups.
+func (cdr *Commander) KeepRegistrationOrder(groups ...string) {
+	if len(groups) == 0 {
+		cdr.keepAllOrder = true
+		return
+	}
+	if cdr.keepOrder == nil {
+		cdr.keepOrder = make(map[string]bool)
+	}
+	for _, g := range groups {
+		cdr.keepOrder[g] = true
+	}
+}
+
+// HandleHelpFlags defines -h and -help (also accepted as --help) on the
+// top-level flags, so that "-h" behaves like the "help" subcommand and
+// "-help <subcommand>" like "help <subcommand>", however the top-level
+// flag set handles errors. Names the top-level flags already define are
+// left alone. It must be called before the top-level flags are parsed.
+func (cdr *Commander) HandleHelpFlags() {
+	for _, name := range []string{"h", "help"} {
+		if cdr.topFlags.Lookup(name) == nil {
+			cdr.topFlags.BoolVar(&cdr.helpFlag, name, false, "show help")
+		}
+	}
+}
+
+// VisitGroups visits each command group in the order set by
+// SetGroupOrder, then in lexicographical order, calling fn for each.
 func (cdr *Commander) VisitGroups(fn func(*CommandGroup)) {
-	sort.Sort(byGroupName(cdr.commands))
+	cdr.sortGroups()
 	for _, g := range cdr.commands {
 		fn(g)
 	}
 }
 
 // VisitCommands visits each command in registered order grouped by
-// command group in lexicographical order, calling fn for each.
+// command group in the order used by VisitGroups, calling fn for each.
 func (cdr *Commander) VisitCommands(fn func(*CommandGroup, Command)) {
+	cdr.loadGroups()
 	cdr.VisitGroups(func(g *CommandGroup) {
 		for _, cmd := range g.commands {
 			fn(g, cmd)
@@ -156,11 +591,7 @@ func (cdr *Commander) VisitCommands(fn func(*CommandGroup, Command)) {
 func (cdr *Commander) VisitAllImportant(fn func(*flag.Flag)) {
 	sort.Strings(cdr.important)
 	for _, name := range cdr.important {
-		f := cdr.topFlags.Lookup(name)
-		if f == nil {
-			panic(fmt.Sprintf("Important flag (%s) is not defined", name))
-		}
-		fn(f)
+		fn(cdr.lookupImportant(name))
 	}
 }
 
@@ -184,50 +615,447 @@ func (cdr *Commander) countTopFlags() int {
 // Execute should be called once the top-level-flags on a Commander
 // have been initialized. It finds the correct subcommand and executes
 // it, and returns an ExitStatus with the result. On a usage error, an
-// appropriate message is printed to os.Stderr, and ExitUsageError is
-// returned. The additional args are provided as-is to the Execute method
-// of the selected Command.
+// appropriate message is printed to cdr.Error as selected by
+// cdr.UsageErrors, and ExitUsageError is returned. The additional args
+// are provided as-is to the Execute method of the selected Command.
 func (cdr *Commander) Execute(ctx context.Context, args ...interface{}) ExitStatus {
+	defer func(prev context.Context) { cdr.ctx = prev }(cdr.ctx)
+	cdr.ctx = ctx
+	cdr.last = Result{}
+
+	if cdr.helpFlag {
+		return cdr.help(cdr.topFlags.Args(), cdr.topFlags.Usage)
+	}
+
 	if cdr.topFlags.NArg() < 1 {
-		cdr.topFlags.Usage()
-		return ExitUsageError
+		return cdr.usageError(cdr.topFlags.Usage, NoCommand, "no subcommand given")
 	}
 
-	name := cdr.topFlags.Arg(0)
+	chain := cdr.splitChain(cdr.topFlags.Args())
+	for _, argv := range chain[:len(chain)-1] {
+		if status := cdr.dispatch(ctx, argv, args...); status != ExitSuccess {
+			return status
+		}
+	}
+	return cdr.dispatch(ctx, chain[len(chain)-1], args...)
+}
 
+// ExecuteE is like Execute, but also returns LastError.
+func (cdr *Commander) ExecuteE(ctx context.Context, args ...interface{}) (ExitStatus, error) {
+	status := cdr.Execute(ctx, args...)
+	return status, cdr.last.Err
+}
+
+// LastError returns the error that made the last call to Execute or Run
+// fail before or after the subcommand itself ran, such as an unknown
+// subcommand or a flag that failed to parse ("invalid value "x" for
+// flag -n: ..."), so that callers can log or translate the cause of a
+// usage error. It returns nil if there was no such error, including when
+// the subcommand returned a failure of its own.
+func (cdr *Commander) LastError() error {
+	return cdr.last.Err
+}
+
+// Reset returns cdr to the state it was in before its top-level flags
+// were parsed, so that tests and interactive programs can parse them and
+// call Execute again with other arguments: the top-level flags are set
+// to their defaults and marked unset, the arguments left by parsing them
+// are dropped, and LastError and ExecuteResult forget the last call. The
+// Commanders mounted in cdr are reset too. The fields commands keep
+// their flags in are not reset; see Cloner.
+//
+// A flag is set to its default by the Reset method of its flag.Value, if
+// it has one, or else by setting its default string. Values that
+// accumulate, such as lists and counters, or whose Set rejects their
+// default, need a Reset method; Reset returns an error naming each flag
+// it could not reset.
+func (cdr *Commander) Reset() error {
+	var errs []error
+	if f := cdr.topFlags; f != nil {
+		fresh := flag.NewFlagSet(f.Name(), f.ErrorHandling())
+		fresh.SetOutput(f.Output())
+		fresh.Usage = f.Usage
+		f.VisitAll(func(fl *flag.Flag) {
+			if err := resetFlag(fl); err != nil {
+				errs = append(errs, fmt.Errorf("cannot reset flag -%s: %v", fl.Name, err))
+			}
+			fresh.Var(fl.Value, fl.Name, fl.Usage)
+			fresh.Lookup(fl.Name).DefValue = fl.DefValue
+		})
+		*f = *fresh
+		provenance.Lock()
+		delete(provenance.m, f)
+		provenance.Unlock()
+	}
+	cdr.last = Result{}
 	for _, group := range cdr.commands {
 		for _, cmd := range group.commands {
-			if name != cmd.Name() {
-				continue
+			if m := mounted(cmd); m != nil {
+				errs = append(errs, m.Reset())
+			}
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// resetFlag sets fl to its default, by the Reset method of its value if
+// it has one.
+func resetFlag(fl *flag.Flag) error {
+	if r, ok := fl.Value.(interface{ Reset() }); ok {
+		r.Reset()
+		return nil
+	}
+	if fl.Value.String() == fl.DefValue {
+		return nil
+	}
+	if err := fl.Value.Set(fl.DefValue); err != nil {
+		return err
+	}
+	if v := fl.Value.String(); v != fl.DefValue {
+		return fmt.Errorf("value is %q after setting the default %q; its value needs a Reset method", v, fl.DefValue)
+	}
+	return nil
+}
+
+// dispatch executes the subcommand named by argv[0] with the rest of
+// argv.
+func (cdr *Commander) dispatch(ctx context.Context, argv []string, args ...interface{}) ExitStatus {
+	cdr.last = Result{}
+	if len(argv) == 0 {
+		return cdr.usageError(cdr.topUsage(), NoCommand, "no subcommand given")
+	}
+	argv = cdr.expandAlias(argv)
+	name := argv[0]
+	cmd := cdr.resolve(name)
+	if cmd == nil {
+		return cdr.usageError(cdr.topUsage(), UnknownCommand, fmt.Sprintf("unknown subcommand %q", name))
+	}
+	return cdr.execute(ctx, cmd, argv[1:], args...)
+}
+
+// splitChain splits argv into the command lines separated by
+// cdr.ChainSeparator. It returns argv alone if there is no separator.
+func (cdr *Commander) splitChain(argv []string) [][]string {
+	switch cdr.ChainSeparator {
+	case "":
+		return [][]string{argv}
+	case "--":
+		panic(`subcommands: ChainSeparator "--" would split the flag terminator`)
+	}
+	var chain [][]string
+	start := 0
+	for i, arg := range argv {
+		if arg == cdr.ChainSeparator {
+			chain = append(chain, argv[start:i])
+			start = i + 1
+		}
+	}
+	return append(chain, argv[start:])
+}
+
+// Run executes the registered subcommand with the given name, parsing
+// its flags from argv as Execute would, and returns its ExitStatus. The
+// extra args are provided as-is to the Execute method of the command.
+// It lets one command invoke another, as when a "deploy" command runs
+// "build" first, without duplicating its logic.
+func (cdr *Commander) Run(ctx context.Context, name string, argv []string, args ...interface{}) ExitStatus {
+	cdr.last = Result{}
+	cmd := cdr.resolve(name)
+	if cmd == nil {
+		cdr.last.Reason, cdr.last.Err = UnknownCommand, fmt.Errorf("unknown subcommand %q", name)
+		cdr.Errorf("%v", cdr.last.Err)
+		return ExitUsageError
+	}
+	return cdr.execute(ctx, cmd, argv, args...)
+}
+
+// execute parses the flags of cmd from argv and executes it.
+func (cdr *Commander) execute(ctx context.Context, cmd Command, argv []string, args ...interface{}) (status ExitStatus) {
+	if cdr.metrics != nil {
+		status = ExitFailure // as counted if the command panics
+		defer cdr.countInvocation(cmd, &status)
+	}
+	if cdr.crashHandler != nil {
+		defer cdr.recoverCrash(cmd, argv, &status)
+	}
+	return cdr.executeCommand(ctx, cmd, argv, args...)
+}
+
+// executeCommand implements execute.
+func (cdr *Commander) executeCommand(ctx context.Context, cmd Command, argv []string, args ...interface{}) ExitStatus {
+	cdr.last.Command, cdr.last.Group = cmd.Name(), cdr.groupOf(cmd)
+	cdr.emit(Event{Kind: CommandResolved, Command: cmd.Name()})
+	limit := cdr.timeoutOf(cmd)
+	cmd = clone(cmd)
+	f := flag.NewFlagSet(cmd.Name(), flag.ContinueOnError)
+	f.SetOutput(cdr.Error)
+	f.Usage = func() { cdr.ExplainCommand(cdr.Error, cmd) }
+	if cdr.usageErrors() != UsageFull || cdr.flagErrorFunc != nil {
+		f.SetOutput(io.Discard)
+		f.Usage = func() {}
+	}
+	ctx = withInvocation(ctx, cdr, cmd, cdr.last.Group)
+	if err := initialize(ctx, cmd); err != nil {
+		cdr.last.Reason, cdr.last.Err = CommandError, err
+		cdr.Errorf("%s: %v", cmd.Name(), err)
+		return ExitFailure
+	}
+	cmd.SetFlags(f)
+	defer forget(f)
+	markSecretFlags(cmd, f)
+	if err := cdr.applyDefaults(cmd, f); err != nil {
+		cdr.last.Reason, cdr.last.Err = FlagError, err
+		cdr.Errorf("%v", err)
+		return ExitUsageError
+	}
+	if err := parse(f, argv, cdr.flagParser); err != nil {
+		return cdr.rejectFlags(cmd, err)
+	}
+	if err := cdr.applyOverrides(cmd, f); err != nil {
+		cdr.last.Reason, cdr.last.Err = FlagError, err
+		cdr.Errorf("%v", err)
+		return ExitUsageError
+	}
+	if err := applyLazyDefaults(f); err != nil {
+		cdr.last.Reason, cdr.last.Err = FlagError, err
+		cdr.Errorf("%s: %v", cmd.Name(), err)
+		return ExitFailure
+	}
+	if err := validate(cmd, f); err != nil {
+		// Report err as the flag package reports a parse error.
+		fmt.Fprintln(f.Output(), err)
+		f.Usage()
+		return cdr.rejectFlags(cmd, err)
+	}
+	if err := checkArgs(cmd, f.Args()); err != nil {
+		fmt.Fprintln(f.Output(), err)
+		f.Usage()
+		status := cdr.rejectFlags(cmd, err)
+		cdr.last.Reason = ArgError
+		return status
+	}
+	cdr.emit(Event{Kind: FlagsParsed, Command: cmd.Name(), Flags: f, Args: f.Args()})
+	limit = cdr.timeLimit(limit)
+	ctx, cancel, expired := withTimeout(ctx, limit)
+	defer cancel()
+	status := ExitFailure // as reported to CleanUp, Metrics and subscribers if the command panics
+	defer cleanUp(ctx, cmd, &status)
+	start := time.Now()
+	cdr.emit(Event{Kind: CommandStarted, Command: cmd.Name(), Time: start, Flags: f, Args: f.Args()})
+	defer cdr.finished(cmd, f, start, &status)
+	status = cdr.chain(cmd)(ctx, cmd, f, args...)
+	switch {
+	case expired():
+		status = cdr.timedOut(cmd, limit)
+	case status != ExitSuccess && ctx.Err() != nil:
+		status = cdr.canceled(ctx, cmd)
+	case status != ExitSuccess:
+		cdr.last.Reason = CommandError
+	}
+	return status
+}
+
+// resolve returns the subcommand selected by name, as matched by the
+// function set by SetMatcher if there is one, or nil if there is none.
+func (cdr *Commander) resolve(name string) Command {
+	if cdr.matcher != nil {
+		var known []string
+		for _, group := range cdr.commands {
+			if group.load != nil && len(group.declared) == 0 {
+				cdr.loadGroup(group)
 			}
-			f := flag.NewFlagSet(name, flag.ContinueOnError)
-			f.Usage = func() { cdr.ExplainCommand(cdr.Error, cmd) }
-			cmd.SetFlags(f)
-			if f.Parse(cdr.topFlags.Args()[1:]) != nil {
-				return ExitUsageError
+			for _, cmd := range group.commands {
+				known = append(known, cmd.Name())
 			}
-			return cmd.Execute(ctx, f, args...)
+			known = append(known, group.declared...)
 		}
+		matched, ok := cdr.matcher(name, known)
+		if !ok {
+			return nil
+		}
+		name = matched
 	}
+	return cdr.Lookup(name)
+}
 
-	// Cannot find this command.
-	cdr.topFlags.Usage()
+// SetMatcher sets the function Execute uses to select a subcommand.
+// Given the subcommand argument and the names of all registered
+// subcommands, match returns the name of the subcommand to execute, or
+// false if there is none. This allows custom routing rules, such as
+// normalizing case or accepting versioned names like "deploy@v2",
+// while keeping the rest of Execute. A nil match restores the default
+// of exact name matching.
+func (cdr *Commander) SetMatcher(match func(arg string, known []string) (string, bool)) {
+	cdr.matcher = match
+}
+
+// SetFlagParser sets the function used to parse the flags of each
+// subcommand from its arguments in place of flag.FlagSet.Parse, for
+// example to also fill them from the environment or a config file with
+// a package such as github.com/peterbourgon/ff:
+//
+//	cdr.SetFlagParser(func(f *flag.FlagSet, args []string) error {
+//		return ff.Parse(f, args, ff.WithEnvVarPrefix("TOOL"))
+//	})
+//
+// The function must leave the remaining arguments in f.Args(). Flags it
+// sets are reported by Provenance as set from the command line. A nil
+// parse restores flag.FlagSet.Parse.
+func (cdr *Commander) SetFlagParser(parse func(f *flag.FlagSet, args []string) error) {
+	cdr.flagParser = parse
+}
+
+// SetFlagErrorFunc sets a function that reports the failure of the flags
+// of a subcommand to parse, in place of the message of the flag package
+// and the usage selected by UsageErrors. It is given the command and the
+// parse error, which is flag.ErrHelp for -h and -help, and returns the
+// message to report with Errorf, or "" to report nothing, and the status
+// to return. For example, to translate the messages:
+//
+//	cdr.SetFlagErrorFunc(func(cmd subcommands.Command, err error) (string, subcommands.ExitStatus) {
+//		return fmt.Sprintf("%s: %s", cmd.Name(), translate(err)), subcommands.ExitUsageError
+//	})
+//
+// A nil fn restores the default behavior.
+func (cdr *Commander) SetFlagErrorFunc(fn func(cmd Command, err error) (string, ExitStatus)) {
+	cdr.flagErrorFunc = fn
+}
+
+// A FlagErrorHandler is a Command that chooses what happens when its
+// flags fail to parse, in place of the FlagErrorHandling of the
+// Commander.
+type FlagErrorHandler interface {
+	// FlagErrorHandling returns flag.ContinueOnError, flag.ExitOnError
+	// or flag.PanicOnError.
+	FlagErrorHandling() flag.ErrorHandling
+}
+
+// flagErrorHandling returns what to do when the flags of cmd fail to
+// parse.
+func (cdr *Commander) flagErrorHandling(cmd Command) flag.ErrorHandling {
+	if h, ok := dealias(cmd).(FlagErrorHandler); ok {
+		return h.FlagErrorHandling()
+	}
+	return cdr.FlagErrorHandling
+}
+
+// rejectFlags reports err, a failure of the flags of cmd to parse or
+// validate, and handles it as selected by cdr.flagErrorHandling.
+func (cdr *Commander) rejectFlags(cmd Command, err error) ExitStatus {
+	status := cdr.flagError(cmd, err)
+	switch cdr.flagErrorHandling(cmd) {
+	case flag.ExitOnError:
+		if err == flag.ErrHelp {
+			os.Exit(0)
+		}
+		os.Exit(int(status))
+	case flag.PanicOnError:
+		panic(err)
+	}
+	return status
+}
+
+// flagError reports a failure to parse the flags of cmd as selected by
+// cdr.UsageErrors, followed by a pointer to the command's help when a
+// help subcommand is registered and the Commander is not silent, and
+// returns ExitUsageError.
+func (cdr *Commander) flagError(cmd Command, err error) ExitStatus {
+	cdr.last.Reason, cdr.last.Err = FlagError, err
+	if cdr.flagErrorFunc != nil {
+		msg, status := cdr.flagErrorFunc(cmd, err)
+		if msg != "" {
+			cdr.Errorf("%s", msg)
+		}
+		return status
+	}
+	switch cdr.usageErrors() {
+	case UsageSilent:
+		return ExitUsageError
+	case UsageFull:
+		// The flag package has already printed err and the usage.
+	default:
+		if err == flag.ErrHelp {
+			cdr.ExplainCommand(cdr.Error, cmd)
+			return ExitUsageError
+		}
+		for _, line := range strings.Split(err.Error(), "\n") {
+			cdr.Errorf("%s: %s", cmd.Name(), line)
+		}
+	}
+	if err != flag.ErrHelp && cdr.lookup("help") != nil && !cdr.silent {
+		cdr.Infof("see '%s help %s'", cdr.DisplayName(), cmd.Name())
+	}
 	return ExitUsageError
 }
 
-// Sorting of a slice of command groups.
-type byGroupName []*CommandGroup
+// usageError reports a usage error for reason as selected by
+// cdr.UsageErrors and returns ExitUsageError. In UsageFull mode explain
+// is called to print the usage; in UsageLine and UsageHint modes msg is
+// printed instead.
+func (cdr *Commander) usageError(explain func(), reason Reason, msg string) ExitStatus {
+	cdr.last.Reason, cdr.last.Err = reason, errors.New(msg)
+	switch cdr.usageErrors() {
+	case UsageFull:
+		explain()
+	case UsageLine:
+		cdr.Errorf("%s", msg)
+	case UsageHint:
+		cdr.Errorf("%s", msg)
+		if cdr.lookup("help") != nil {
+			cdr.Infof("run '%s help' for usage", cdr.DisplayName())
+		}
+	}
+	return ExitUsageError
+}
 
-// TODO Sort by function rather than implement sortable?
-func (p byGroupName) Len() int           { return len(p) }
-func (p byGroupName) Less(i, j int) bool { return p[i].name < p[j].name }
-func (p byGroupName) Swap(i, j int)      { p[i], p[j] = p[j], p[i] }
+// sortGroups sorts the command groups into the order set by
+// SetGroupOrder, followed by the remaining groups in lexicographical
+// order. The unnamed group comes first unless SetGroupOrder names it.
+func (cdr *Commander) sortGroups() {
+	rank := func(name string) int {
+		for i, n := range cdr.groupOrder {
+			if n == name {
+				return i
+			}
+		}
+		if name == "" {
+			return -1
+		}
+		return len(cdr.groupOrder)
+	}
+	sort.SliceStable(cdr.commands, func(i, j int) bool {
+		ri, rj := rank(cdr.commands[i].name), rank(cdr.commands[j].name)
+		if ri != rj {
+			return ri < rj
+		}
+		return cdr.commands[i].name < cdr.commands[j].name
+	})
+}
 
 // explain prints a brief description of all the subcommands and the
 // important top-level flags.
 func (cdr *Commander) explain(w io.Writer) {
-	fmt.Fprintf(w, "Usage: %s <flags> <subcommand> <subcommand args>\n\n", cdr.name)
-	sort.Sort(byGroupName(cdr.commands))
+	buf := getBuffer()
+	defer putBuffer(buf, w)
+	tty := IsTerminal(w)
+	w = buf
+
+	if cdr.banner != nil && tty {
+		cdr.printHelpText(w, cdr.banner)
+		fmt.Fprintln(w)
+	}
+	if cdr.helpHeader != nil {
+		cdr.printHelpText(w, cdr.helpHeader)
+		fmt.Fprintln(w)
+	}
+	if cdr.helpFooter != nil {
+		defer func() {
+			fmt.Fprintln(w)
+			cdr.printHelpText(w, cdr.helpFooter)
+		}()
+	}
+	fmt.Fprintf(w, "Usage: %s <flags> <subcommand> <subcommand args>\n\n", cdr.DisplayName())
+	cdr.sortGroups()
 	for _, group := range cdr.commands {
 		cdr.ExplainGroup(w, group)
 	}
@@ -238,20 +1066,47 @@ func (cdr *Commander) explain(w io.Writer) {
 
 	sort.Strings(cdr.important)
 	if len(cdr.important) == 0 {
-		if cdr.countTopFlags() > 0 {
-			fmt.Fprintf(w, "\nUse \"%s flags\" for a list of top-level flags\n", cdr.name)
+		if cdr.countTopFlags() > 0 && !cdr.disabled["flags"] {
+			fmt.Fprintf(w, "\nUse \"%s flags\" for a list of top-level flags\n", cdr.DisplayName())
 		}
 		return
 	}
 
-	fmt.Fprintf(w, "\nTop-level flags (use \"%s flags\" for a full list):\n", cdr.name)
+	if cdr.disabled["flags"] {
+		fmt.Fprintf(w, "\nTop-level flags:\n")
+	} else {
+		fmt.Fprintf(w, "\nTop-level flags (use \"%s flags\" for a full list):\n", cdr.DisplayName())
+	}
 	for _, name := range cdr.important {
-		f := cdr.topFlags.Lookup(name)
-		if f == nil {
-			panic(fmt.Sprintf("Important flag (%s) is not defined", name))
-		}
-		fmt.Fprintf(w, "  -%s=%s: %s\n", f.Name, f.DefValue, f.Usage)
+		cdr.explainFlag(w, cdr.lookupImportant(name))
+	}
+}
+
+// lookupImportant returns the top-level flag marked important under the
+// given name. It panics if there is no such flag.
+func (cdr *Commander) lookupImportant(name string) *flag.Flag {
+	f := cdr.topFlags.Lookup(name)
+	if f == nil {
+		panic(fmt.Sprintf("Important flag (%s) is not defined", name))
+	}
+	return f
+}
+
+// explainFlag prints a one-line description of an important flag,
+// using the text set by ImportantFlagf if there is any.
+func (cdr *Commander) explainFlag(w io.Writer, f *flag.Flag) {
+	if text, ok := cdr.importantText[f.Name]; ok {
+		fmt.Fprintf(w, "  %s\n", text)
+		return
+	}
+	def := f.DefValue
+	if def != "" {
+		def = shown(cdr.topFlags, f.Name, def)
+	}
+	if text, ok := defaultText(cdr.topFlags, f.Name); ok {
+		def = text
 	}
+	fmt.Fprintf(w, "  -%s=%s: %s\n", f.Name, def, f.Usage)
 }
 
 // Sorting of the commands within a group.
@@ -259,54 +1114,290 @@ func (g CommandGroup) Len() int           { return len(g.commands) }
 func (g CommandGroup) Less(i, j int) bool { return g.commands[i].Name() < g.commands[j].Name() }
 func (g CommandGroup) Swap(i, j int)      { g.commands[i], g.commands[j] = g.commands[j], g.commands[i] }
 
-// explainGroup explains all the subcommands for a particular group.
-func explainGroup(w io.Writer, group *CommandGroup) {
-	if len(group.commands) == 0 {
+// explainGroup explains all the subcommands for a particular group and
+// the top-level flags marked important for it.
+func (cdr *Commander) explainGroup(w io.Writer, group *CommandGroup) {
+	if group.load != nil {
+		explainGroupHeader(w, group)
+		fmt.Fprintf(w, "%sRun '%s help %s' for a list.\n", cdr.Style.Indent, cdr.DisplayName(), group.name)
+		fmt.Fprint(w, strings.Repeat("\n", cdr.Style.GroupSpacing))
+		return
+	}
+	cmds := cdr.listOrder(group)
+	if len(cmds) == 0 {
+		return
+	}
+	buf := getBuffer()
+	defer putBuffer(buf, w)
+	explainGroupHeader(buf, group)
+
+	// The columns are the same for every command, so the padding is
+	// computed once, and each line is written straight into buf.
+	st := cdr.Style
+	gutter := strings.Repeat(" ", st.Gutter)
+	continuation := st.Indent + strings.Repeat(" ", st.NameWidth+st.Gutter)
+	aliases := groupAliases(group)
+	for _, cmd := range cmds {
+		if _, ok := cmd.(*aliaser); ok {
+			continue
+		}
+
+		name := cmd.Name()
+		buf.WriteString(st.Indent)
+		buf.WriteString(name)
+		width := textwidth.String(name)
+		for _, alias := range aliases[name] {
+			buf.WriteString(", ")
+			buf.WriteString(alias)
+			width += 2 + textwidth.String(alias)
+		}
+		for ; width < st.NameWidth; width++ {
+			buf.WriteByte(' ')
+		}
+		buf.WriteString(gutter)
+		buf.WriteString(cdr.fitSynopsis(cdr.listingSynopsis(cmd), continuation))
+		buf.WriteByte('\n')
+	}
+	cdr.explainGroupFlags(buf, group)
+	for i := 0; i < st.GroupSpacing; i++ {
+		buf.WriteByte('\n')
+	}
+}
+
+// explainBuffers holds the buffers help is rendered into, to be written
+// in one piece.
+var explainBuffers = sync.Pool{New: func() interface{} { return new(bytes.Buffer) }}
+
+// getBuffer returns an empty buffer from explainBuffers.
+func getBuffer() *bytes.Buffer {
+	buf := explainBuffers.Get().(*bytes.Buffer)
+	buf.Reset()
+	return buf
+}
+
+// putBuffer writes the contents of buf to w and returns buf to
+// explainBuffers, unless it has grown too large to keep.
+func putBuffer(buf *bytes.Buffer, w io.Writer) {
+	w.Write(buf.Bytes())
+	if buf.Cap() <= 64<<10 {
+		explainBuffers.Put(buf)
+	}
+}
+
+// explainGroupDetail explains the subcommands for a particular group at
+// more length than explainGroup, adding the first line of each
+// subcommand's usage.
+func (cdr *Commander) explainGroupDetail(w io.Writer, group *CommandGroup) {
+	explainGroupHeader(w, group)
+
+	aliases := groupAliases(group)
+	for _, cmd := range cdr.listOrder(group) {
+		if _, ok := cmd.(*aliaser); ok {
+			continue
+		}
+
+		name := cmd.Name()
+		if a, ok := aliases[name]; ok {
+			name += " (aliases: " + strings.Join(a, ", ") + ")"
+		}
+		indent := cdr.Style.Indent
+		fmt.Fprintf(w, "%s%s\n%s%s\n", indent, name, indent+indent, cdr.fitSynopsis(cdr.listingSynopsis(cmd), indent+indent))
+		if line := firstLine(usage(cmd)); line != "" {
+			fmt.Fprintf(w, "%sUsage: %s\n", indent+indent, strings.TrimSuffix(line, ":"))
+		}
+	}
+	cdr.explainGroupFlags(w, group)
+	fmt.Fprint(w, strings.Repeat("\n", cdr.Style.GroupSpacing))
+}
+
+// explainGroupFlags prints the top-level flags marked important for group.
+func (cdr *Commander) explainGroupFlags(w io.Writer, group *CommandGroup) {
+	names := cdr.groupImportant[group.name]
+	if len(names) == 0 {
 		return
 	}
+	sort.Strings(names)
+	if group.name == "" {
+		fmt.Fprintf(w, "Top-level flags for these subcommands:\n")
+	} else {
+		fmt.Fprintf(w, "Top-level flags for %s:\n", group.name)
+	}
+	for _, name := range names {
+		cdr.explainFlag(w, cdr.lookupImportant(name))
+	}
+}
+
+// synopsis returns the synopsis of cmd, from SynopsisContext if it is a
+// ContextSynopsizer, and derived by cdr.SynopsisFallback if cmd's own is
+// empty.
+func (cdr *Commander) synopsis(cmd Command) string {
+	s := cmd.Synopsis()
+	if cs, ok := dealias(cmd).(ContextSynopsizer); ok {
+		ctx := cdr.ctx
+		if ctx == nil {
+			ctx = context.Background()
+		}
+		s = cs.SynopsisContext(ctx)
+	}
+	if s != "" || cdr.SynopsisFallback == nil {
+		return s
+	}
+	return cdr.SynopsisFallback(cmd)
+}
+
+// usageSynopsis returns the first non-blank line of cmd's usage.
+func usageSynopsis(cmd Command) string {
+	return strings.TrimSuffix(firstLine(cmd.Usage()), ":")
+}
+
+// listingSynopsis returns the synopsis of cmd followed by its key flags,
+// if it is a KeyFlagger.
+func (cdr *Commander) listingSynopsis(cmd Command) string {
+	synopsis := cdr.synopsis(cmd)
+	kf, ok := dealias(cmd).(KeyFlagger)
+	if !ok {
+		return synopsis
+	}
+	names := kf.KeyFlags()
+	if len(names) == 0 {
+		return synopsis
+	}
+	flags := make([]string, len(names))
+	for i, name := range names {
+		flags[i] = "-" + name
+	}
+	return fmt.Sprintf("%s [%s]", synopsis, strings.Join(flags, ", "))
+}
+
+// listOrder returns the commands of group in the order they are listed
+// in help output: lexicographical unless KeepRegistrationOrder applies.
+func (cdr *Commander) listOrder(group *CommandGroup) []Command {
+	var cmds []Command
+	for _, cmd := range group.commands {
+		if !hidden(cmd) {
+			cmds = append(cmds, cmd)
+		}
+	}
+	if !cdr.keepAllOrder && !cdr.keepOrder[group.name] {
+		sort.Sort(CommandGroup{commands: cmds})
+	}
+	return cmds
+}
+
+// fitSynopsis fits synopsis into cdr.SynopsisWidth display columns, either by
+// truncating it or, if cdr.SynopsisWrap is set, by wrapping it at word
+// boundaries onto continuation lines that begin with indent.
+func (cdr *Commander) fitSynopsis(synopsis, indent string) string {
+	width := cdr.SynopsisWidth
+	if width <= 0 || textwidth.String(synopsis) <= width {
+		return synopsis
+	}
+	if !cdr.SynopsisWrap {
+		ellipsis := cdr.SynopsisEllipsis
+		if textwidth.String(ellipsis) >= width {
+			return textwidth.Truncate(synopsis, width)
+		}
+		cut := textwidth.Truncate(synopsis, width-textwidth.String(ellipsis))
+		return strings.TrimRight(cut, " ") + ellipsis
+	}
+
+	var lines []string
+	line := ""
+	for _, word := range strings.Fields(synopsis) {
+		if line != "" && textwidth.String(line)+1+textwidth.String(word) > width {
+			lines = append(lines, line)
+			line = ""
+		}
+		if line != "" {
+			line += " "
+		}
+		line += word
+	}
+	lines = append(lines, line)
+	return strings.Join(lines, "\n"+indent)
+}
+
+// explainGroupHeader prints the heading for a group's subcommands.
+func explainGroupHeader(w io.Writer, group *CommandGroup) {
 	if group.name == "" {
 		fmt.Fprintf(w, "Subcommands:\n")
 	} else {
 		fmt.Fprintf(w, "Subcommands for %s:\n", group.name)
 	}
-	sort.Sort(group)
+}
 
+// groupAliases maps the names of the commands in group to the names of
+// the aliases for them in the same group.
+func groupAliases(group *CommandGroup) map[string][]string {
 	aliases := make(map[string][]string)
 	for _, cmd := range group.commands {
 		if alias, ok := cmd.(*aliaser); ok {
 			root := dealias(alias).Name()
-
-			if _, ok := aliases[root]; !ok {
-				aliases[root] = []string{}
-			}
 			aliases[root] = append(aliases[root], alias.Name())
 		}
 	}
+	return aliases
+}
 
-	for _, cmd := range group.commands {
-		if _, ok := cmd.(*aliaser); ok {
-			continue
-		}
-
-		name := cmd.Name()
-		names := []string{name}
-
-		if a, ok := aliases[name]; ok {
-			names = append(names, a...)
+// firstLine returns the first non-blank line of s, with surrounding
+// space removed.
+func firstLine(s string) string {
+	for _, line := range strings.Split(s, "\n") {
+		if line = strings.TrimSpace(line); line != "" {
+			return line
 		}
-
-		fmt.Fprintf(w, "\t%-15s  %s\n", strings.Join(names, ", "), cmd.Synopsis())
 	}
-	fmt.Fprintln(w)
+	return ""
 }
 
-// explainCmd prints a brief description of a single command.
-func explain(w io.Writer, cmd Command) {
-	fmt.Fprintf(w, "%s", cmd.Usage())
+// explainCommand prints a brief description of a single command.
+func (cdr *Commander) explainCommand(w io.Writer, cmd Command) {
+	fmt.Fprintf(w, "%s", usage(cmd))
 	subflags := flag.NewFlagSet(cmd.Name(), flag.PanicOnError)
-	subflags.SetOutput(w)
 	cmd.SetFlags(subflags)
-	subflags.PrintDefaults()
+	defer forget(subflags)
+	markSecretFlags(cmd, subflags)
+	cdr.printDefaults(w, cmd, subflags)
+}
+
+// printDefaults prints the defaults of the flags in f, which belong to
+// cmd, or to the top level if cmd is nil, as f.PrintDefaults would, with
+// a note of where else each flag may be set. Texts set by
+// SetDefaultText are shown in place of the defaults, and the defaults of
+// secret flags are redacted.
+func (cdr *Commander) printDefaults(w io.Writer, cmd Command, f *flag.FlagSet) {
+	out := flag.NewFlagSet(f.Name(), flag.ContinueOnError)
+	out.SetOutput(w)
+	f.VisitAll(func(fl *flag.Flag) {
+		usage, def := fl.Usage, fl.DefValue
+		if note := cdr.flagNote(cmd, fl); note != "" {
+			usage += " (" + note + ")"
+		}
+		if def != zeroString(fl.Value) {
+			def = shown(f, fl.Name, def)
+		}
+		if text, ok := defaultText(f, fl.Name); ok {
+			usage += " (" + text + ")"
+			def = zeroString(fl.Value)
+		}
+		out.Var(fl.Value, fl.Name, usage)
+		out.Lookup(fl.Name).DefValue = def
+	})
+	out.PrintDefaults()
+}
+
+// flagNote returns a note on the values the flag fl of cmd accepts, if
+// they are limited to a few Choices, and on where else than the command
+// line it may be set, if anywhere.
+func (cdr *Commander) flagNote(cmd Command, fl *flag.Flag) string {
+	var notes []string
+	if c, ok := fl.Value.(Chooser); ok {
+		notes = append(notes, "one of: "+strings.Join(c.Choices(), "|"))
+	}
+	if cdr.envBound {
+		notes = append(notes, "env "+cdr.EnvName(cmd, fl.Name))
+	}
+	return strings.Join(notes, "; ")
 }
 
 // A helper is a Command implementing a "help" command for
@@ -317,32 +1408,48 @@ func (h *helper) Name() string           { return "help" }
 func (h *helper) Synopsis() string       { return "describe subcommands and their syntax" }
 func (h *helper) SetFlags(*flag.FlagSet) {}
 func (h *helper) Usage() string {
-	return `help [<subcommand>]:
+	return `help [<subcommand>|<group>]:
 	With an argument, prints detailed information on the use of
-	the specified subcommand. With no argument, print a list of
-	all commands and a brief description of each.
+	the specified subcommand, or a longer description of each
+	subcommand in the specified group. With no argument, print a
+	list of all commands and a brief description of each.
 `
 }
-func (h *helper) Execute(_ context.Context, f *flag.FlagSet, args ...interface{}) ExitStatus {
-	switch f.NArg() {
+func (h *helper) Execute(ctx context.Context, f *flag.FlagSet, args ...interface{}) ExitStatus {
+	return (*Commander)(h).executing(ctx).help(f.Args(), f.Usage)
+}
+
+// help prints detailed information on the subcommand or group named in
+// args, or a list of all subcommands if args is empty. If args cannot be
+// understood, usage is called and ExitUsageError is returned.
+func (cdr *Commander) help(args []string, usage func()) ExitStatus {
+	if len(args) > 1 {
+		if m := mounted(cdr.Lookup(args[0])); m != nil {
+			return m.help(args[1:], usage)
+		}
+	}
+
+	switch len(args) {
 	case 0:
-		(*Commander)(h).Explain(h.Output)
+		cdr.Explain(cdr.Output)
 		return ExitSuccess
 
 	case 1:
-		for _, group := range h.commands {
-			for _, cmd := range group.commands {
-				if f.Arg(0) != cmd.Name() {
-					continue
-				}
-				(*Commander)(h).ExplainCommand(h.Output, cmd)
+		if cmd := cdr.Lookup(args[0]); cmd != nil {
+			cdr.ExplainCommand(cdr.Output, cmd)
+			return ExitSuccess
+		}
+		for _, group := range cdr.commands {
+			if group.name != "" && args[0] == group.name {
+				cdr.loadGroup(group)
+				cdr.explainGroupDetail(cdr.Output, group)
 				return ExitSuccess
 			}
 		}
-		fmt.Fprintf(h.Error, "Subcommand %s not understood\n", f.Arg(0))
+		cdr.Errorf("help: subcommand %s not understood", args[0])
 	}
 
-	f.Usage()
+	usage()
 	return ExitUsageError
 }
 
@@ -365,34 +1472,34 @@ func (flg *flagger) Usage() string {
 	top-level flags.)
 `
 }
-func (flg *flagger) Execute(_ context.Context, f *flag.FlagSet, _ ...interface{}) ExitStatus {
+func (flg *flagger) Execute(ctx context.Context, f *flag.FlagSet, _ ...interface{}) ExitStatus {
+	cdr := (*Commander)(flg).executing(ctx)
 	if f.NArg() > 1 {
 		f.Usage()
 		return ExitUsageError
 	}
 
 	if f.NArg() == 0 {
-		if flg.topFlags == nil {
-			fmt.Fprintln(flg.Output, "No top-level flags are defined.")
+		if cdr.topFlags == nil {
+			fmt.Fprintln(cdr.Output, "No top-level flags are defined.")
 		} else {
-			flg.topFlags.PrintDefaults()
+			w := cdr.topFlags.Output()
+			if cdr != (*Commander)(flg) {
+				w = cdr.Error // a copy writing elsewhere
+			}
+			cdr.printDefaults(w, nil, cdr.topFlags)
 		}
 		return ExitSuccess
 	}
 
-	for _, group := range flg.commands {
-		for _, cmd := range group.commands {
-			if f.Arg(0) != cmd.Name() {
-				continue
-			}
-			subflags := flag.NewFlagSet(cmd.Name(), flag.PanicOnError)
-			subflags.SetOutput(flg.Output)
-			cmd.SetFlags(subflags)
-			subflags.PrintDefaults()
-			return ExitSuccess
-		}
+	if cmd := cdr.Lookup(f.Arg(0)); cmd != nil {
+		subflags := flag.NewFlagSet(cmd.Name(), flag.PanicOnError)
+		cmd.SetFlags(subflags)
+		defer forget(subflags)
+		cdr.printDefaults(cdr.Output, cmd, subflags)
+		return ExitSuccess
 	}
-	fmt.Fprintf(flg.Error, "Subcommand %s not understood\n", f.Arg(0))
+	cdr.Errorf("flags: subcommand %s not understood", f.Arg(0))
 	return ExitFailure
 }
 
@@ -412,15 +1519,19 @@ func (l *lister) Usage() string {
 	Print a list of all commands.
 `
 }
-func (l *lister) Execute(_ context.Context, f *flag.FlagSet, _ ...interface{}) ExitStatus {
+func (l *lister) Execute(ctx context.Context, f *flag.FlagSet, _ ...interface{}) ExitStatus {
 	if f.NArg() != 0 {
 		f.Usage()
 		return ExitUsageError
 	}
 
-	for _, group := range l.commands {
+	cdr := (*Commander)(l).executing(ctx)
+	cdr.loadGroups()
+	for _, group := range cdr.commands {
 		for _, cmd := range group.commands {
-			fmt.Fprintf(l.Output, "%s\n", cmd.Name())
+			if !hidden(cmd) {
+				fmt.Fprintf(cdr.Output, "%s\n", cmd.Name())
+			}
 		}
 	}
 	return ExitSuccess
@@ -446,21 +1557,78 @@ func Alias(alias string, cmd Command) Command {
 }
 
 // dealias recursivly dealiases a command until a non-aliased command
-// is reached.
+// is reached. Commands wrapped by WithRetry are unwrapped as well.
 func dealias(cmd Command) Command {
-	if alias, ok := cmd.(*aliaser); ok {
-		return dealias(alias.Command)
+	switch c := cmd.(type) {
+	case *aliaser:
+		return dealias(c.Command)
+	case *retrier:
+		return dealias(c.Command)
+	case *wrapper:
+		return dealias(c.Command)
 	}
 
 	return cmd
 }
 
+// A funcCommand is a Command made of functions by CommandFunc.
+type funcCommand struct {
+	name, synopsis, usage string
+	setFlags              func(*flag.FlagSet)
+	run                   func(context.Context, *flag.FlagSet) error
+}
+
+func (c *funcCommand) Name() string     { return c.name }
+func (c *funcCommand) Synopsis() string { return c.synopsis }
+func (c *funcCommand) Usage() string    { return c.usage }
+func (c *funcCommand) SetFlags(f *flag.FlagSet) {
+	if c.setFlags != nil {
+		c.setFlags(f)
+	}
+}
+
+func (c *funcCommand) Execute(ctx context.Context, f *flag.FlagSet, _ ...interface{}) ExitStatus {
+	err := c.run(ctx, f)
+	if err == nil {
+		return ExitSuccess
+	}
+	cdr := CommanderFromContext(ctx)
+	if cdr == nil {
+		cdr = DefaultCommander
+	} else if ctx.Err() != nil && errors.Is(err, ctx.Err()) {
+		return ExitFailure // the Commander reports the cause
+	}
+	cdr.Errorf("%s: %v", c.name, err)
+	return ExitFailure
+}
+
+// CommandFunc returns a Command with the given name, synopsis and usage
+// that defines its flags with setFlags, which may be nil, and executes
+// run. If run returns an error, it is printed to the Error of the
+// Commander and the command fails with ExitFailure; an error from the
+// cancellation of its context is left for the Commander to report. The
+// extra args given to Execute are not available to run.
+func CommandFunc(name, synopsis, usage string, setFlags func(*flag.FlagSet), run func(ctx context.Context, f *flag.FlagSet) error) Command {
+	return &funcCommand{name, synopsis, usage, setFlags, run}
+}
+
 // DefaultCommander is the default commander using flag.CommandLine for flags
-// and os.Args[0] for the command name.
+// and the base name of os.Args[0], without any ".exe" suffix, for the
+// command name.
 var DefaultCommander *Commander
 
 func init() {
-	DefaultCommander = NewCommander(flag.CommandLine, path.Base(os.Args[0]))
+	DefaultCommander = NewCommander(flag.CommandLine, programName(os.Args[0]))
+}
+
+// programName returns the name of the program invoked as arg0, without
+// its directory or any ".exe" suffix.
+func programName(arg0 string) string {
+	name := filepath.Base(arg0)
+	if ext := filepath.Ext(name); strings.EqualFold(ext, ".exe") {
+		name = strings.TrimSuffix(name, ext)
+	}
+	return name
 }
 
 // Register adds a subcommand to the supported subcommands in the
@@ -481,6 +1649,14 @@ func ImportantFlag(name string) {
 	DefaultCommander.ImportantFlag(name)
 }
 
+// HandleHelpFlags defines -h and -help on the default top-level flags
+// so that they behave like the "help" subcommand. It must be called
+// before flag.Parse. It is a wrapper around
+// DefaultCommander.HandleHelpFlags.
+func HandleHelpFlags() {
+	DefaultCommander.HandleHelpFlags()
+}
+
 // Execute should be called once the default flags have been
 // initialized by flag.Parse. It finds the correct subcommand and
 // executes it, and returns an ExitStatus with the result. On a usage
@@ -0,0 +1,1370 @@
+/*
+Copyright 2016 Google Inc. All Rights Reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package subcommands
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+)
+
+// A testCommand is a Command for tests. It records the arguments left
+// after its flags are parsed, and returns status.
+type testCommand struct {
+	name     string
+	synopsis string
+	flags    func(*flag.FlagSet)
+	execute  func(ctx context.Context, f *flag.FlagSet) ExitStatus
+	status   ExitStatus
+	args     []string // set by Execute
+	runs     int      // counted by Execute
+}
+
+func (c *testCommand) Name() string     { return c.name }
+func (c *testCommand) Synopsis() string { return c.synopsis }
+func (c *testCommand) Usage() string    { return c.name + ":\n\tA command for tests.\n" }
+func (c *testCommand) SetFlags(f *flag.FlagSet) {
+	if c.flags != nil {
+		c.flags(f)
+	}
+}
+func (c *testCommand) Execute(ctx context.Context, f *flag.FlagSet, _ ...interface{}) ExitStatus {
+	c.args = f.Args()
+	c.runs++
+	if c.execute != nil {
+		return c.execute(ctx, f)
+	}
+	return c.status
+}
+
+// newTestCommander returns a Commander named tool, with a print
+// command taking a -n flag, that writes to the returned buffers.
+func newTestCommander() (cdr *Commander, stdout, stderr *bytes.Buffer) {
+	stdout, stderr = new(bytes.Buffer), new(bytes.Buffer)
+	cdr = NewCommander(flag.NewFlagSet("tool", flag.ContinueOnError), "tool")
+	cdr.Output, cdr.Error = stdout, stderr
+	cdr.topFlags.SetOutput(stderr)
+	cdr.Register(&testCommand{
+		name:     "print",
+		synopsis: "print args",
+		flags:    func(f *flag.FlagSet) { f.Bool("n", false, "no newline") },
+	}, "")
+	return cdr, stdout, stderr
+}
+
+// execute parses args as the top-level flags of cdr, and executes it.
+func execute(t *testing.T, cdr *Commander, args ...string) ExitStatus {
+	t.Helper()
+	if err := cdr.topFlags.Parse(args); err != nil {
+		t.Fatalf("parsing %q: %v", args, err)
+	}
+	return cdr.Execute(context.Background())
+}
+
+func TestUsageErrors(t *testing.T) {
+	const (
+		topUsage     = "Usage: tool <flags> <subcommand> <subcommand args>\n\nSubcommands:\n\tprint            print args\n\n"
+		commandUsage = "print:\n\tA command for tests.\n  -n\tno newline\n"
+	)
+	tests := []struct {
+		mode       UsageErrorMode
+		args       []string
+		wantStderr string
+	}{
+		{UsageFull, nil, topUsage},
+		{UsageFull, []string{"nosuch"}, topUsage},
+		{UsageFull, []string{"print", "-x"}, "flag provided but not defined: -x\n" + commandUsage},
+		{UsageFull, []string{"print", "-h"}, commandUsage},
+		{UsageLine, nil, "tool: no subcommand given\n"},
+		{UsageLine, []string{"nosuch"}, "tool: unknown subcommand \"nosuch\"\n"},
+		{UsageLine, []string{"print", "-x"}, "tool: print: flag provided but not defined: -x\n"},
+		{UsageLine, []string{"print", "-h"}, commandUsage},
+		{UsageSilent, nil, ""},
+		{UsageSilent, []string{"nosuch"}, ""},
+		{UsageSilent, []string{"print", "-x"}, ""},
+		{UsageSilent, []string{"print", "-h"}, ""},
+	}
+	for _, tt := range tests {
+		cdr, stdout, stderr := newTestCommander()
+		cdr.UsageErrors = tt.mode
+		if status := execute(t, cdr, tt.args...); status != ExitUsageError {
+			t.Errorf("mode %d, %q: status %v, want %v", tt.mode, tt.args, status, ExitUsageError)
+		}
+		if got := stderr.String(); got != tt.wantStderr {
+			t.Errorf("mode %d, %q: stderr\n%q\nwant\n%q", tt.mode, tt.args, got, tt.wantStderr)
+		}
+		if stdout.Len() != 0 {
+			t.Errorf("mode %d, %q: stdout %q, want nothing", tt.mode, tt.args, stdout.String())
+		}
+	}
+}
+
+func TestUsageHint(t *testing.T) {
+	tests := []struct {
+		help       bool // register the help command
+		args       []string
+		wantStderr string
+	}{
+		{true, nil, "tool: no subcommand given\ntool: run 'tool help' for usage\n"},
+		{true, []string{"nosuch"}, "tool: unknown subcommand \"nosuch\"\ntool: run 'tool help' for usage\n"},
+		{true, []string{"print", "-x"}, "tool: print: flag provided but not defined: -x\ntool: see 'tool help print'\n"},
+		{false, nil, "tool: no subcommand given\n"},
+		{false, []string{"nosuch"}, "tool: unknown subcommand \"nosuch\"\n"},
+		{false, []string{"print", "-x"}, "tool: print: flag provided but not defined: -x\n"},
+	}
+	for _, tt := range tests {
+		cdr, _, stderr := newTestCommander()
+		cdr.UsageErrors = UsageHint
+		if tt.help {
+			cdr.Register(cdr.HelpCommand(), "")
+		}
+		if status := execute(t, cdr, tt.args...); status != ExitUsageError {
+			t.Errorf("help %v, %q: status %v, want %v", tt.help, tt.args, status, ExitUsageError)
+		}
+		if got := stderr.String(); got != tt.wantStderr {
+			t.Errorf("help %v, %q: stderr\n%q\nwant\n%q", tt.help, tt.args, got, tt.wantStderr)
+		}
+	}
+}
+
+func TestFlagErrorFooter(t *testing.T) {
+	const footer = "tool: see 'tool help print'\n"
+	tests := []struct {
+		name       string
+		mode       UsageErrorMode
+		help       bool // register the help command
+		silent     bool
+		args       []string
+		wantFooter bool
+	}{
+		{"full", UsageFull, true, false, []string{"print", "-x"}, true},
+		{"line", UsageLine, true, false, []string{"print", "-x"}, true},
+		{"bad value", UsageLine, true, false, []string{"print", "-n=maybe"}, true},
+		{"asked for help", UsageLine, true, false, []string{"print", "-h"}, false},
+		{"no help command", UsageLine, false, false, []string{"print", "-x"}, false},
+		{"silent mode", UsageFull, true, true, []string{"print", "-x"}, false},
+		{"silent usage errors", UsageSilent, true, false, []string{"print", "-x"}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cdr, _, stderr := newTestCommander()
+			cdr.UsageErrors = tt.mode
+			cdr.SetSilent(tt.silent)
+			if tt.help {
+				cdr.Register(cdr.HelpCommand(), "")
+			}
+			execute(t, cdr, tt.args...)
+			if got := strings.HasSuffix(stderr.String(), footer); got != tt.wantFooter {
+				t.Errorf("stderr %q; footer %v, want %v", stderr.String(), got, tt.wantFooter)
+			}
+		})
+	}
+}
+
+func TestHandleHelpFlags(t *testing.T) {
+	tests := []struct {
+		args       []string
+		predefined bool // define a -h flag of the program's own first
+		wantStatus ExitStatus
+		wantStdout string
+		wantStderr string
+		wantRun    bool
+	}{
+		{[]string{"-h"}, false, ExitSuccess, "Usage: tool", "", false},
+		{[]string{"-help"}, false, ExitSuccess, "Usage: tool", "", false},
+		{[]string{"--help"}, false, ExitSuccess, "Usage: tool", "", false},
+		{[]string{"-h", "print"}, false, ExitSuccess, "A command for tests.", "", false},
+		{[]string{"-help", "nosuch"}, false, ExitUsageError, "", "tool: help: subcommand nosuch not understood\n", false},
+		{[]string{"print", "-x"}, false, ExitUsageError, "", "-x", false},
+		{[]string{"print"}, false, ExitSuccess, "", "", true},
+		{[]string{"-h", "print"}, true, ExitSuccess, "", "", true},
+		{[]string{"-help"}, true, ExitSuccess, "Usage: tool", "", false},
+	}
+	for _, tt := range tests {
+		cdr, stdout, stderr := newTestCommander()
+		print := cdr.lookup("print").(*testCommand)
+		if tt.predefined {
+			cdr.topFlags.Bool("h", false, "be human")
+		}
+		cdr.HandleHelpFlags()
+		status := execute(t, cdr, tt.args...)
+		if status != tt.wantStatus {
+			t.Errorf("%q: status %v, want %v", tt.args, status, tt.wantStatus)
+		}
+		if !strings.Contains(stdout.String(), tt.wantStdout) {
+			t.Errorf("%q: stdout %q, want it to contain %q", tt.args, stdout.String(), tt.wantStdout)
+		}
+		if !strings.Contains(stderr.String(), tt.wantStderr) {
+			t.Errorf("%q: stderr %q, want it to contain %q", tt.args, stderr.String(), tt.wantStderr)
+		}
+		if ran := print.runs > 0; ran != tt.wantRun {
+			t.Errorf("%q: print ran %v, want %v", tt.args, ran, tt.wantRun)
+		}
+	}
+}
+
+func TestHelpGroup(t *testing.T) {
+	tests := []struct {
+		args       []string
+		wantStatus ExitStatus
+		wantStdout string
+		wantStderr string
+	}{
+		{[]string{"dev"}, ExitSuccess, "Subcommands for dev:\n" +
+			"\tbuild\n\t\tbuild things\n\t\tUsage: build\n" +
+			"\ttest (aliases: t)\n\t\ttest things\n\t\tUsage: test\n\n", ""},
+		{[]string{"test"}, ExitSuccess, "test:\n\tA command for tests.\n", ""},
+		{[]string{"t"}, ExitSuccess, "test:\n\tA command for tests.\n", ""},
+		{[]string{"nosuch"}, ExitUsageError, "", "tool: help: subcommand nosuch not understood\nhelp [<subcommand>|<group>]:\n"},
+		{[]string{"dev", "build"}, ExitUsageError, "", "help [<subcommand>|<group>]:\n"},
+	}
+	for _, tt := range tests {
+		cdr, stdout, stderr := newTestCommander()
+		cdr.Register(cdr.HelpCommand(), "")
+		test := &testCommand{name: "test", synopsis: "test things"}
+		cdr.Register(&testCommand{name: "build", synopsis: "build things"}, "dev")
+		cdr.Register(test, "dev")
+		cdr.Register(Alias("t", test), "dev")
+
+		if status := cdr.Run(context.Background(), "help", tt.args); status != tt.wantStatus {
+			t.Errorf("help %q: status %v, want %v", tt.args, status, tt.wantStatus)
+		}
+		if got := stdout.String(); got != tt.wantStdout {
+			t.Errorf("help %q: stdout\n%s\nwant\n%s", tt.args, got, tt.wantStdout)
+		}
+		if !strings.HasPrefix(stderr.String(), tt.wantStderr) {
+			t.Errorf("help %q: stderr %q, want it to begin %q", tt.args, stderr.String(), tt.wantStderr)
+		}
+	}
+}
+
+func TestSetGroupOrder(t *testing.T) {
+	tests := []struct {
+		order []string
+		want  []string
+	}{
+		{nil, []string{"", "admin", "build", "zeta"}},
+		{[]string{"zeta"}, []string{"", "zeta", "admin", "build"}},
+		{[]string{"build", ""}, []string{"build", "", "admin", "zeta"}},
+		{[]string{"zeta", "nosuch", "admin"}, []string{"", "zeta", "admin", "build"}},
+		{[]string{"zeta", "", "admin"}, []string{"zeta", "", "admin", "build"}},
+	}
+	for _, tt := range tests {
+		cdr := NewCommander(flag.NewFlagSet("tool", flag.ContinueOnError), "tool")
+		for i, group := range []string{"build", "zeta", "", "admin"} {
+			cdr.Register(&testCommand{name: fmt.Sprint("cmd", i)}, group)
+		}
+		cdr.SetGroupOrder(tt.order...)
+		var got []string
+		cdr.VisitGroups(func(g *CommandGroup) { got = append(got, g.Name()) })
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("SetGroupOrder(%q): groups %q, want %q", tt.order, got, tt.want)
+		}
+	}
+}
+
+func TestImportantFlagForGroup(t *testing.T) {
+	cdr, _, _ := newTestCommander()
+	cdr.topFlags.Bool("verbose", false, "log more")
+	cdr.topFlags.Int("jobs", 4, "number of jobs")
+	cdr.topFlags.Bool("trace", false, "trace calls")
+	cdr.Register(&testCommand{name: "build", synopsis: "build things"}, "dev")
+	cdr.Register(&testCommand{name: "deploy", synopsis: "deploy things"}, "ops")
+	cdr.ImportantFlagForGroup("dev", "verbose")
+	cdr.ImportantFlagForGroup("dev", "jobs")
+	cdr.ImportantFlagForGroup("", "trace")
+	cdr.ImportantFlag("verbose")
+
+	var buf bytes.Buffer
+	cdr.Explain(&buf)
+	want := "Usage: tool <flags> <subcommand> <subcommand args>\n\n" +
+		"Subcommands:\n" +
+		"\tprint            print args\n" +
+		"Top-level flags for these subcommands:\n" +
+		"  -trace=false: trace calls\n\n" +
+		"Subcommands for dev:\n" +
+		"\tbuild            build things\n" +
+		"Top-level flags for dev:\n" +
+		"  -jobs=4: number of jobs\n" +
+		"  -verbose=false: log more\n\n" +
+		"Subcommands for ops:\n" +
+		"\tdeploy           deploy things\n\n" +
+		"\nTop-level flags (use \"tool flags\" for a full list):\n" +
+		"  -verbose=false: log more\n"
+	if got := buf.String(); got != want {
+		t.Errorf("Explain wrote\n%s\nwant\n%s", got, want)
+	}
+
+	buf.Reset()
+	cdr.Output = &buf
+	cdr.Register(cdr.HelpCommand(), "")
+	cdr.Run(context.Background(), "help", []string{"dev"})
+	if !strings.Contains(buf.String(), "Top-level flags for dev:\n  -jobs=4: number of jobs\n") {
+		t.Errorf("help dev wrote\n%s\nwant the flags for dev", buf.String())
+	}
+}
+
+func TestImportantFlagf(t *testing.T) {
+	tests := []struct {
+		important func(cdr *Commander)
+		want      string
+	}{
+		{func(cdr *Commander) { cdr.ImportantFlag("project") }, "  -project=: the `project` to use\n"},
+		{func(cdr *Commander) { cdr.ImportantFlagf("project", "-project=ID  target project") }, "  -project=ID  target project\n"},
+		{func(cdr *Commander) { cdr.ImportantFlagf("project", "-project=%s  target %s", "ID", "project") }, "  -project=ID  target project\n"},
+		{func(cdr *Commander) { cdr.ImportantFlagf("jobs", "-j N  run N jobs") }, "  -j N  run N jobs\n"},
+	}
+	for _, tt := range tests {
+		cdr, _, _ := newTestCommander()
+		cdr.topFlags.String("project", "", "the `project` to use")
+		cdr.topFlags.Int("jobs", 4, "number of jobs")
+		tt.important(cdr)
+		var buf bytes.Buffer
+		cdr.Explain(&buf)
+		want := "\nTop-level flags (use \"tool flags\" for a full list):\n" + tt.want
+		if got := buf.String(); !strings.HasSuffix(got, want) {
+			t.Errorf("Explain wrote\n%s\nwant it to end\n%s", got, want)
+		}
+	}
+}
+
+// A keyFlagsCommand is a testCommand naming its key flags.
+type keyFlagsCommand struct {
+	testCommand
+	keys []string
+}
+
+func (c *keyFlagsCommand) KeyFlags() []string { return c.keys }
+
+func TestKeyFlags(t *testing.T) {
+	tests := []struct {
+		cmd  Command
+		want string
+	}{
+		{&testCommand{name: "deploy", synopsis: "deploy things"}, "\tdeploy           deploy things\n"},
+		{&keyFlagsCommand{testCommand{name: "deploy", synopsis: "deploy things"}, nil}, "\tdeploy           deploy things\n"},
+		{&keyFlagsCommand{testCommand{name: "deploy", synopsis: "deploy things"}, []string{"env"}}, "\tdeploy           deploy things [-env]\n"},
+		{&keyFlagsCommand{testCommand{name: "deploy", synopsis: "deploy things"}, []string{"env", "n"}}, "\tdeploy           deploy things [-env, -n]\n"},
+	}
+	for _, tt := range tests {
+		cdr := NewCommander(flag.NewFlagSet("tool", flag.ContinueOnError), "tool")
+		cdr.Register(tt.cmd, "ops")
+		var buf bytes.Buffer
+		cdr.Explain(&buf)
+		if got := buf.String(); !strings.Contains(got, "Subcommands for ops:\n"+tt.want) {
+			t.Errorf("Explain wrote\n%s\nwant the line\n%s", got, tt.want)
+		}
+	}
+}
+
+func TestKeepRegistrationOrder(t *testing.T) {
+	tests := []struct {
+		keep []string // nil for no call
+		want map[string][]string
+	}{
+		{nil, map[string][]string{"": {"alpha", "zulu"}, "ops": {"deploy", "rollback"}}},
+		{[]string{}, map[string][]string{"": {"zulu", "alpha"}, "ops": {"rollback", "deploy"}}},
+		{[]string{"ops"}, map[string][]string{"": {"alpha", "zulu"}, "ops": {"rollback", "deploy"}}},
+		{[]string{""}, map[string][]string{"": {"zulu", "alpha"}, "ops": {"deploy", "rollback"}}},
+	}
+	for _, tt := range tests {
+		cdr := NewCommander(flag.NewFlagSet("tool", flag.ContinueOnError), "tool")
+		cdr.Register(&testCommand{name: "zulu"}, "")
+		cdr.Register(&testCommand{name: "alpha"}, "")
+		cdr.Register(&testCommand{name: "rollback"}, "ops")
+		cdr.Register(&testCommand{name: "deploy"}, "ops")
+		if tt.keep != nil {
+			cdr.KeepRegistrationOrder(tt.keep...)
+		}
+		got := make(map[string][]string)
+		for _, group := range cdr.commands {
+			for _, cmd := range cdr.listOrder(group) {
+				got[group.name] = append(got[group.name], cmd.Name())
+			}
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("KeepRegistrationOrder(%q): listed %q, want %q", tt.keep, got, tt.want)
+		}
+	}
+}
+
+func TestFitSynopsis(t *testing.T) {
+	const synopsis = "copy files between hosts quickly"
+	tests := []struct {
+		width    int
+		wrap     bool
+		ellipsis string
+		in       string
+		want     string
+	}{
+		{0, false, "...", synopsis, synopsis},
+		{40, false, "...", synopsis, synopsis},
+		{len(synopsis), false, "...", synopsis, synopsis},
+		{16, false, "...", synopsis, "copy files be..."},
+		{16, false, "…", synopsis, "copy files betw…"},
+		{16, false, "", synopsis, "copy files betwe"},
+		{2, false, "...", synopsis, "co"},
+		{16, true, "...", synopsis, "copy files\n>between hosts\n>quickly"},
+		{5, true, "...", synopsis, "copy\n>files\n>between\n>hosts\n>quickly"},
+		{6, false, "…", "日本語のテキスト", "日本…"},
+		{6, true, "", "日本 語の テキスト", "日本\n>語の\n>テキスト"},
+	}
+	for _, tt := range tests {
+		cdr := NewCommander(flag.NewFlagSet("tool", flag.ContinueOnError), "tool")
+		cdr.SynopsisWidth, cdr.SynopsisWrap, cdr.SynopsisEllipsis = tt.width, tt.wrap, tt.ellipsis
+		if got := cdr.fitSynopsis(tt.in, ">"); got != tt.want {
+			t.Errorf("width %d, wrap %v, ellipsis %q: fitSynopsis(%q) = %q, want %q", tt.width, tt.wrap, tt.ellipsis, tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestSynopsisWrapInListing(t *testing.T) {
+	cdr := NewCommander(flag.NewFlagSet("tool", flag.ContinueOnError), "tool")
+	cdr.Register(&testCommand{name: "copy", synopsis: "copy files between hosts quickly"}, "")
+	cdr.SynopsisWidth, cdr.SynopsisWrap = 16, true
+	var buf bytes.Buffer
+	cdr.Explain(&buf)
+	want := "\tcopy             copy files\n" +
+		"\t                 between hosts\n" +
+		"\t                 quickly\n"
+	if got := buf.String(); !strings.Contains(got, want) {
+		t.Errorf("Explain wrote\n%s\nwant\n%s", got, want)
+	}
+}
+
+func TestExplainWideNames(t *testing.T) {
+	cdr := NewCommander(flag.NewFlagSet("tool", flag.ContinueOnError), "tool")
+	cdr.Register(&testCommand{name: "ascii", synopsis: "plain"}, "")
+	cdr.Register(&testCommand{name: "日本語", synopsis: "wide"}, "")
+	cdr.Register(&testCommand{name: "he\u0301llo", synopsis: "combining"}, "")
+	var buf bytes.Buffer
+	cdr.Explain(&buf)
+	want := "Subcommands:\n" +
+		"\tascii            plain\n" +
+		"\the\u0301llo            combining\n" +
+		"\t日本語           wide\n"
+	if got := buf.String(); !strings.Contains(got, want) {
+		t.Errorf("Explain wrote\n%s\nwant\n%s", got, want)
+	}
+}
+
+func TestStyle(t *testing.T) {
+	tests := []struct {
+		style Style
+		want  string
+	}{
+		{Style{Indent: "\t", NameWidth: 15, Gutter: 2, GroupSpacing: 1},
+			"Subcommands:\n\tbuild            compile\n\tlongername       other\n\nSubcommands for ops:\n\tdeploy           ship it\n\n"},
+		{Style{Indent: "  ", NameWidth: 8, Gutter: 1, GroupSpacing: 0},
+			"Subcommands:\n  build    compile\n  longername other\nSubcommands for ops:\n  deploy   ship it\n"},
+		{Style{Indent: "", NameWidth: 0, Gutter: 3, GroupSpacing: 2},
+			"Subcommands:\nbuild   compile\nlongername   other\n\n\nSubcommands for ops:\ndeploy   ship it\n\n\n"},
+	}
+	for _, tt := range tests {
+		cdr := NewCommander(flag.NewFlagSet("tool", flag.ContinueOnError), "tool")
+		cdr.Register(&testCommand{name: "build", synopsis: "compile"}, "")
+		cdr.Register(&testCommand{name: "longername", synopsis: "other"}, "")
+		cdr.Register(&testCommand{name: "deploy", synopsis: "ship it"}, "ops")
+		cdr.Style = tt.style
+		var buf bytes.Buffer
+		cdr.Explain(&buf)
+		want := "Usage: tool <flags> <subcommand> <subcommand args>\n\n" + tt.want
+		if got := buf.String(); got != want {
+			t.Errorf("%+v: Explain wrote\n%q\nwant\n%q", tt.style, got, want)
+		}
+	}
+}
+
+func TestExplainPerCommander(t *testing.T) {
+	custom, customOut, _ := newTestCommander()
+	plain, plainOut, _ := newTestCommander()
+	for _, cdr := range []*Commander{custom, plain} {
+		cdr.Register(cdr.HelpCommand(), "")
+	}
+	custom.Explain = func(w io.Writer) { fmt.Fprintln(w, "custom help") }
+	custom.ExplainCommand = func(w io.Writer, cmd Command) { fmt.Fprintln(w, "custom help for", cmd.Name()) }
+
+	ctx := context.Background()
+	custom.Run(ctx, "help", nil)
+	custom.Run(ctx, "help", []string{"print"})
+	plain.Run(ctx, "help", nil)
+	if got, want := customOut.String(), "custom help\ncustom help for print\n"; got != want {
+		t.Errorf("custom help wrote %q, want %q", got, want)
+	}
+	if got := plainOut.String(); !strings.HasPrefix(got, "Usage: tool") {
+		t.Errorf("plain help wrote %q, want the default explanation", got)
+	}
+}
+
+// A usageCommand is a testCommand with a Usage of its own.
+type usageCommand struct {
+	testCommand
+	usage string
+}
+
+func (c *usageCommand) Usage() string { return c.usage }
+
+func TestSynopsisFallback(t *testing.T) {
+	tests := []struct {
+		cmd      Command
+		fallback func(Command) string
+		noFall   bool // set SynopsisFallback to nil
+		want     string
+	}{
+		{&usageCommand{testCommand{name: "a", synopsis: "own"}, "a <x>:\n\tDo a.\n"}, nil, false, "own"},
+		{&usageCommand{testCommand{name: "a"}, "a <x>:\n\tDo a.\n"}, nil, false, "a <x>"},
+		{&usageCommand{testCommand{name: "a"}, "\n\n  a [-v] <x>\n"}, nil, false, "a [-v] <x>"},
+		{&usageCommand{testCommand{name: "a"}, ""}, nil, false, ""},
+		{Alias("b", &usageCommand{testCommand{name: "a"}, "a <x>:\n"}), nil, false, "a <x>"},
+		{&usageCommand{testCommand{name: "a"}, "a <x>:\n"}, nil, true, ""},
+		{&usageCommand{testCommand{name: "a"}, "a <x>:\n"}, func(cmd Command) string { return "see " + cmd.Name() }, false, "see a"},
+	}
+	for _, tt := range tests {
+		cdr := NewCommander(flag.NewFlagSet("tool", flag.ContinueOnError), "tool")
+		if tt.fallback != nil {
+			cdr.SynopsisFallback = tt.fallback
+		}
+		if tt.noFall {
+			cdr.SynopsisFallback = nil
+		}
+		if got := cdr.synopsis(tt.cmd); got != tt.want {
+			t.Errorf("synopsis of %q = %q, want %q", tt.cmd.Usage(), got, tt.want)
+		}
+	}
+}
+
+type profileKey struct{}
+
+// A profileCommand is a testCommand whose synopsis names the profile in
+// its context.
+type profileCommand struct{ testCommand }
+
+func (c *profileCommand) SynopsisContext(ctx context.Context) string {
+	if profile, ok := ctx.Value(profileKey{}).(string); ok {
+		return "deploy to " + profile
+	}
+	return "deploy"
+}
+
+func TestContextSynopsizer(t *testing.T) {
+	tests := []struct {
+		ctx  context.Context
+		want string
+	}{
+		{context.Background(), "\tdeploy           deploy\n"},
+		{context.WithValue(context.Background(), profileKey{}, "staging"), "\tdeploy           deploy to staging\n"},
+	}
+	for _, tt := range tests {
+		cdr, stdout, _ := newTestCommander()
+		cdr.Register(cdr.HelpCommand(), "")
+		cdr.Register(&profileCommand{testCommand{name: "deploy", synopsis: "static"}}, "ops")
+		if err := cdr.topFlags.Parse([]string{"help"}); err != nil {
+			t.Fatal(err)
+		}
+		cdr.Execute(tt.ctx)
+		if got := stdout.String(); !strings.Contains(got, tt.want) {
+			t.Errorf("help wrote\n%s\nwant\n%s", got, tt.want)
+		}
+	}
+
+	// Outside Execute, the synopsis is given a background context.
+	cdr := NewCommander(flag.NewFlagSet("tool", flag.ContinueOnError), "tool")
+	cmd := &profileCommand{testCommand{name: "deploy"}}
+	if got := cdr.synopsis(cmd); got != "deploy" {
+		t.Errorf("synopsis outside Execute = %q, want %q", got, "deploy")
+	}
+	if got := cdr.synopsis(Alias("d", cmd)); got != "deploy" {
+		t.Errorf("synopsis of alias = %q, want %q", got, "deploy")
+	}
+}
+
+func TestProgramName(t *testing.T) {
+	tests := []struct {
+		arg0 string
+		want string
+	}{
+		{"tool", "tool"},
+		{"/usr/local/bin/tool", "tool"},
+		{"./tool.exe", "tool"},
+		{"bin/TOOL.EXE", "TOOL"},
+		{"tool.v2", "tool.v2"},
+		{"/tmp/go-build123/b001/exe/tool", "tool"},
+	}
+	for _, tt := range tests {
+		if got := programName(tt.arg0); got != tt.want {
+			t.Errorf("programName(%q) = %q, want %q", tt.arg0, got, tt.want)
+		}
+	}
+}
+
+func TestSetDisplayName(t *testing.T) {
+	cdr, _, stderr := newTestCommander()
+	cdr.UsageErrors = UsageLine
+	cdr.SetDisplayName("my tool")
+	if got := cdr.Name(); got != "tool" {
+		t.Errorf("Name() = %q, want %q", got, "tool")
+	}
+	var buf bytes.Buffer
+	cdr.Explain(&buf)
+	if !strings.HasPrefix(buf.String(), "Usage: my tool <flags>") {
+		t.Errorf("Explain wrote %q, want it to use the display name", buf.String())
+	}
+	execute(t, cdr, "nosuch")
+	if got, want := stderr.String(), "my tool: unknown subcommand \"nosuch\"\n"; got != want {
+		t.Errorf("stderr %q, want %q", got, want)
+	}
+}
+
+func TestReplaceBuiltin(t *testing.T) {
+	tests := []struct {
+		name        string
+		customFirst bool
+	}{
+		{"custom registered first", true},
+		{"builtin registered first", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cdr, _, _ := newTestCommander()
+			custom := &testCommand{name: "help", synopsis: "our help"}
+			if tt.customFirst {
+				cdr.Register(custom, "")
+				cdr.Register(cdr.HelpCommand(), "")
+			} else {
+				cdr.Register(cdr.HelpCommand(), "")
+				cdr.Register(custom, "docs")
+			}
+			if got := cdr.Lookup("help"); got != custom {
+				t.Fatalf("Lookup(help) = %T, want the custom command", got)
+			}
+			n := 0
+			cdr.VisitCommands(func(_ *CommandGroup, cmd Command) {
+				if cmd.Name() == "help" {
+					n++
+				}
+			})
+			if n != 1 {
+				t.Errorf("%d commands named help registered, want 1", n)
+			}
+			execute(t, cdr, "help")
+			if custom.runs != 1 {
+				t.Errorf("custom help ran %d times, want 1", custom.runs)
+			}
+		})
+	}
+
+	// Two commands of the program's own with the same name are both kept,
+	// as before builtins could be replaced.
+	cdr := NewCommander(flag.NewFlagSet("tool", flag.ContinueOnError), "tool")
+	first := &testCommand{name: "dup"}
+	cdr.Register(first, "")
+	cdr.Register(&testCommand{name: "dup"}, "")
+	if got := cdr.Lookup("dup"); got != first {
+		t.Errorf("Lookup(dup) did not return the first registered")
+	}
+}
+
+func TestDisableBuiltins(t *testing.T) {
+	tests := []struct {
+		disable    []string
+		wantNames  []string
+		wantStderr string // for a usage error in UsageHint mode
+		wantFooter bool   // "use tool flags" in the top-level help
+	}{
+		{nil, []string{"print"}, "tool: unknown subcommand \"nosuch\"\n", false},
+		{[]string{"help"}, []string{"flags", "commands", "print"}, "tool: unknown subcommand \"nosuch\"\n", true},
+		{[]string{"flags", "commands"}, []string{"help", "print"}, "tool: unknown subcommand \"nosuch\"\ntool: run 'tool help' for usage\n", false},
+		{[]string{"tree"}, []string{"help", "flags", "commands", "print"}, "tool: unknown subcommand \"nosuch\"\ntool: run 'tool help' for usage\n", true},
+	}
+	for _, tt := range tests {
+		cdr, _, stderr := newTestCommander()
+		cdr.topFlags.Bool("v", false, "verbose")
+		cdr.UsageErrors = UsageHint
+		for _, cmd := range []Command{cdr.HelpCommand(), cdr.FlagsCommand(), cdr.CommandsCommand()} {
+			cdr.Register(cmd, "")
+		}
+		if tt.disable == nil {
+			cdr.DisableBuiltins()
+		} else {
+			cdr.DisableBuiltins(tt.disable...)
+		}
+
+		var names []string
+		cdr.VisitCommands(func(_ *CommandGroup, cmd Command) { names = append(names, cmd.Name()) })
+		sort.Strings(names)
+		want := append([]string(nil), tt.wantNames...)
+		sort.Strings(want)
+		if !reflect.DeepEqual(names, want) {
+			t.Errorf("DisableBuiltins(%q): commands %q, want %q", tt.disable, names, want)
+		}
+		execute(t, cdr, "nosuch")
+		if got := stderr.String(); got != tt.wantStderr {
+			t.Errorf("DisableBuiltins(%q): stderr %q, want %q", tt.disable, got, tt.wantStderr)
+		}
+		var buf bytes.Buffer
+		cdr.Explain(&buf)
+		if got := strings.Contains(buf.String(), `Use "tool flags"`); got != tt.wantFooter {
+			t.Errorf("DisableBuiltins(%q): help refers to flags %v, want %v:\n%s", tt.disable, got, tt.wantFooter, &buf)
+		}
+	}
+
+	// Later registrations of a disabled builtin are ignored, but not
+	// those of a command of the program's own with its name.
+	cdr := NewCommander(flag.NewFlagSet("tool", flag.ContinueOnError), "tool")
+	cdr.DisableBuiltins("help")
+	cdr.Register(cdr.HelpCommand(), "")
+	if cmd := cdr.Lookup("help"); cmd != nil {
+		t.Errorf("disabled help registered as %T", cmd)
+	}
+	custom := &testCommand{name: "help"}
+	cdr.Register(custom, "")
+	if cmd := cdr.Lookup("help"); cmd != custom {
+		t.Errorf("Lookup(help) = %T, want the custom command", cmd)
+	}
+}
+
+func TestSetMatcher(t *testing.T) {
+	caseless := func(arg string, known []string) (string, bool) {
+		for _, name := range known {
+			if strings.EqualFold(arg, name) {
+				return name, true
+			}
+		}
+		return "", false
+	}
+	versioned := func(arg string, known []string) (string, bool) {
+		name, _, _ := strings.Cut(arg, "@")
+		return name, true
+	}
+	tests := []struct {
+		match      func(string, []string) (string, bool)
+		arg        string
+		wantStatus ExitStatus
+		wantRun    bool
+	}{
+		{nil, "print", ExitSuccess, true},
+		{nil, "PRINT", ExitUsageError, false},
+		{caseless, "PRINT", ExitSuccess, true},
+		{caseless, "Print", ExitSuccess, true},
+		{caseless, "prin", ExitUsageError, false},
+		{versioned, "print@v2", ExitSuccess, true},
+		{versioned, "nosuch@v2", ExitUsageError, false},
+	}
+	for _, tt := range tests {
+		cdr, _, _ := newTestCommander()
+		cdr.UsageErrors = UsageSilent
+		print := cdr.lookup("print").(*testCommand)
+		cdr.SetMatcher(tt.match)
+		if status := execute(t, cdr, tt.arg); status != tt.wantStatus {
+			t.Errorf("%q: status %v, want %v", tt.arg, status, tt.wantStatus)
+		}
+		if ran := print.runs > 0; ran != tt.wantRun {
+			t.Errorf("%q: print ran %v, want %v", tt.arg, ran, tt.wantRun)
+		}
+	}
+
+	// The matcher is given the names of all commands, and also selects
+	// the command Run executes.
+	cdr, _, _ := newTestCommander()
+	cdr.Register(&testCommand{name: "deploy"}, "ops")
+	var known []string
+	cdr.SetMatcher(func(arg string, names []string) (string, bool) {
+		known = names
+		return caseless(arg, names)
+	})
+	if status := cdr.Run(context.Background(), "DEPLOY", nil); status != ExitSuccess {
+		t.Errorf("Run(DEPLOY): status %v, want %v", status, ExitSuccess)
+	}
+	if want := []string{"print", "deploy"}; !reflect.DeepEqual(known, want) {
+		t.Errorf("matcher given %q, want %q", known, want)
+	}
+}
+
+// largeCommander returns a Commander with 500 commands in 10 groups, as
+// in a large tool.
+func largeCommander() *Commander {
+	cdr := NewCommander(flag.NewFlagSet("tool", flag.ContinueOnError), "tool")
+	cdr.Output, cdr.Error = io.Discard, io.Discard
+	for i := 0; i < 500; i++ {
+		cdr.Register(&testCommand{
+			name:     fmt.Sprintf("command%03d", i),
+			synopsis: fmt.Sprintf("do the thing numbered %d, with some care", i),
+		}, fmt.Sprintf("group%d", i%10))
+	}
+	return cdr
+}
+
+func BenchmarkExplain(b *testing.B) {
+	cdr := largeCommander()
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		cdr.Explain(io.Discard)
+	}
+}
+
+func BenchmarkExplainGroup(b *testing.B) {
+	cdr := largeCommander()
+	var group *CommandGroup
+	cdr.VisitGroups(func(g *CommandGroup) {
+		if g.Name() == "group0" {
+			group = g
+		}
+	})
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		cdr.ExplainGroup(io.Discard, group)
+	}
+}
+
+func TestChain(t *testing.T) {
+	tests := []struct {
+		args     []string
+		status   map[string]ExitStatus // of the commands that fail
+		want     ExitStatus
+		wantRuns []string // commands run, with their arguments
+	}{
+		{[]string{"a"}, nil, ExitSuccess, []string{"a"}},
+		{[]string{"a", "x", "+", "b", "y", "z"}, nil, ExitSuccess, []string{"a x", "b y z"}},
+		{[]string{"a", "+", "b", "+", "a", "w"}, nil, ExitSuccess, []string{"a", "b", "a w"}},
+		{[]string{"a", "+", "b", "+", "a"}, map[string]ExitStatus{"b": ExitFailure}, ExitFailure, []string{"a", "b"}},
+		{[]string{"a", "-v", "--", "-x", "+", "b"}, nil, ExitSuccess, []string{"a -x", "b"}},
+		{[]string{"a", "+"}, nil, ExitUsageError, []string{"a"}},
+	}
+	for _, tt := range tests {
+		var runs []string
+		cdr := NewCommander(flag.NewFlagSet("tool", flag.ContinueOnError), "tool")
+		cdr.Output, cdr.Error = io.Discard, io.Discard
+		cdr.ChainSeparator = "+"
+		for _, name := range []string{"a", "b"} {
+			name := name
+			var verbose bool
+			cdr.Register(&testCommand{
+				name:  name,
+				flags: func(f *flag.FlagSet) { f.BoolVar(&verbose, "v", false, "") },
+				execute: func(_ context.Context, f *flag.FlagSet) ExitStatus {
+					runs = append(runs, strings.Join(append([]string{name}, f.Args()...), " "))
+					return tt.status[name]
+				},
+			}, "")
+		}
+		if err := cdr.topFlags.Parse(tt.args); err != nil {
+			t.Fatal(err)
+		}
+		if got := cdr.Execute(context.Background()); got != tt.want {
+			t.Errorf("%q: status %v, want %v", tt.args, got, tt.want)
+		}
+		if !reflect.DeepEqual(runs, tt.wantRuns) {
+			t.Errorf("%q: ran %q, want %q", tt.args, runs, tt.wantRuns)
+		}
+	}
+}
+
+func TestChainSeparatorFlagTerminator(t *testing.T) {
+	cdr := NewCommander(flag.NewFlagSet("tool", flag.ContinueOnError), "tool")
+	cdr.Register(&testCommand{name: "a"}, "")
+	cdr.ChainSeparator = "--"
+	cdr.topFlags.Parse([]string{"a", "--", "a"})
+	defer func() {
+		if recover() == nil {
+			t.Error("Execute with ChainSeparator \"--\" did not panic")
+		}
+	}()
+	cdr.Execute(context.Background())
+}
+
+// extrasCommand is a testCommand recording the extra arguments passed
+// to Execute.
+type extrasCommand struct {
+	testCommand
+	extras []interface{}
+}
+
+func (c *extrasCommand) Execute(ctx context.Context, f *flag.FlagSet, args ...interface{}) ExitStatus {
+	c.extras = args
+	return c.testCommand.Execute(ctx, f)
+}
+
+func TestRunSibling(t *testing.T) {
+	tests := []struct {
+		name       string
+		argv       []string
+		wantStatus ExitStatus
+		wantArgs   []string
+		wantFlag   bool
+		wantStderr string
+	}{
+		{name: "build", argv: []string{"-release", "app"}, wantStatus: ExitSuccess, wantArgs: []string{"app"}, wantFlag: true},
+		{name: "build", argv: []string{"-bogus"}, wantStatus: ExitUsageError, wantStderr: "flag provided but not defined: -bogus"},
+		{name: "nosuch", wantStatus: ExitUsageError, wantStderr: "tool: unknown subcommand \"nosuch\"\n"},
+	}
+	for _, tt := range tests {
+		cdr, _, stderr := newTestCommander()
+		var release bool
+		build := &extrasCommand{testCommand: testCommand{
+			name:  "build",
+			flags: func(f *flag.FlagSet) { f.BoolVar(&release, "release", false, "") },
+		}}
+		cdr.Register(build, "")
+		var status ExitStatus
+		cdr.Register(&testCommand{name: "deploy", execute: func(ctx context.Context, f *flag.FlagSet) ExitStatus {
+			status = CommanderFromContext(ctx).Run(ctx, tt.name, tt.argv, "extra")
+			return status
+		}}, "")
+
+		if got := execute(t, cdr, "deploy"); got != tt.wantStatus || status != tt.wantStatus {
+			t.Errorf("Run(%q, %q): status %v, deploy %v; want %v", tt.name, tt.argv, status, got, tt.wantStatus)
+		}
+		if tt.wantStatus == ExitSuccess {
+			if !reflect.DeepEqual(build.args, tt.wantArgs) || release != tt.wantFlag {
+				t.Errorf("Run(%q, %q): build got args %q, -release %v; want %q, %v", tt.name, tt.argv, build.args, release, tt.wantArgs, tt.wantFlag)
+			}
+			if !reflect.DeepEqual(build.extras, []interface{}{"extra"}) {
+				t.Errorf("Run(%q, %q): build got extras %q, want [extra]", tt.name, tt.argv, build.extras)
+			}
+		}
+		if !strings.Contains(stderr.String(), tt.wantStderr) || tt.wantStderr == "" && stderr.Len() != 0 {
+			t.Errorf("Run(%q, %q): stderr %q, want %q", tt.name, tt.argv, stderr.String(), tt.wantStderr)
+		}
+	}
+}
+
+func TestSetFlagParser(t *testing.T) {
+	// fill parses the arguments, then sets the flags named by env that
+	// the arguments did not set, as a parser like ff would.
+	fill := func(env map[string]string) func(*flag.FlagSet, []string) error {
+		return func(f *flag.FlagSet, args []string) error {
+			if err := f.Parse(args); err != nil {
+				return err
+			}
+			set := make(map[string]bool)
+			f.Visit(func(fl *flag.Flag) { set[fl.Name] = true })
+			for name, value := range env {
+				if !set[name] {
+					if err := f.Set(name, value); err != nil {
+						return fmt.Errorf("%s from the environment: %v", name, err)
+					}
+				}
+			}
+			return nil
+		}
+	}
+	tests := []struct {
+		name       string
+		parse      func(*flag.FlagSet, []string) error
+		args       []string
+		wantStatus ExitStatus
+		wantN      bool
+		wantArgs   []string
+		wantStderr string
+	}{
+		{"default", nil, []string{"a"}, ExitSuccess, false, []string{"a"}, ""},
+		{"filled", fill(map[string]string{"n": "true"}), []string{"a"}, ExitSuccess, true, []string{"a"}, ""},
+		{"command line wins", fill(map[string]string{"n": "true"}), []string{"-n=false", "a"}, ExitSuccess, false, []string{"a"}, ""},
+		{"bad value", fill(map[string]string{"n": "maybe"}), []string{"a"}, ExitUsageError, false, nil, "n from the environment"},
+	}
+	for _, tt := range tests {
+		cdr, _, stderr := newTestCommander()
+		cdr.UsageErrors = UsageLine
+		cdr.SetFlagParser(tt.parse)
+		var n bool
+		print := &testCommand{name: "show", flags: func(f *flag.FlagSet) { f.BoolVar(&n, "n", false, "") }}
+		cdr.Register(print, "")
+		if status := cdr.Run(context.Background(), "show", tt.args); status != tt.wantStatus {
+			t.Errorf("%s: status %v, want %v", tt.name, status, tt.wantStatus)
+		}
+		if n != tt.wantN || !reflect.DeepEqual(print.args, tt.wantArgs) {
+			t.Errorf("%s: -n %v, args %q; want %v, %q", tt.name, n, print.args, tt.wantN, tt.wantArgs)
+		}
+		if got := stderr.String(); !strings.Contains(got, tt.wantStderr) || tt.wantStderr == "" && got != "" {
+			t.Errorf("%s: stderr %q, want %q", tt.name, got, tt.wantStderr)
+		}
+	}
+}
+
+func TestCommandFunc(t *testing.T) {
+	tests := []struct {
+		name       string
+		args       []string
+		err        error
+		cancel     bool
+		wantStatus ExitStatus
+		wantStderr string
+	}{
+		{name: "success", args: []string{"-v", "a"}, wantStatus: ExitSuccess},
+		{name: "error", args: []string{"a"}, err: errors.New("no such file"), wantStatus: ExitFailure, wantStderr: "tool: cat: no such file\n"},
+		{name: "canceled", args: []string{"a"}, cancel: true, wantStatus: ExitCanceled, wantStderr: "tool: cat: context canceled\n"},
+		{name: "bad flag", args: []string{"-x"}, wantStatus: ExitUsageError, wantStderr: "flag provided but not defined: -x\n"},
+	}
+	for _, tt := range tests {
+		cdr, _, stderr := newTestCommander()
+		cdr.UsageErrors = UsageLine
+		var verbose bool
+		var args []string
+		cmd := CommandFunc("cat", "print files", "cat <file>...:\n\tPrint files.\n",
+			func(f *flag.FlagSet) { f.BoolVar(&verbose, "v", false, "verbose") },
+			func(ctx context.Context, f *flag.FlagSet) error {
+				args = f.Args()
+				if tt.cancel {
+					<-ctx.Done()
+					return ctx.Err()
+				}
+				return tt.err
+			})
+		cdr.Register(cmd, "")
+		ctx, cancel := context.WithCancel(context.Background())
+		if tt.cancel {
+			cancel()
+		}
+		if status := cdr.Run(ctx, "cat", tt.args); status != tt.wantStatus {
+			t.Errorf("%s: status %v, want %v", tt.name, status, tt.wantStatus)
+		}
+		cancel()
+		if tt.name == "success" && (!verbose || !reflect.DeepEqual(args, []string{"a"})) {
+			t.Errorf("%s: -v %v, args %q; want true, [a]", tt.name, verbose, args)
+		}
+		if got := stderr.String(); !strings.HasSuffix(got, tt.wantStderr) || tt.wantStderr == "" && got != "" {
+			t.Errorf("%s: stderr %q, want %q", tt.name, got, tt.wantStderr)
+		}
+	}
+
+	cmd := CommandFunc("cat", "print files", "cat <file>...:\n", nil, func(context.Context, *flag.FlagSet) error { return nil })
+	if cmd.Name() != "cat" || cmd.Synopsis() != "print files" || cmd.Usage() != "cat <file>...:\n" {
+		t.Errorf("CommandFunc returned %q, %q, %q", cmd.Name(), cmd.Synopsis(), cmd.Usage())
+	}
+	cmd.SetFlags(flag.NewFlagSet("cat", flag.ContinueOnError)) // a nil setFlags defines no flags
+}
+
+func TestRestrictCommands(t *testing.T) {
+	tests := []struct {
+		name  string
+		allow []string
+		deny  []string
+		want  []string // the commands listed, all of which execute
+	}{
+		{"unrestricted", nil, nil, []string{"print", "commands", "get", "list", "delete", "rm"}},
+		{"allow", []string{"get", "list"}, nil, []string{"commands", "get", "list"}},
+		{"allow alias", []string{"rm"}, nil, []string{"commands", "rm"}},
+		{"allow aliased", []string{"delete"}, nil, []string{"commands", "delete", "rm"}},
+		{"deny", nil, []string{"print", "list"}, []string{"commands", "get", "delete", "rm"}},
+		{"deny aliased", nil, []string{"delete"}, []string{"print", "commands", "get", "list"}},
+		{"deny wins", []string{"get", "list"}, []string{"list"}, []string{"commands", "get"}},
+	}
+	all := []string{"print", "commands", "get", "list", "delete", "rm"}
+	for _, tt := range tests {
+		cdr, stdout, _ := newTestCommander()
+		cdr.Register(cdr.CommandsCommand(), "")
+		cdr.Register(&testCommand{name: "get"}, "")
+		// Restrictions apply to commands registered before and after.
+		if tt.allow != nil {
+			cdr.AllowCommands(tt.allow...)
+		}
+		if tt.deny != nil {
+			cdr.DenyCommands(tt.deny...)
+		}
+		cdr.Register(&testCommand{name: "list"}, "")
+		del := &testCommand{name: "delete"}
+		cdr.Register(del, "")
+		cdr.Register(Alias("rm", del), "")
+
+		cdr.Run(context.Background(), "commands", nil)
+		if got := strings.Fields(stdout.String()); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s: listed %q, want %q", tt.name, got, tt.want)
+		}
+		for _, name := range all {
+			want := ExitUsageError
+			for _, w := range tt.want {
+				if w == name {
+					want = ExitSuccess
+				}
+			}
+			if status := cdr.Run(context.Background(), name, nil); status != want {
+				t.Errorf("%s: running %s: status %v, want %v", tt.name, name, status, want)
+			}
+		}
+	}
+}
+
+func TestExitStatusString(t *testing.T) {
+	tests := []struct {
+		status ExitStatus
+		want   string
+	}{
+		{ExitSuccess, "success"},
+		{ExitFailure, "failure"},
+		{ExitUsageError, "usage error"},
+		{ExitTimeout, "timeout"},
+		{ExitCanceled, "canceled"},
+		{ExitInterrupted, "interrupted"},
+		{ExitCode(3), "exit status 3"},
+	}
+	for _, tt := range tests {
+		if got := tt.status.String(); got != tt.want {
+			t.Errorf("ExitStatus(%d).String() = %q, want %q", int(tt.status), got, tt.want)
+		}
+		if got, err := ParseExitStatus(tt.want); err != nil || got != tt.status {
+			t.Errorf("ParseExitStatus(%q) = %v, %v; want %d", tt.want, int(got), err, int(tt.status))
+		}
+	}
+}
+
+func TestParseExitStatus(t *testing.T) {
+	tests := []struct {
+		s       string
+		want    ExitStatus
+		wantErr bool
+	}{
+		{"0", ExitSuccess, false},
+		{"2", ExitUsageError, false},
+		{"64", ExitCode(64), false},
+		{"exit status 255", ExitCode(255), false},
+		{"256", 0, true},
+		{"-1", 0, true},
+		{"Success", 0, true},
+		{"", 0, true},
+	}
+	for _, tt := range tests {
+		got, err := ParseExitStatus(tt.s)
+		if (err != nil) != tt.wantErr || got != tt.want {
+			t.Errorf("ParseExitStatus(%q) = %d, %v; want %d, error %v", tt.s, int(got), err, int(tt.want), tt.wantErr)
+		}
+	}
+}
+
+func TestExitCode(t *testing.T) {
+	for _, n := range []int{0, 3, 64, 255} {
+		cdr, _, stderr := newTestCommander()
+		cdr.Register(&testCommand{name: "partial", status: ExitCode(n)}, "")
+		if status := execute(t, cdr, "partial"); int(status) != n {
+			t.Errorf("ExitCode(%d): Execute returned %d", n, int(status))
+		}
+		if stderr.Len() != 0 {
+			t.Errorf("ExitCode(%d): stderr %q, want nothing", n, stderr.String())
+		}
+	}
+	for _, n := range []int{-1, 256} {
+		func() {
+			defer func() {
+				if recover() == nil {
+					t.Errorf("ExitCode(%d) did not panic", n)
+				}
+			}()
+			ExitCode(n)
+		}()
+	}
+}
+
+func TestLastError(t *testing.T) {
+	tests := []struct {
+		args       []string
+		wantStatus ExitStatus
+		wantErr    string // "" for none
+	}{
+		{[]string{"count", "-n=3"}, ExitSuccess, ""},
+		{[]string{"count", "-n=x"}, ExitUsageError, `invalid value "x" for flag -n: parse error`},
+		{[]string{"count", "-m"}, ExitUsageError, "flag provided but not defined: -m"},
+		{[]string{"nosuch"}, ExitUsageError, `unknown subcommand "nosuch"`},
+		{nil, ExitUsageError, "no subcommand given"},
+		{[]string{"fail"}, ExitFailure, ""},
+	}
+	for _, tt := range tests {
+		cdr, _, _ := newTestCommander()
+		cdr.UsageErrors = UsageSilent
+		cdr.Register(&testCommand{name: "count", flags: func(f *flag.FlagSet) { f.Int("n", 0, "") }}, "")
+		cdr.Register(&testCommand{name: "fail", status: ExitFailure}, "")
+		if err := cdr.topFlags.Parse(tt.args); err != nil {
+			t.Fatal(err)
+		}
+		status, err := cdr.ExecuteE(context.Background())
+		if status != tt.wantStatus {
+			t.Errorf("%q: status %v, want %v", tt.args, status, tt.wantStatus)
+		}
+		if got := errString(err); got != tt.wantErr {
+			t.Errorf("%q: ExecuteE error %q, want %q", tt.args, got, tt.wantErr)
+		}
+		if cdr.LastError() != err {
+			t.Errorf("%q: LastError() = %v, want %v", tt.args, cdr.LastError(), err)
+		}
+		// The next execution forgets the error.
+		if cdr.Run(context.Background(), "count", nil); cdr.LastError() != nil {
+			t.Errorf("%q: LastError() after a later success = %v", tt.args, cdr.LastError())
+		}
+	}
+}
+
+// handlerCommand is a testCommand choosing its own FlagErrorHandling.
+type handlerCommand struct {
+	testCommand
+	handling flag.ErrorHandling
+}
+
+func (c *handlerCommand) FlagErrorHandling() flag.ErrorHandling { return c.handling }
+
+func TestFlagErrorHandling(t *testing.T) {
+	tests := []struct {
+		name      string
+		handling  flag.ErrorHandling // of the Commander
+		cmd       string
+		wantPanic bool
+	}{
+		{"continue", flag.ContinueOnError, "print", false},
+		{"panic", flag.PanicOnError, "print", true},
+		{"command continues", flag.PanicOnError, "calm", false},
+		{"command panics", flag.ContinueOnError, "strict", true},
+	}
+	for _, tt := range tests {
+		cdr, _, _ := newTestCommander()
+		cdr.UsageErrors = UsageSilent
+		cdr.FlagErrorHandling = tt.handling
+		cdr.Register(&handlerCommand{testCommand{name: "calm"}, flag.ContinueOnError}, "")
+		cdr.Register(&handlerCommand{testCommand{name: "strict"}, flag.PanicOnError}, "")
+		var recovered interface{}
+		status := func() ExitStatus {
+			defer func() { recovered = recover() }()
+			return cdr.Run(context.Background(), tt.cmd, []string{"-bogus"})
+		}()
+		if tt.wantPanic {
+			if err, ok := recovered.(error); !ok || err.Error() != "flag provided but not defined: -bogus" {
+				t.Errorf("%s: recovered %v, want the parse error", tt.name, recovered)
+			}
+		} else if recovered != nil || status != ExitUsageError {
+			t.Errorf("%s: status %v, recovered %v; want %v, nil", tt.name, status, recovered, ExitUsageError)
+		}
+	}
+}
+
+func TestFlagErrorHandlingExit(t *testing.T) {
+	if os.Getenv("SUBCOMMANDS_TEST_EXIT") != "" {
+		cdr, _, _ := newTestCommander()
+		cdr.FlagErrorHandling = flag.ExitOnError
+		cdr.Run(context.Background(), "print", []string{os.Getenv("SUBCOMMANDS_TEST_EXIT")})
+		os.Exit(99) // not reached
+	}
+	tests := []struct {
+		arg  string
+		want int
+	}{
+		{"-bogus", int(ExitUsageError)},
+		{"-help", 0},
+	}
+	for _, tt := range tests {
+		cmd := exec.Command(os.Args[0], "-test.run=^TestFlagErrorHandlingExit$")
+		cmd.Env = append(os.Environ(), "SUBCOMMANDS_TEST_EXIT="+tt.arg)
+		err := cmd.Run()
+		code := 0
+		if exitErr, ok := err.(*exec.ExitError); ok {
+			code = exitErr.ExitCode()
+		} else if err != nil {
+			t.Fatal(err)
+		}
+		if code != tt.want {
+			t.Errorf("%s: exited with %d, want %d", tt.arg, code, tt.want)
+		}
+	}
+}
+
+func TestSetFlagErrorFunc(t *testing.T) {
+	translate := func(cmd Command, err error) (string, ExitStatus) {
+		if err == flag.ErrHelp {
+			return "", ExitSuccess
+		}
+		return fmt.Sprintf("%s: bad flags (%v)", cmd.Name(), err), ExitCode(64)
+	}
+	tests := []struct {
+		name       string
+		fn         func(Command, error) (string, ExitStatus)
+		args       []string
+		wantStatus ExitStatus
+		wantStderr string
+	}{
+		{"message", translate, []string{"-bogus"}, ExitCode(64), "tool: print: bad flags (flag provided but not defined: -bogus)\n"},
+		{"help", translate, []string{"-h"}, ExitSuccess, ""},
+		{"default", nil, []string{"-bogus"}, ExitUsageError, "flag provided but not defined: -bogus\nprint:\n\tA command for tests.\n  -n\tno newline\n"},
+	}
+	for _, tt := range tests {
+		cdr, _, stderr := newTestCommander()
+		cdr.SetFlagErrorFunc(func(Command, error) (string, ExitStatus) { return "replaced", ExitFailure })
+		cdr.SetFlagErrorFunc(tt.fn)
+		if status := cdr.Run(context.Background(), "print", tt.args); status != tt.wantStatus {
+			t.Errorf("%s: status %v, want %v", tt.name, status, tt.wantStatus)
+		}
+		if got := stderr.String(); got != tt.wantStderr {
+			t.Errorf("%s: stderr %q, want %q", tt.name, got, tt.wantStderr)
+		}
+	}
+}
+
+// A resettableList is a stringList with a Reset method.
+type resettableList struct{ stringList }
+
+func (l *resettableList) Reset() { l.stringList = nil }
+
+func TestReset(t *testing.T) {
+	cdr, _, _ := newTestCommander()
+	cdr.UsageErrors = UsageSilent
+	verbose := cdr.topFlags.Bool("v", false, "")
+	n := cdr.topFlags.Int("n", 3, "")
+	var tags resettableList
+	cdr.topFlags.Var(&tags, "tag", "")
+
+	if status := execute(t, cdr, "-v", "-n=5", "-tag=a", "-tag=b", "nosuch"); status != ExitUsageError {
+		t.Fatalf("status %v, want %v", status, ExitUsageError)
+	}
+	if err := cdr.Reset(); err != nil {
+		t.Fatalf("Reset: %v", err)
+	}
+	if *verbose || *n != 3 || len(tags.stringList) != 0 {
+		t.Errorf("after Reset: -v %v, -n %d, -tag %q; want false, 3, []", *verbose, *n, tags.stringList)
+	}
+	cdr.topFlags.Visit(func(fl *flag.Flag) { t.Errorf("after Reset: -%s is set", fl.Name) })
+	if cdr.topFlags.Parsed() || cdr.topFlags.NArg() != 0 || cdr.LastError() != nil {
+		t.Errorf("after Reset: parsed %v, args %q, LastError %v", cdr.topFlags.Parsed(), cdr.topFlags.Args(), cdr.LastError())
+	}
+	if got := Provenance(cdr.topFlags, "v"); got != SourceDefault {
+		t.Errorf("after Reset: -v from %v, want %v", got, SourceDefault)
+	}
+
+	// The Commander executes with other arguments as if new.
+	if status := execute(t, cdr, "-tag=c", "print", "x"); status != ExitSuccess {
+		t.Errorf("after Reset: status %v, want %v", status, ExitSuccess)
+	}
+	if want := []string{"c"}; !reflect.DeepEqual([]string(tags.stringList), want) {
+		t.Errorf("after Reset: -tag %q, want %q", tags.stringList, want)
+	}
+}
+
+func TestResetError(t *testing.T) {
+	cdr, _, _ := newTestCommander()
+	var tags stringList
+	cdr.topFlags.Var(&tags, "tag", "")
+	cdr.topFlags.Bool("v", false, "")
+	execute(t, cdr, "-v", "-tag=a", "print")
+	want := `cannot reset flag -tag: value is "a," after setting the default ""; its value needs a Reset method`
+	if err := cdr.Reset(); errString(err) != want {
+		t.Errorf("Reset: %v, want %s", err, want)
+	}
+}
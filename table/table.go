@@ -0,0 +1,126 @@
+/*
+Copyright 2016 Google Inc. All Rights Reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package table renders rows of text as aligned columns, measuring text
+// by its display width and truncating cells that are too wide, in the
+// same style as the help output of a Commander.
+//
+//	t := table.New("NAME", "SIZE")
+//	t.Align = []table.Align{table.Left, table.Right}
+//	t.Append("a.txt", "12")
+//	t.Append("b.txt", "1024")
+//	t.Render(os.Stdout)
+package table
+
+import (
+	"io"
+	"strings"
+
+	"github.com/google/subcommands/internal/textwidth"
+)
+
+// An Align is the alignment of the cells of a column.
+type Align int
+
+const (
+	Left  Align = iota // Pad cells on the right.
+	Right              // Pad cells on the left.
+)
+
+// A Table holds rows of cells to render as aligned columns.
+type Table struct {
+	Header   []string // Header is the first row, if any.
+	Align    []Align  // Align holds the alignment of each column (default: Left).
+	MaxWidth []int    // MaxWidth limits the width of each column (default: 0, unlimited).
+	Gutter   int      // Gutter is the number of spaces between columns (default: 2).
+	Ellipsis string   // Ellipsis ends truncated cells (default: "...").
+
+	rows [][]string
+}
+
+// New returns a Table with the given header, which may be empty.
+func New(header ...string) *Table {
+	return &Table{
+		Header:   header,
+		Gutter:   2,
+		Ellipsis: "...",
+	}
+}
+
+// Append adds a row of cells to the table.
+func (t *Table) Append(cells ...string) {
+	t.rows = append(t.rows, cells)
+}
+
+// Render writes the table to w.
+func (t *Table) Render(w io.Writer) error {
+	rows := t.rows
+	if len(t.Header) > 0 {
+		rows = append([][]string{t.Header}, rows...)
+	}
+
+	var widths []int
+	cells := make([][]string, len(rows))
+	for i, row := range rows {
+		cells[i] = make([]string, len(row))
+		for j, cell := range row {
+			cell = t.truncate(j, cell)
+			cells[i][j] = cell
+			if j >= len(widths) {
+				widths = append(widths, 0)
+			}
+			if n := textwidth.String(cell); n > widths[j] {
+				widths[j] = n
+			}
+		}
+	}
+
+	gutter := strings.Repeat(" ", t.Gutter)
+	var b strings.Builder
+	for _, row := range cells {
+		var line strings.Builder
+		for j, cell := range row {
+			if j > 0 {
+				line.WriteString(gutter)
+			}
+			pad := strings.Repeat(" ", widths[j]-textwidth.String(cell))
+			if j < len(t.Align) && t.Align[j] == Right {
+				line.WriteString(pad + cell)
+			} else {
+				line.WriteString(cell + pad)
+			}
+		}
+		b.WriteString(strings.TrimRight(line.String(), " "))
+		b.WriteByte('\n')
+	}
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// truncate fits cell into the maximum width of column j, if it has one.
+func (t *Table) truncate(j int, cell string) string {
+	if j >= len(t.MaxWidth) || t.MaxWidth[j] <= 0 {
+		return cell
+	}
+	width := t.MaxWidth[j]
+	if textwidth.String(cell) <= width {
+		return cell
+	}
+	if textwidth.String(t.Ellipsis) >= width {
+		return textwidth.Truncate(cell, width)
+	}
+	return textwidth.Truncate(cell, width-textwidth.String(t.Ellipsis)) + t.Ellipsis
+}
@@ -0,0 +1,95 @@
+/*
+Copyright 2016 Google Inc. All Rights Reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package table
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestRender(t *testing.T) {
+	tests := []struct {
+		name  string
+		table func() *Table
+		want  string
+	}{
+		{"empty", func() *Table { return New() }, ""},
+		{"header only", func() *Table { return New("NAME", "SIZE") }, "NAME  SIZE\n"},
+		{"left aligned", func() *Table {
+			t := New("NAME", "SIZE")
+			t.Append("a.txt", "12")
+			t.Append("bigger.txt", "1024")
+			return t
+		}, "NAME        SIZE\na.txt       12\nbigger.txt  1024\n"},
+		{"right aligned", func() *Table {
+			t := New("NAME", "SIZE")
+			t.Align = []Align{Left, Right}
+			t.Append("a.txt", "12")
+			t.Append("b.txt", "1024")
+			return t
+		}, "NAME   SIZE\na.txt    12\nb.txt  1024\n"},
+		{"ragged rows", func() *Table {
+			t := New()
+			t.Append("a")
+			t.Append("bb", "c", "d")
+			t.Append("", "eee")
+			return t
+		}, "a\nbb  c    d\n    eee\n"},
+		{"wide characters", func() *Table {
+			t := New("NAME", "LANG")
+			t.Append("日本語", "ja")
+			t.Append("go", "en")
+			return t
+		}, "NAME    LANG\n日本語  ja\ngo      en\n"},
+		{"gutter", func() *Table {
+			t := New("A", "B")
+			t.Gutter = 1
+			t.Append("aa", "b")
+			return t
+		}, "A  B\naa b\n"},
+		{"truncated", func() *Table {
+			t := New("NAME", "DESCRIPTION")
+			t.MaxWidth = []int{0, 10}
+			t.Append("a", "a long description")
+			t.Append("b", "short")
+			return t
+		}, "NAME  DESCRIP...\na     a long ...\nb     short\n"},
+		{"truncated without room for the ellipsis", func() *Table {
+			t := New()
+			t.MaxWidth = []int{2}
+			t.Append("abcdef")
+			return t
+		}, "ab\n"},
+		{"custom ellipsis", func() *Table {
+			t := New()
+			t.MaxWidth = []int{4}
+			t.Ellipsis = "…"
+			t.Append("abcdef")
+			return t
+		}, "abc…\n"},
+	}
+	for _, tt := range tests {
+		var b strings.Builder
+		if err := tt.table().Render(&b); err != nil {
+			t.Errorf("%s: %v", tt.name, err)
+			continue
+		}
+		if got := b.String(); got != tt.want {
+			t.Errorf("%s: rendered\n%q\nwant\n%q", tt.name, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,123 @@
+/*
+Copyright 2016 Google Inc. All Rights Reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package subcommands
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// errTimedOut is the cause of the cancellation of a context whose
+// deadline was set by the flag defined by TimeoutFlag, or for the
+// command by RegisterWithTimeout or its Timeout method.
+var errTimedOut = errors.New("command timed out")
+
+// A Timeouter is a Command with a default time limit, as for a command
+// calling a service that should answer within seconds. Execute gives it
+// a context with that deadline, unless the flag defined by TimeoutFlag
+// is set.
+type Timeouter interface {
+	// Timeout returns the time limit of the command, or 0 for none.
+	Timeout() time.Duration
+}
+
+// TimeoutFlag defines a top-level duration flag with the given name,
+// conventionally "timeout". If it is set, Execute gives the subcommand a
+// context with that deadline, in place of any the subcommand has by
+// RegisterWithTimeout or Timeouter, and -timeout=0 removes the limit;
+// if the deadline passes before the subcommand returns, Execute prints
+// "command timed out after" the duration and returns ExitTimeout. It
+// must be called before the top-level flags are parsed.
+func (cdr *Commander) TimeoutFlag(name string) {
+	cdr.timeoutFlag = name
+	cdr.topFlags.DurationVar(&cdr.timeout, name, 0, "stop the subcommand after this `duration` (default: no limit)")
+}
+
+// RegisterWithTimeout registers cmd in group as Register does, with the
+// time limit d, which overrides that of its Timeout method if it is a
+// Timeouter.
+//
+//	cdr.RegisterWithTimeout(&fetchCmd{}, "", 30*time.Second)
+func (cdr *Commander) RegisterWithTimeout(cmd Command, group string, d time.Duration) {
+	if cdr.timeouts == nil {
+		cdr.timeouts = make(map[Command]time.Duration)
+	}
+	cdr.timeouts[cmd] = d
+	cdr.Register(cmd, group)
+}
+
+// timeoutOf returns the time limit of cmd, or of the command it stands
+// for if it is an alias, given to RegisterWithTimeout or by its Timeout
+// method, or else 0.
+func (cdr *Commander) timeoutOf(cmd Command) time.Duration {
+	if d, ok := cdr.timeouts[cmd]; ok {
+		return d
+	}
+	if d, ok := cdr.timeouts[dealias(cmd)]; ok {
+		return d
+	}
+	if t, ok := dealias(cmd).(Timeouter); ok {
+		return t.Timeout()
+	}
+	return 0
+}
+
+// timeLimit returns the value of the flag defined by TimeoutFlag, if it
+// was set by any source, or else d. It must be called after the flags
+// are applied.
+func (cdr *Commander) timeLimit(d time.Duration) time.Duration {
+	if cdr.timeoutFlag != "" && Provenance(cdr.topFlags, cdr.timeoutFlag) != SourceDefault {
+		return cdr.timeout
+	}
+	return d
+}
+
+// withTimeout returns ctx with the deadline d from now, if d is
+// positive, and a function reporting whether it passed.
+func withTimeout(ctx context.Context, d time.Duration) (context.Context, context.CancelFunc, func() bool) {
+	if d <= 0 {
+		return ctx, func() {}, func() bool { return false }
+	}
+	ctx, cancel := context.WithTimeoutCause(ctx, d, errTimedOut)
+	return ctx, cancel, func() bool { return context.Cause(ctx) == errTimedOut }
+}
+
+// timedOut reports that cmd timed out, and returns ExitTimeout.
+func (cdr *Commander) timedOut(cmd Command, d time.Duration) ExitStatus {
+	cdr.last.Reason, cdr.last.Err = CommandError, fmt.Errorf("%w after %v", errTimedOut, d)
+	cdr.Errorf("%s: %v", cmd.Name(), cdr.last.Err)
+	return ExitTimeout
+}
+
+// canceled reports that cmd failed after ctx was canceled, with the
+// cause of the cancellation, and returns ExitTimeout if its deadline
+// passed, ExitInterrupted if a signal canceled it, or else ExitCanceled.
+func (cdr *Commander) canceled(ctx context.Context, cmd Command) ExitStatus {
+	cause := context.Cause(ctx)
+	cdr.last.Reason, cdr.last.Err = CommandError, cause
+	cdr.Errorf("%s: %v", cmd.Name(), cause)
+	var sig signalError
+	switch {
+	case errors.Is(cause, context.DeadlineExceeded):
+		return ExitTimeout
+	case errors.As(cause, &sig):
+		return ExitInterrupted
+	}
+	return ExitCanceled
+}
@@ -0,0 +1,196 @@
+/*
+Copyright 2016 Google Inc. All Rights Reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package subcommands
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"syscall"
+	"testing"
+	"time"
+)
+
+// waitCommand returns a testCommand named name that waits for its
+// context to be done, if it has a deadline, and fails when it is.
+func waitCommand(name string) *testCommand {
+	return &testCommand{name: name, execute: func(ctx context.Context, f *flag.FlagSet) ExitStatus {
+		if _, ok := ctx.Deadline(); !ok {
+			return ExitSuccess
+		}
+		<-ctx.Done()
+		return ExitFailure
+	}}
+}
+
+func TestTimeoutFlag(t *testing.T) {
+	tests := []struct {
+		args       []string
+		wantStatus ExitStatus
+		wantStderr string
+	}{
+		{[]string{"wait"}, ExitSuccess, ""},
+		{[]string{"-timeout=10ms", "wait"}, ExitTimeout, "tool: wait: command timed out after 10ms\n"},
+		{[]string{"-timeout=0", "wait"}, ExitSuccess, ""},
+		{[]string{"-timeout=1h", "print"}, ExitSuccess, ""},
+	}
+	for _, tt := range tests {
+		cdr, _, stderr := newTestCommander()
+		cdr.TimeoutFlag("timeout")
+		cdr.Register(waitCommand("wait"), "")
+		if status := execute(t, cdr, tt.args...); status != tt.wantStatus {
+			t.Errorf("%q: status %v, want %v", tt.args, status, tt.wantStatus)
+		}
+		if got := stderr.String(); got != tt.wantStderr {
+			t.Errorf("%q: stderr %q, want %q", tt.args, got, tt.wantStderr)
+		}
+	}
+}
+
+// timeoutCommand is a waitCommand with a time limit.
+type timeoutCommand struct {
+	*testCommand
+	timeout time.Duration
+}
+
+func (c *timeoutCommand) Timeout() time.Duration { return c.timeout }
+
+func TestRegisterWithTimeout(t *testing.T) {
+	tests := []struct {
+		name       string
+		timeout    time.Duration // of the Timeout method, if not 0
+		register   time.Duration // given to RegisterWithTimeout, if not negative
+		args       []string
+		wantStatus ExitStatus
+		wantStderr string
+	}{
+		{"none", 0, -1, []string{"wait"}, ExitSuccess, ""},
+		{"Timeouter", 10 * time.Millisecond, -1, []string{"wait"}, ExitTimeout, "tool: wait: command timed out after 10ms\n"},
+		{"Timeouter through alias", 10 * time.Millisecond, -1, []string{"w"}, ExitTimeout, "tool: w: command timed out after 10ms\n"},
+		{"registered", 0, 10 * time.Millisecond, []string{"wait"}, ExitTimeout, "tool: wait: command timed out after 10ms\n"},
+		{"registered through alias", 0, 10 * time.Millisecond, []string{"w"}, ExitTimeout, "tool: w: command timed out after 10ms\n"},
+		{"registered overrides Timeouter", time.Hour, 10 * time.Millisecond, []string{"wait"}, ExitTimeout, "tool: wait: command timed out after 10ms\n"},
+		{"registered without limit", 10 * time.Millisecond, 0, []string{"wait"}, ExitSuccess, ""},
+		{"flag overrides", time.Hour, -1, []string{"-timeout=10ms", "wait"}, ExitTimeout, "tool: wait: command timed out after 10ms\n"},
+		{"flag removes limit", 10 * time.Millisecond, 10 * time.Millisecond, []string{"-timeout=0", "wait"}, ExitSuccess, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cdr, _, stderr := newTestCommander()
+			cdr.TimeoutFlag("timeout")
+			var cmd Command = waitCommand("wait")
+			if tt.timeout != 0 {
+				cmd = &timeoutCommand{cmd.(*testCommand), tt.timeout}
+			}
+			if tt.register >= 0 {
+				cdr.RegisterWithTimeout(cmd, "", tt.register)
+			} else {
+				cdr.Register(cmd, "")
+			}
+			cdr.Register(Alias("w", cmd), "")
+			if status := execute(t, cdr, tt.args...); status != tt.wantStatus {
+				t.Errorf("status %v, want %v", status, tt.wantStatus)
+			}
+			if got := stderr.String(); got != tt.wantStderr {
+				t.Errorf("stderr %q, want %q", got, tt.wantStderr)
+			}
+		})
+	}
+}
+
+func TestCanceled(t *testing.T) {
+	tests := []struct {
+		name       string
+		ctx        func() (context.Context, context.CancelFunc)
+		status     ExitStatus // returned by the command
+		wantStatus ExitStatus
+		wantStderr string
+	}{
+		{
+			name: "canceled",
+			ctx: func() (context.Context, context.CancelFunc) {
+				ctx, cancel := context.WithCancel(context.Background())
+				cancel()
+				return ctx, cancel
+			},
+			status:     ExitFailure,
+			wantStatus: ExitCanceled,
+			wantStderr: "tool: wait: context canceled\n",
+		},
+		{
+			name: "canceled with cause",
+			ctx: func() (context.Context, context.CancelFunc) {
+				ctx, cancel := context.WithCancelCause(context.Background())
+				cancel(errors.New("server shutting down"))
+				return ctx, func() {}
+			},
+			status:     ExitFailure,
+			wantStatus: ExitCanceled,
+			wantStderr: "tool: wait: server shutting down\n",
+		},
+		{
+			name: "signal",
+			ctx: func() (context.Context, context.CancelFunc) {
+				ctx, cancel := context.WithCancelCause(context.Background())
+				cancel(signalError{syscall.SIGINT})
+				return ctx, func() {}
+			},
+			status:     ExitFailure,
+			wantStatus: ExitInterrupted,
+			wantStderr: "tool: wait: canceled by SIGINT\n",
+		},
+		{
+			name: "deadline",
+			ctx: func() (context.Context, context.CancelFunc) {
+				return context.WithTimeout(context.Background(), -time.Second)
+			},
+			status:     ExitFailure,
+			wantStatus: ExitTimeout,
+			wantStderr: "tool: wait: context deadline exceeded\n",
+		},
+		{
+			name: "succeeded anyway",
+			ctx: func() (context.Context, context.CancelFunc) {
+				ctx, cancel := context.WithCancel(context.Background())
+				cancel()
+				return ctx, cancel
+			},
+			status:     ExitSuccess,
+			wantStatus: ExitSuccess,
+		},
+		{
+			name:       "not canceled",
+			ctx:        func() (context.Context, context.CancelFunc) { return context.Background(), func() {} },
+			status:     ExitFailure,
+			wantStatus: ExitFailure,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cdr, _, stderr := newTestCommander()
+			cdr.Register(&testCommand{name: "wait", status: tt.status}, "")
+			ctx, cancel := tt.ctx()
+			defer cancel()
+			if status := cdr.Run(ctx, "wait", nil); status != tt.wantStatus {
+				t.Errorf("status %v, want %v", status, tt.wantStatus)
+			}
+			if got := stderr.String(); got != tt.wantStderr {
+				t.Errorf("stderr %q, want %q", got, tt.wantStderr)
+			}
+		})
+	}
+}
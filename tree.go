@@ -0,0 +1,113 @@
+/*
+Copyright 2016 Google Inc. All Rights Reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package subcommands
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+)
+
+// A treePrinter is a Command implementing a "tree" command for a given
+// Commander.
+type treePrinter struct {
+	cdr      *Commander
+	synopses bool
+}
+
+func (t *treePrinter) Name() string     { return "tree" }
+func (t *treePrinter) Synopsis() string { return "show the hierarchy of subcommands" }
+func (t *treePrinter) SetFlags(f *flag.FlagSet) {
+	f.BoolVar(&t.synopses, "synopsis", false, "show the synopsis of each subcommand")
+}
+func (t *treePrinter) Usage() string {
+	return `tree [-synopsis]:
+	Print every subcommand as a tree: the commands of each group, and
+	the subcommands of each command that has them. Aliases are marked
+	with the command they stand for.
+`
+}
+
+// A treeNode is a line of the tree printed by a treePrinter.
+type treeNode struct {
+	label    string
+	children []*treeNode
+}
+
+func (t *treePrinter) Execute(ctx context.Context, f *flag.FlagSet, _ ...interface{}) ExitStatus {
+	if f.NArg() != 0 {
+		f.Usage()
+		return ExitUsageError
+	}
+	cdr := t.cdr.executing(ctx)
+	root := cdr.tree(t.synopses)
+	fmt.Fprintln(cdr.Output, root.label)
+	printTree(cdr.Output, root.children, "")
+	return ExitSuccess
+}
+
+// tree returns the hierarchy of the visible subcommands of cdr, with
+// their synopses if synopses is set. The commands of the unnamed group
+// are children of the root; those of other groups are children of a
+// node for the group.
+func (cdr *Commander) tree(synopses bool) *treeNode {
+	root := &treeNode{label: cdr.DisplayName()}
+	cdr.loadGroups()
+	cdr.VisitGroups(func(group *CommandGroup) {
+		parent := root
+		for _, cmd := range cdr.listOrder(group) {
+			if parent == root && group.name != "" {
+				parent = &treeNode{label: "[" + group.name + "]"}
+				root.children = append(root.children, parent)
+			}
+			node := &treeNode{label: cmd.Name()}
+			if a, ok := cmd.(*aliaser); ok {
+				node.label += " -> " + dealias(a).Name()
+			} else if synopses {
+				if s := cdr.synopsis(cmd); s != "" {
+					node.label += " - " + s
+				}
+			}
+			if m := mounted(cmd); m != nil {
+				node.children = m.tree(synopses).children
+			}
+			parent.children = append(parent.children, node)
+		}
+	})
+	return root
+}
+
+// printTree prints nodes, each line preceded by prefix and lines
+// connecting it to its parent.
+func printTree(w io.Writer, nodes []*treeNode, prefix string) {
+	for i, node := range nodes {
+		branch, indent := "├── ", "│   "
+		if i == len(nodes)-1 {
+			branch, indent = "└── ", "    "
+		}
+		fmt.Fprintf(w, "%s%s%s\n", prefix, branch, node.label)
+		printTree(w, node.children, prefix+indent)
+	}
+}
+
+// TreeCommand returns a Command which implements a "tree" subcommand,
+// which prints the hierarchy of subcommands, including those of mounted
+// Commanders.
+func (cdr *Commander) TreeCommand() Command {
+	return &treePrinter{cdr: cdr}
+}
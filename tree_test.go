@@ -0,0 +1,83 @@
+/*
+Copyright 2016 Google Inc. All Rights Reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package subcommands
+
+import (
+	"context"
+	"flag"
+	"testing"
+)
+
+func TestTree(t *testing.T) {
+	tests := []struct {
+		args       []string
+		wantStatus ExitStatus
+		want       string
+	}{
+		{
+			args:       nil,
+			wantStatus: ExitSuccess,
+			want: "" +
+				"tool\n" +
+				"├── p -> print\n" +
+				"├── print\n" +
+				"├── remote\n" +
+				"│   ├── add\n" +
+				"│   └── rm\n" +
+				"├── tree\n" +
+				"└── [admin]\n" +
+				"    └── purge\n",
+		},
+		{
+			args:       []string{"-synopsis"},
+			wantStatus: ExitSuccess,
+			want: "" +
+				"tool\n" +
+				"├── p -> print\n" +
+				"├── print - print args\n" +
+				"├── remote - remote subcommands\n" +
+				"│   ├── add - add a remote\n" +
+				"│   └── rm - remove a remote\n" +
+				"├── tree - show the hierarchy of subcommands\n" +
+				"└── [admin]\n" +
+				"    └── purge - delete everything\n",
+		},
+		{
+			args:       []string{"extra"},
+			wantStatus: ExitUsageError,
+		},
+	}
+	for _, tt := range tests {
+		cdr, stdout, _ := newTestCommander()
+		cdr.Register(cdr.TreeCommand(), "")
+		print := cdr.Lookup("print")
+		cdr.Register(Alias("p", print), "")
+		remote := NewCommander(flag.NewFlagSet("remote", flag.ContinueOnError), "remote")
+		remote.Register(&testCommand{name: "add", synopsis: "add a remote"}, "")
+		remote.Register(&testCommand{name: "rm", synopsis: "remove a remote"}, "")
+		remote.Register(&testCommand{name: "__internal"}, "")
+		cdr.Mount("remote", remote, "")
+		cdr.Register(&testCommand{name: "purge", synopsis: "delete everything"}, "admin")
+
+		if status := cdr.Run(context.Background(), "tree", tt.args); status != tt.wantStatus {
+			t.Errorf("%q: status %v, want %v", tt.args, status, tt.wantStatus)
+		}
+		if got := stdout.String(); got != tt.want {
+			t.Errorf("%q: printed\n%s\nwant\n%s", tt.args, got, tt.want)
+		}
+	}
+}
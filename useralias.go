@@ -0,0 +1,135 @@
+/*
+Copyright 2016 Google Inc. All Rights Reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package subcommands
+
+import (
+	"bufio"
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+)
+
+// LoadAliases reads user-defined aliases, in the style of git aliases,
+// from the file at path. Each line of the file has the form
+//
+//	name = subcommand [args...]
+//
+// and blank lines and lines starting with '#' are ignored. When Execute
+// is given a subcommand name that is not registered but is an alias, it
+// replaces the name with the alias's expansion before dispatch. A
+// missing file is not an error, so that the file can be optional.
+func (cdr *Commander) LoadAliases(path string) error {
+	file, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	if cdr.userAliases == nil {
+		cdr.userAliases = make(map[string][]string)
+	}
+	scanner := bufio.NewScanner(file)
+	for n := 1; scanner.Scan(); n++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		i := strings.Index(line, "=")
+		if i < 0 {
+			return fmt.Errorf("%s:%d: expected \"name = subcommand [args...]\"", path, n)
+		}
+		name, expansion := strings.TrimSpace(line[:i]), strings.Fields(line[i+1:])
+		if name == "" || strings.ContainsAny(name, " \t") {
+			return fmt.Errorf("%s:%d: invalid alias name %q", path, n, name)
+		}
+		if len(expansion) == 0 {
+			return fmt.Errorf("%s:%d: alias %s has an empty expansion", path, n, name)
+		}
+		cdr.userAliases[name] = expansion
+	}
+	if err := scanner.Err(); err != nil {
+		return fmt.Errorf("%s: %v", path, err)
+	}
+	return nil
+}
+
+// expandAlias replaces a user-defined alias at the start of args with
+// its expansion. Registered subcommands take precedence over aliases,
+// and expansions are not themselves expanded.
+func (cdr *Commander) expandAlias(args []string) []string {
+	if len(args) == 0 || cdr.Lookup(args[0]) != nil {
+		return args
+	}
+	expansion, ok := cdr.userAliases[args[0]]
+	if !ok {
+		return args
+	}
+	return append(append([]string(nil), expansion...), args[1:]...)
+}
+
+// An aliasLister is a Command implementing an "alias" command for a
+// given Commander.
+type aliasLister Commander
+
+func (al *aliasLister) Name() string           { return "alias" }
+func (al *aliasLister) Synopsis() string       { return "list user-defined aliases" }
+func (al *aliasLister) SetFlags(*flag.FlagSet) {}
+func (al *aliasLister) Usage() string {
+	return `alias [<name>]:
+	With an argument, print the expansion of the named alias. Else,
+	print all user-defined aliases.
+`
+}
+func (al *aliasLister) Execute(ctx context.Context, f *flag.FlagSet, _ ...interface{}) ExitStatus {
+	cdr := (*Commander)(al).executing(ctx)
+	switch f.NArg() {
+	case 0:
+		names := make([]string, 0, len(cdr.userAliases))
+		for name := range cdr.userAliases {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			fmt.Fprintf(cdr.Output, "%s = %s\n", name, strings.Join(cdr.userAliases[name], " "))
+		}
+		return ExitSuccess
+
+	case 1:
+		expansion, ok := cdr.userAliases[f.Arg(0)]
+		if !ok {
+			cdr.Errorf("alias: %s not defined", f.Arg(0))
+			return ExitFailure
+		}
+		fmt.Fprintln(cdr.Output, strings.Join(expansion, " "))
+		return ExitSuccess
+	}
+
+	f.Usage()
+	return ExitUsageError
+}
+
+// AliasesCommand returns a Command which implements an "alias"
+// subcommand listing the aliases loaded by LoadAliases.
+func (cdr *Commander) AliasesCommand() Command {
+	return (*aliasLister)(cdr)
+}
@@ -0,0 +1,146 @@
+/*
+Copyright 2016 Google Inc. All Rights Reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package subcommands
+
+import (
+	"context"
+	"flag"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+// writeFile writes content to a file named name in a temporary
+// directory, and returns its path.
+func writeFile(t *testing.T, name, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, []byte(content), 0o666); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestLoadAliases(t *testing.T) {
+	tests := []struct {
+		content string
+		want    map[string][]string
+		wantErr string
+	}{
+		{"", map[string][]string{}, ""},
+		{"# comment\n\np = print -n\n  q=print  \n", map[string][]string{"p": {"print", "-n"}, "q": {"print"}}, ""},
+		{"p = print\np = print -n\n", map[string][]string{"p": {"print", "-n"}}, ""},
+		{"p print\n", nil, ":1: expected \"name = subcommand [args...]\""},
+		{"\n= print\n", nil, ":2: invalid alias name \"\""},
+		{"a b = print\n", nil, ":1: invalid alias name \"a b\""},
+		{"p =\n", nil, ":1: alias p has an empty expansion"},
+	}
+	for _, tt := range tests {
+		path := writeFile(t, "aliases", tt.content)
+		cdr := NewCommander(flag.NewFlagSet("tool", flag.ContinueOnError), "tool")
+		err := cdr.LoadAliases(path)
+		if tt.wantErr != "" {
+			if err == nil || err.Error() != path+tt.wantErr {
+				t.Errorf("%q: error %v, want %s%s", tt.content, err, path, tt.wantErr)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%q: %v", tt.content, err)
+			continue
+		}
+		if got := cdr.userAliases; !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%q: aliases %q, want %q", tt.content, got, tt.want)
+		}
+	}
+
+	cdr := NewCommander(flag.NewFlagSet("tool", flag.ContinueOnError), "tool")
+	if err := cdr.LoadAliases(filepath.Join(t.TempDir(), "missing")); err != nil {
+		t.Errorf("missing file: %v", err)
+	}
+}
+
+func TestUserAliasExecute(t *testing.T) {
+	tests := []struct {
+		args       []string
+		wantStatus ExitStatus
+		wantRan    string
+		wantArgs   []string
+	}{
+		{[]string{"p", "x"}, ExitSuccess, "print", []string{"x"}},
+		{[]string{"pn", "x", "y"}, ExitSuccess, "print", []string{"x", "y"}},
+		{[]string{"list"}, ExitSuccess, "list", []string{}}, // registered commands win
+		{[]string{"loop"}, ExitUsageError, "", nil},         // expansions are not expanded
+	}
+	for _, tt := range tests {
+		path := writeFile(t, "aliases", "p = print\npn = print -n\nlist = print\nloop = p\n")
+		cdr, _, _ := newTestCommander()
+		cdr.UsageErrors = UsageSilent
+		print := cdr.lookup("print").(*testCommand)
+		list := &testCommand{name: "list"}
+		cdr.Register(list, "")
+		if err := cdr.LoadAliases(path); err != nil {
+			t.Fatal(err)
+		}
+		if status := execute(t, cdr, tt.args...); status != tt.wantStatus {
+			t.Errorf("%q: status %v, want %v", tt.args, status, tt.wantStatus)
+		}
+		ran := ""
+		for _, cmd := range []*testCommand{print, list} {
+			if cmd.runs > 0 {
+				ran = cmd.name
+				if !reflect.DeepEqual(cmd.args, tt.wantArgs) {
+					t.Errorf("%q: %s args %q, want %q", tt.args, cmd.name, cmd.args, tt.wantArgs)
+				}
+			}
+		}
+		if ran != tt.wantRan {
+			t.Errorf("%q: ran %q, want %q", tt.args, ran, tt.wantRan)
+		}
+	}
+}
+
+func TestAliasesCommand(t *testing.T) {
+	tests := []struct {
+		args       []string
+		wantStatus ExitStatus
+		wantStdout string
+		wantStderr string
+	}{
+		{nil, ExitSuccess, "p = print\npn = print -n\n", ""},
+		{[]string{"pn"}, ExitSuccess, "print -n\n", ""},
+		{[]string{"nosuch"}, ExitFailure, "", "tool: alias: nosuch not defined\n"},
+	}
+	for _, tt := range tests {
+		path := writeFile(t, "aliases", "pn = print -n\np = print\n")
+		cdr, stdout, stderr := newTestCommander()
+		cdr.Register(cdr.AliasesCommand(), "")
+		if err := cdr.LoadAliases(path); err != nil {
+			t.Fatal(err)
+		}
+		if status := cdr.Run(context.Background(), "alias", tt.args); status != tt.wantStatus {
+			t.Errorf("alias %q: status %v, want %v", tt.args, status, tt.wantStatus)
+		}
+		if got := stdout.String(); got != tt.wantStdout {
+			t.Errorf("alias %q: stdout %q, want %q", tt.args, got, tt.wantStdout)
+		}
+		if got := stderr.String(); got != tt.wantStderr {
+			t.Errorf("alias %q: stderr %q, want %q", tt.args, got, tt.wantStderr)
+		}
+	}
+}
@@ -0,0 +1,105 @@
+/*
+Copyright 2016 Google Inc. All Rights Reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package subcommands
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"sync"
+)
+
+// A Validator is a Command with constraints spanning several of its
+// flags, such as "-from requires -to" or "exactly one of -a and -b".
+type Validator interface {
+	// Validate is called with the flags of the command after they are
+	// parsed and pass the checks added by ValidateFlag, and before
+	// Execute. An error is reported as a usage error, like a flag that
+	// fails to parse, and the command is not executed.
+	Validate(f *flag.FlagSet) error
+}
+
+// A flagValidator is a check of the value of a flag added by
+// ValidateFlag.
+type flagValidator struct {
+	name  string
+	check func(string) error
+}
+
+// validators records the checks added by ValidateFlag, in the order
+// they were added.
+var validators = struct {
+	sync.Mutex
+	m map[*flag.FlagSet][]flagValidator
+}{m: make(map[*flag.FlagSet][]flagValidator)}
+
+// ValidateFlag adds check to the checks of the named flag in f. After
+// the flags of a command are parsed, and set from the environment and
+// config files, the Commander calls check with the value of the flag,
+// whether or not it was set, and the value is rejected if check returns
+// an error. Every check is run, and all the values rejected are
+// reported together as a usage error, so that a user can fix them at
+// once. Commands call it from SetFlags:
+//
+//	func (c *fetchCmd) SetFlags(f *flag.FlagSet) {
+//		f.IntVar(&c.count, "count", 1, "number of items to fetch")
+//		subcommands.ValidateFlag(f, "count", func(v string) error {
+//			if n, _ := strconv.Atoi(v); n < 1 {
+//				return errors.New("must be at least 1")
+//			}
+//			return nil
+//		})
+//	}
+func ValidateFlag(f *flag.FlagSet, name string, check func(value string) error) {
+	validators.Lock()
+	defer validators.Unlock()
+	validators.m[f] = append(validators.m[f], flagValidator{name, check})
+}
+
+// validateFlags runs the checks added to f by ValidateFlag and returns
+// the values they reject, joined, or nil if there are none.
+func validateFlags(f *flag.FlagSet) error {
+	validators.Lock()
+	checks := validators.m[f]
+	validators.Unlock()
+	var errs []error
+	for _, v := range checks {
+		fl := f.Lookup(v.name)
+		if fl == nil {
+			errs = append(errs, fmt.Errorf("cannot validate undefined flag -%s", v.name))
+			continue
+		}
+		value := fl.Value.String()
+		if err := v.check(value); err != nil {
+			errs = append(errs, fmt.Errorf("invalid value %q for flag -%s: %v", shown(f, v.name, value), v.name, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// validate returns the values in f rejected by the checks added by
+// ValidateFlag or, if there are none, the error returned by the Validate
+// method of cmd, if it is a Validator.
+func validate(cmd Command, f *flag.FlagSet) error {
+	if err := validateFlags(f); err != nil {
+		return err
+	}
+	if v, ok := dealias(cmd).(Validator); ok {
+		return v.Validate(f)
+	}
+	return nil
+}
@@ -0,0 +1,130 @@
+/*
+Copyright 2016 Google Inc. All Rights Reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package subcommands
+
+import (
+	"errors"
+	"flag"
+	"strconv"
+	"testing"
+)
+
+func TestValidateFlag(t *testing.T) {
+	positive := func(v string) error {
+		if n, _ := strconv.Atoi(v); n < 1 {
+			return errors.New("must be at least 1")
+		}
+		return nil
+	}
+	tests := []struct {
+		args       []string
+		wantStatus ExitStatus
+		wantStderr string
+	}{
+		{[]string{"fetch", "-count=2", "-retries=1"}, ExitSuccess, ""},
+		{[]string{"fetch", "-count=0", "-retries=1"}, ExitUsageError, "tool: fetch: invalid value \"0\" for flag -count: must be at least 1\n"},
+		// A default is checked too, and every rejected value is reported.
+		{[]string{"fetch", "-count=0"}, ExitUsageError, "tool: fetch: invalid value \"0\" for flag -count: must be at least 1\ntool: fetch: invalid value \"0\" for flag -retries: must be at least 1\n"},
+		{[]string{"typo"}, ExitUsageError, "tool: typo: cannot validate undefined flag -cuont\n"},
+	}
+	for _, tt := range tests {
+		cdr, _, stderr := newTestCommander()
+		cdr.UsageErrors = UsageLine
+		fetch := &testCommand{name: "fetch", flags: func(f *flag.FlagSet) {
+			f.Int("count", 1, "")
+			f.Int("retries", 0, "")
+			ValidateFlag(f, "count", positive)
+			ValidateFlag(f, "retries", positive)
+		}}
+		cdr.Register(fetch, "")
+		cdr.Register(&testCommand{name: "typo", flags: func(f *flag.FlagSet) {
+			f.Int("count", 1, "")
+			ValidateFlag(f, "cuont", positive)
+		}}, "")
+		if status := execute(t, cdr, tt.args...); status != tt.wantStatus {
+			t.Errorf("%q: status %v, want %v", tt.args, status, tt.wantStatus)
+		}
+		if ran := fetch.runs > 0; ran != (tt.wantStatus == ExitSuccess) {
+			t.Errorf("%q: ran %v", tt.args, ran)
+		}
+		if got := stderr.String(); got != tt.wantStderr {
+			t.Errorf("%q: stderr %q, want %q", tt.args, got, tt.wantStderr)
+		}
+	}
+}
+
+// rangeCommand is a testCommand whose -from flag requires -to.
+type rangeCommand struct {
+	testCommand
+}
+
+func (c *rangeCommand) Validate(f *flag.FlagSet) error {
+	if isSet(f, "from") && !isSet(f, "to") {
+		return errors.New("-from requires -to")
+	}
+	return nil
+}
+
+func TestValidator(t *testing.T) {
+	tests := []struct {
+		args       []string
+		wantStatus ExitStatus
+		wantStderr string
+	}{
+		{[]string{"log"}, ExitSuccess, ""},
+		{[]string{"log", "-from=1", "-to=2"}, ExitSuccess, ""},
+		{[]string{"log", "-from=1"}, ExitUsageError, "tool: log: -from requires -to\n"},
+		{[]string{"history", "-from=1"}, ExitUsageError, "tool: history: -from requires -to\n"},
+		// The checks of ValidateFlag come first.
+		{[]string{"log", "-from=0"}, ExitUsageError, "tool: log: invalid value \"0\" for flag -from: must not be 0\n"},
+	}
+	for _, tt := range tests {
+		cdr, _, stderr := newTestCommander()
+		cdr.UsageErrors = UsageLine
+		log := &rangeCommand{testCommand{name: "log", flags: func(f *flag.FlagSet) {
+			f.Int("from", 0, "")
+			f.Int("to", 0, "")
+			ValidateFlag(f, "from", func(v string) error {
+				if v == "0" && isSet(f, "from") {
+					return errors.New("must not be 0")
+				}
+				return nil
+			})
+		}}}
+		cdr.Register(log, "")
+		cdr.Register(Alias("history", log), "")
+		status := execute(t, cdr, tt.args...)
+		if status != tt.wantStatus {
+			t.Errorf("%q: status %v, want %v", tt.args, status, tt.wantStatus)
+		}
+		if got := stderr.String(); got != tt.wantStderr {
+			t.Errorf("%q: stderr %q, want %q", tt.args, got, tt.wantStderr)
+		}
+		if status != ExitSuccess {
+			if r := cdr.last.Reason; r != FlagError {
+				t.Errorf("%q: reason %v, want %v", tt.args, r, FlagError)
+			}
+		}
+	}
+}
+
+// isSet reports whether the named flag in f was set.
+func isSet(f *flag.FlagSet, name string) bool {
+	set := false
+	f.Visit(func(fl *flag.Flag) { set = set || fl.Name == name })
+	return set
+}
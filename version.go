@@ -0,0 +1,117 @@
+/*
+Copyright 2016 Google Inc. All Rights Reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package subcommands
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"runtime/debug"
+	"text/tabwriter"
+)
+
+// A versioner is a Command implementing a "version" command for a given
+// Commander.
+type versioner struct {
+	cdr    *Commander
+	format string
+}
+
+func (v *versioner) Name() string     { return "version" }
+func (v *versioner) Synopsis() string { return "print the version of this program" }
+func (v *versioner) SetFlags(f *flag.FlagSet) {
+	f.StringVar(&v.format, "format", "text", "print as `text` or json")
+	ValidateFlag(f, "format", func(s string) error {
+		if s != "text" && s != "json" {
+			return errors.New("want text or json")
+		}
+		return nil
+	})
+}
+func (v *versioner) Usage() string {
+	return `version [-format text|json]:
+	Print the version of the module this program was built from, the
+	revision of its source and whether it had uncommitted changes, and
+	the version of Go it was built with, as recorded by the go command.
+`
+}
+
+// A buildVersion describes how a program was built.
+type buildVersion struct {
+	Module   string `json:"module,omitempty"`
+	Version  string `json:"version,omitempty"`
+	Revision string `json:"revision,omitempty"`
+	Time     string `json:"time,omitempty"`
+	Modified bool   `json:"modified"`
+	Go       string `json:"go"`
+}
+
+func (v *versioner) Execute(ctx context.Context, f *flag.FlagSet, _ ...interface{}) ExitStatus {
+	cdr := v.cdr.executing(ctx)
+	info, ok := debug.ReadBuildInfo()
+	if !ok {
+		cdr.Errorf("version: no build information in this program")
+		return ExitFailure
+	}
+	bv := buildVersion{Module: info.Main.Path, Version: info.Main.Version, Go: info.GoVersion}
+	for _, s := range info.Settings {
+		switch s.Key {
+		case "vcs.revision":
+			bv.Revision = s.Value
+		case "vcs.time":
+			bv.Time = s.Value
+		case "vcs.modified":
+			bv.Modified = s.Value == "true"
+		}
+	}
+
+	if v.format == "json" {
+		enc := json.NewEncoder(cdr.Output)
+		enc.SetIndent("", "  ")
+		if err := enc.Encode(bv); err != nil {
+			cdr.Errorf("version: %v", err)
+			return ExitFailure
+		}
+		return ExitSuccess
+	}
+	fmt.Fprintf(cdr.Output, "%s %s\n", cdr.DisplayName(), bv.Version)
+	tw := tabwriter.NewWriter(cdr.Output, 0, 8, 1, ' ', 0)
+	fmt.Fprintf(tw, "  module:\t%s\n", bv.Module)
+	if bv.Revision != "" {
+		revision := bv.Revision
+		if bv.Modified {
+			revision += " (modified)"
+		}
+		fmt.Fprintf(tw, "  revision:\t%s\n", revision)
+	}
+	if bv.Time != "" {
+		fmt.Fprintf(tw, "  time:\t%s\n", bv.Time)
+	}
+	fmt.Fprintf(tw, "  go:\t%s\n", bv.Go)
+	tw.Flush()
+	return ExitSuccess
+}
+
+// VersionCommand returns a Command which implements a "version"
+// subcommand, which prints the version of the program as recorded in
+// its build information by the go command, with no need to set it at
+// link time.
+func (cdr *Commander) VersionCommand() Command {
+	return &versioner{cdr: cdr}
+}
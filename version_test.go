@@ -0,0 +1,57 @@
+/*
+Copyright 2016 Google Inc. All Rights Reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package subcommands
+
+import (
+	"context"
+	"encoding/json"
+	"regexp"
+	"runtime"
+	"testing"
+)
+
+func TestVersion(t *testing.T) {
+	tests := []struct {
+		args       []string
+		wantStatus ExitStatus
+		want       string // a regexp matching what is printed
+	}{
+		{nil, ExitSuccess, `^tool \S*\n  module: +\S*\n(  revision: .*\n)?(  time: .*\n)?  go: +` + regexp.QuoteMeta(runtime.Version()) + `\n$`},
+		{[]string{"-format=text"}, ExitSuccess, `^tool `},
+		{[]string{"-format=json"}, ExitSuccess, `^\{\n  "module": .*"go": "` + regexp.QuoteMeta(runtime.Version()) + `"\n\}\n$`},
+		{[]string{"-format=xml"}, ExitUsageError, `^$`},
+		{[]string{"extra"}, ExitSuccess, `^tool `},
+	}
+	for _, tt := range tests {
+		cdr, stdout, _ := newTestCommander()
+		cdr.Register(cdr.VersionCommand(), "")
+		if status := cdr.Run(context.Background(), "version", tt.args); status != tt.wantStatus {
+			t.Errorf("%q: status %v, want %v", tt.args, status, tt.wantStatus)
+		}
+		if got := stdout.String(); !regexp.MustCompile(`(?s)` + tt.want).MatchString(got) {
+			t.Errorf("%q: printed %q, want a match for %q", tt.args, got, tt.want)
+		}
+		if tt.args != nil && tt.args[0] == "-format=json" {
+			var bv buildVersion
+			if err := json.Unmarshal(stdout.Bytes(), &bv); err != nil {
+				t.Errorf("%q: %v", tt.args, err)
+			} else if bv.Go != runtime.Version() {
+				t.Errorf("%q: go %q, want %q", tt.args, bv.Go, runtime.Version())
+			}
+		}
+	}
+}